@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,8 +32,17 @@ func NewAdminHandler(auditService *audit.Service, recordService *record.Service)
 // GET ALL RECORDS (Admin)
 // =========================
 func (h *AdminHandler) GetAllRecords(c *gin.Context) {
-	records, err := h.recordService.GetAll()
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	records, err := h.recordService.GetAll(c.Request.Context(), adminID)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch records",
 		})
@@ -52,7 +67,7 @@ func (h *AdminHandler) GetAuditLogs(c *gin.Context) {
 		pageSize = 20
 	}
 
-	logs, total, err := h.auditService.GetLogs(page, pageSize)
+	logs, total, err := h.auditService.GetLogs(c.Request.Context(), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch audit logs",
@@ -74,76 +89,260 @@ func (h *AdminHandler) GetAuditLogs(c *gin.Context) {
 // =========================
 func (h *AdminHandler) FilterAuditLogs(c *gin.Context) {
 
-	opts := audit.FilterOptions{}
+	opts, err := parseFilterOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Parse page
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		page = 1
+	logs, total, err := h.auditService.FilterLogs(c.Request.Context(), opts)
+	if err != nil {
+		respondAuditQueryError(c, err, "Failed to filter audit logs")
+		return
 	}
-	opts.Page = page
 
-	// Parse page size
-	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if err != nil || pageSize < 1 {
-		pageSize = 20
+	c.JSON(http.StatusOK, gin.H{
+		"data":      logs,
+		"total":     total,
+		"page":      opts.Page,
+		"page_size": opts.PageSize,
+		"pages":     (int(total) + opts.PageSize - 1) / opts.PageSize,
+	})
+}
+
+// =========================
+// SEARCH AUDIT LOGS — cursor paginated (Admin)
+// =========================
+func (h *AdminHandler) SearchAuditLogs(c *gin.Context) {
+
+	opts, err := parseFilterOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs, next, err := h.auditService.QueryLogs(c.Request.Context(), opts)
+	if err != nil {
+		respondAuditQueryError(c, err, "Failed to search audit logs")
+		return
 	}
-	opts.PageSize = pageSize
 
-	// Parse optional user_id filter
-	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		userIDUint, err := strconv.ParseUint(userIDStr, 10, 64)
-		if err == nil {
-			userID := uint(userIDUint)
-			opts.UserID = &userID
+	c.JSON(http.StatusOK, gin.H{
+		"data":        logs,
+		"page_size":   opts.PageSize,
+		"next_cursor": next,
+	})
+}
+
+// =========================
+// EXPORT AUDIT LOGS — CSV / JSONL stream (Admin)
+// =========================
+func (h *AdminHandler) ExportAuditLogs(c *gin.Context) {
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format: must be 'csv' or 'jsonl'"})
+		return
+	}
+
+	opts, err := parseFilterOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var write func(audit.AuditLog) error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		w := csv.NewWriter(c.Writer)
+		if err := w.Write(auditCSVHeader); err != nil {
+			return
 		}
+		write = func(entry audit.AuditLog) error {
+			if err := w.Write(auditCSVRow(entry)); err != nil {
+				return err
+			}
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(c.Writer)
+		write = func(entry audit.AuditLog) error {
+			return enc.Encode(entry)
+		}
+	}
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure mid-stream can only be logged
+	if err := h.auditService.ExportLogs(c.Request.Context(), opts, write); err != nil {
+		log.Printf("⚠️  Audit export aborted: %v", err)
 	}
+}
+
+var auditCSVHeader = []string{
+	"id", "timestamp", "user_id", "role", "action", "record_id", "patient_id",
+	"ip_address", "request_id", "details", "hash_version", "prev_hash", "hash", "signature",
+}
+
+func auditCSVRow(entry audit.AuditLog) []string {
+	return []string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.FormatUint(uint64(entry.UserID), 10),
+		csvText(entry.Role),
+		csvText(entry.Action),
+		formatOptionalUint(entry.RecordID),
+		formatOptionalUint(entry.PatientID),
+		csvText(entry.IPAddress),
+		csvText(entry.RequestID),
+		csvText(entry.Details),
+		strconv.Itoa(entry.HashVersion),
+		entry.PrevHash,
+		entry.Hash,
+		entry.Signature,
+	}
+}
 
-	// Parse optional action filter
-	if action := c.Query("action"); action != "" {
-		opts.Action = action
+// csvText stops spreadsheets from running a cell as a formula. The quote
+// shows in the cell, so verifying a row against its hash means removing it.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
 	}
+	return value
+}
 
-	// Parse optional from_date filter
-	if fromStr := c.Query("from_date"); fromStr != "" {
-		from, err := time.Parse("2006-01-02", fromStr)
-		if err == nil {
-			opts.FromDate = &from
+// =========================
+// Helper: Parse audit filter query parameters
+// =========================
+func parseFilterOptions(c *gin.Context) (audit.FilterOptions, error) {
+	opts := audit.FilterOptions{}
+	var err error
+
+	if opts.Page, err = parseOptionalInt(c, "page"); err != nil {
+		return opts, err
+	}
+	if opts.PageSize, err = parseOptionalInt(c, "page_size"); err != nil {
+		return opts, err
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		value, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return opts, errors.New("invalid cursor: must be the next_cursor value from a previous page")
 		}
+		opts.Cursor = uint(value)
 	}
 
-	// Parse optional to_date filter
-	if toStr := c.Query("to_date"); toStr != "" {
-		to, err := time.Parse("2006-01-02", toStr)
-		if err == nil {
-			// Set to end of day
-			to = to.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-			opts.ToDate = &to
+	if opts.UserID, err = parseOptionalID(c, "user_id"); err != nil {
+		return opts, err
+	}
+	if opts.RecordID, err = parseOptionalID(c, "record_id"); err != nil {
+		return opts, err
+	}
+	if opts.PatientID, err = parseOptionalID(c, "patient_id"); err != nil {
+		return opts, err
+	}
+
+	// action may be repeated or comma separated
+	for _, value := range c.QueryArray("action") {
+		for _, action := range strings.Split(value, ",") {
+			if action = strings.TrimSpace(action); action != "" {
+				opts.Actions = append(opts.Actions, strings.ToUpper(action))
+			}
 		}
 	}
 
-	logs, total, err := h.auditService.FilterLogs(opts)
+	opts.Role = c.Query("role")
+	opts.IPAddress = c.Query("ip")
+	opts.RequestID = c.Query("request_id")
+
+	if opts.FromDate, err = parseFilterTime(c, "from_date", false); err != nil {
+		return opts, err
+	}
+	if opts.ToDate, err = parseFilterTime(c, "to_date", true); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// parseFilterTime accepts RFC 3339 timestamps or plain dates. A plain
+// to_date is extended to the end of that day.
+func parseFilterTime(c *gin.Context, key string, endOfDay bool) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to filter audit logs",
-		})
-		return
+		return nil, fmt.Errorf("invalid %s: must be YYYY-MM-DD or RFC 3339 (e.g. 2024-01-31T14:00:00Z)", key)
 	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":      logs,
-		"total":     total,
-		"page":      opts.Page,
-		"page_size": opts.PageSize,
-		"pages":     (int(total) + opts.PageSize - 1) / opts.PageSize,
-	})
+func parseOptionalID(c *gin.Context, key string) (*uint, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return nil, fmt.Errorf("invalid %s: must be a positive integer", key)
+	}
+	result := uint(id)
+	return &result, nil
+}
+
+func parseOptionalInt(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: must be an integer", key)
+	}
+	return n, nil
+}
+
+func formatOptionalUint(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+// respondAuditQueryError maps validation failures to 400 and everything else to 500
+func respondAuditQueryError(c *gin.Context, err error, message string) {
+	var validationErr *audit.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
 }
 
 // =========================
 // VERIFY AUDIT CHAIN (Admin)
 // =========================
 func (h *AdminHandler) VerifyAuditChain(c *gin.Context) {
-	valid, err := h.auditService.VerifyChain()
+	valid, err := h.auditService.VerifyChain(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"verified": false,
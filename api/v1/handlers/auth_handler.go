@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/khawsic/health/internal/auth"
@@ -25,8 +29,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	var req struct {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=6"`
+		Password string `json:"password" binding:"required"`
 		Role     string `json:"role" binding:"required"`
+
+		// Doctors only — reviewed by an administrator before activation
+		LicenseNumber string `json:"license_number"`
+		Notes         string `json:"notes"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -34,13 +42,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.Register(req.Name, req.Email, req.Password, req.Role)
+	err := h.authService.Register(req.Name, req.Email, req.Password, req.Role, req.LicenseNumber, req.Notes)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondPasswordError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	message := "Registration received — check your email to verify your address"
+	if req.Role == "doctor" {
+		message += ". An administrator will then review your application"
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": message})
 }
 
 // =========================
@@ -58,15 +70,107 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authService.Login(req.Email, req.Password)
+	result, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+// =========================
+// LOGIN — MFA STEP
+// =========================
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+
+	var req struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.authService.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, req.RecoveryCode)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+// =========================
+// LOGIN — REQUIRED MFA ENROLLMENT
+// =========================
+func (h *AuthHandler) BeginMFAEnrollment(c *gin.Context) {
+
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, uri, err := h.authService.BeginChallengeEnrollment(req.MFAToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+func (h *AuthHandler) ConfirmMFAEnrollment(c *gin.Context) {
+
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, codes, err := h.authService.ConfirmChallengeEnrollment(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":   result.AccessToken,
+		"refresh_token":  result.RefreshToken,
+		"recovery_codes": codes,
+	})
+}
+
+// =========================
+// Helper: Login response
+// =========================
+func respondLogin(c *gin.Context, result *auth.LoginResult) {
+	if result.MFAToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":            true,
+			"mfa_enrollment_required": result.MFAEnrollmentRequired,
+			"mfa_token":               result.MFAToken,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  result.AccessToken,
+		"refresh_token": result.RefreshToken,
 	})
 }
 
@@ -84,13 +188,17 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	accessToken, err := h.authService.Refresh(req.RefreshToken)
+	accessToken, refreshToken, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"access_token": accessToken})
+	// The presented refresh token is now spent — clients must store this one
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
 }
 
 // =========================
@@ -130,17 +238,12 @@ func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.RequestPasswordReset(req.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
-		return
-	}
+	// Same response whether or not the account exists; the token is only
+	// ever delivered by email
+	h.authService.RequestPasswordReset(req.Email)
 
-	// In production send this via email
-	// For demo purposes return token directly
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "If this email exists a reset token has been sent",
-		"reset_token": token,
+		"message": "If this email is registered, a reset link has been sent",
 	})
 }
 
@@ -151,7 +254,7 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 
 	var req struct {
 		Token       string `json:"token" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=6"`
+		NewPassword string `json:"new_password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -161,9 +264,60 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 
 	err := h.authService.ResetPassword(req.Token, req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondPasswordError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// =========================
+// PASSWORD POLICY — lets clients describe the rules up front
+// =========================
+func (h *AuthHandler) PasswordPolicy(c *gin.Context) {
+	policy := h.authService.PasswordPolicy()
+
+	c.JSON(http.StatusOK, gin.H{
+		"min_length":           policy.MinLength,
+		"max_length":           policy.MaxLength,
+		"require_lowercase":    policy.RequireLower,
+		"require_uppercase":    policy.RequireUpper,
+		"require_digit":        policy.RequireDigit,
+		"require_symbol":       policy.RequireSymbol,
+		"reject_personal_info": policy.RejectPersonalInfo,
+		"history_size":         policy.HistorySize,
+	})
+}
+
+// respondLoginError answers a failed sign-in with 401, or with 429 and
+// Retry-After while the account is locked out
+func respondLoginError(c *gin.Context, err error) {
+	var lockedErr *auth.LockedError
+	if errors.As(err, &lockedErr) {
+		retryAfter := int(math.Ceil(time.Until(lockedErr.Until).Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":        lockedErr.Error(),
+			"locked_until": lockedErr.Until,
+		})
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+// respondPasswordError reports password policy violations with their codes
+// so clients can show each one; other errors are passed through as-is
+func respondPasswordError(c *gin.Context, err error) {
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      policyErr.Error(),
+			"code":       "password_policy_violation",
+			"violations": policyErr.Violations,
+		})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
\ No newline at end of file
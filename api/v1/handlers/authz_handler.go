@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/authz"
+)
+
+type AuthzHandler struct {
+	authorizer *authz.Service
+}
+
+func NewAuthzHandler(authorizer *authz.Service) *AuthzHandler {
+	return &AuthzHandler{
+		authorizer: authorizer,
+	}
+}
+
+// =========================
+// MY PERMISSIONS (any role)
+// =========================
+func (h *AuthzHandler) MyPermissions(c *gin.Context) {
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":        roleName,
+		"permissions": h.authorizer.RolePermissions(roleName),
+	})
+}
+
+// =========================
+// EXPLAIN MY ACCESS (any role)
+// =========================
+func (h *AuthzHandler) ExplainMine(c *gin.Context) {
+
+	var req struct {
+		Permission string `json:"permission" binding:"required"`
+		PatientID  uint   `json:"patient_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !authz.KnownPermission(req.Permission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.authorizer.ExplainSelf(c.Request.Context(), req.Permission, patientResource(req.PatientID)))
+}
+
+// =========================
+// EXPLAIN A USER'S ACCESS (Admin)
+// =========================
+func (h *AuthzHandler) Explain(c *gin.Context) {
+
+	var req struct {
+		UserID     uint       `json:"user_id" binding:"required"`
+		Permission string     `json:"permission" binding:"required"`
+		PatientID  uint       `json:"patient_id"`
+		IPAddress  string     `json:"ip_address"`
+		At         *time.Time `json:"at"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !authz.KnownPermission(req.Permission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission"})
+		return
+	}
+
+	// Default to the conditions of the admin's own request
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+	if req.IPAddress == "" {
+		req.IPAddress = c.ClientIP()
+	}
+
+	decision, err := h.authorizer.Explain(req.UserID, req.Permission, patientResource(req.PatientID), req.IPAddress, at)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// =========================
+// LIST PERMISSIONS (Admin)
+// =========================
+func (h *AuthzHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, authz.Catalogue)
+}
+
+// =========================
+// LIST ROLES (Admin)
+// =========================
+func (h *AuthzHandler) ListRoles(c *gin.Context) {
+	roles, err := h.authorizer.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+type roleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// =========================
+// CREATE ROLE (Admin)
+// =========================
+func (h *AuthzHandler) CreateRole(c *gin.Context) {
+
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authorizer.CreateRole(c.Request.Context(), req.Name, req.Description, req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Role created"})
+}
+
+// =========================
+// UPDATE ROLE (Admin)
+// =========================
+func (h *AuthzHandler) UpdateRole(c *gin.Context) {
+
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authorizer.UpdateRole(c.Request.Context(), c.Param("role"), req.Description, req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
+}
+
+// =========================
+// DELETE ROLE (Admin)
+// =========================
+func (h *AuthzHandler) DeleteRole(c *gin.Context) {
+	if err := h.authorizer.DeleteRole(c.Request.Context(), c.Param("role")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+// =========================
+// LIST POLICIES (Admin)
+// =========================
+func (h *AuthzHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.authorizer.ListPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// =========================
+// CREATE POLICY (Admin)
+// =========================
+func (h *AuthzHandler) CreatePolicy(c *gin.Context) {
+
+	var req authz.Policy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	policy, err := h.authorizer.CreatePolicy(c.Request.Context(), adminID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// =========================
+// UPDATE POLICY (Admin)
+// =========================
+func (h *AuthzHandler) UpdatePolicy(c *gin.Context) {
+	policyID, ok := policyIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req authz.Policy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	policy, err := h.authorizer.UpdatePolicy(c.Request.Context(), adminID, policyID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// =========================
+// DELETE POLICY (Admin)
+// =========================
+func (h *AuthzHandler) DeletePolicy(c *gin.Context) {
+	policyID, ok := policyIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authorizer.DeletePolicy(c.Request.Context(), policyID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Policy deleted"})
+}
+
+// policyIDParam parses :policy_id, responding with 400 when it is malformed
+func policyIDParam(c *gin.Context) (uint, bool) {
+	policyID, err := strconv.ParseUint(c.Param("policy_id"), 10, 64)
+	if err != nil || policyID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return 0, false
+	}
+	return uint(policyID), true
+}
+
+// patientResource is the resource for an optional patient ID; without one
+// patient conditions are deferred, as at the route
+func patientResource(patientID uint) *authz.Resource {
+	if patientID == 0 {
+		return nil
+	}
+	return &authz.Resource{PatientID: patientID}
+}
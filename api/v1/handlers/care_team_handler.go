@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	record "github.com/khawsic/health/internal/records"
+)
+
+type CareTeamHandler struct {
+	recordService *record.Service
+}
+
+func NewCareTeamHandler(recordService *record.Service) *CareTeamHandler {
+	return &CareTeamHandler{
+		recordService: recordService,
+	}
+}
+
+type careAssignmentRequest struct {
+	PatientID uint       `json:"patient_id" binding:"required"`
+	Reason    string     `json:"reason"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+}
+
+func (r careAssignmentRequest) start() time.Time {
+	if r.StartsAt == nil {
+		return time.Time{}
+	}
+	return *r.StartsAt
+}
+
+// =========================
+// MY PATIENTS (Clinician)
+// =========================
+func (h *CareTeamHandler) MyPatients(c *gin.Context) {
+	clinicianID := getUserID(c)
+	if clinicianID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	assignments, err := h.recordService.MyPatients(clinicianID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// =========================
+// REQUEST ASSIGNMENT (Clinician)
+// =========================
+func (h *CareTeamHandler) RequestAssignment(c *gin.Context) {
+
+	var req careAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clinicianID := getUserID(c)
+	if clinicianID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	assignment, err := h.recordService.RequestCareAssignment(c.Request.Context(), clinicianID, req.PatientID, req.Reason, req.start(), req.EndsAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Request sent — a care team manager will review it",
+		"assignment": assignment,
+	})
+}
+
+// =========================
+// LEAVE CARE TEAM (Clinician)
+// =========================
+func (h *CareTeamHandler) EndMyAssignment(c *gin.Context) {
+	h.end(c, false)
+}
+
+// =========================
+// PATIENT CARE TEAM (Patient)
+// =========================
+func (h *CareTeamHandler) PatientCareTeam(c *gin.Context) {
+	patientID := patientSubject(c)
+	if patientID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	team, err := h.recordService.CareTeam(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch care team"})
+		return
+	}
+
+	members := make([]gin.H, len(team))
+	for i, member := range team {
+		members[i] = gin.H{
+			"clinician_id":   member.ClinicianID,
+			"clinician_name": member.ClinicianName,
+			"clinician_role": member.ClinicianRole,
+			"starts_at":      member.StartsAt,
+			"ends_at":        member.EndsAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// =========================
+// LIST ASSIGNMENTS (Admin)
+// =========================
+func (h *CareTeamHandler) ListAssignments(c *gin.Context) {
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	patientID, err := parseOptionalID(c, "patient_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient_id"})
+		return
+	}
+	clinicianID, err := parseOptionalID(c, "clinician_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid clinician_id"})
+		return
+	}
+
+	filter := record.CareFilter{
+		Status:   c.Query("status"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if patientID != nil {
+		filter.PatientID = *patientID
+	}
+	if clinicianID != nil {
+		filter.ClinicianID = *clinicianID
+	}
+
+	assignments, total, err := h.recordService.ListCareAssignments(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch care assignments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      assignments,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// =========================
+// ASSIGN CLINICIAN (Admin)
+// =========================
+func (h *CareTeamHandler) Assign(c *gin.Context) {
+
+	var req struct {
+		careAssignmentRequest
+		ClinicianID uint `json:"clinician_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	managerID := getUserID(c)
+	if managerID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	assignment, err := h.recordService.AssignCare(c.Request.Context(), managerID, req.ClinicianID, req.PatientID, req.Reason, req.start(), req.EndsAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, assignment)
+}
+
+// =========================
+// APPROVE / DECLINE REQUEST (Admin)
+// =========================
+func (h *CareTeamHandler) Approve(c *gin.Context) {
+	h.review(c, true)
+}
+
+func (h *CareTeamHandler) Decline(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *CareTeamHandler) review(c *gin.Context, approve bool) {
+	assignmentID, ok := assignmentIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+
+	// Notes are optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	managerID := getUserID(c)
+	if managerID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	assignment, err := h.recordService.ReviewCareAssignment(c.Request.Context(), managerID, assignmentID, approve, req.Notes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// =========================
+// END ASSIGNMENT (Admin)
+// =========================
+func (h *CareTeamHandler) EndAssignment(c *gin.Context) {
+	h.end(c, true)
+}
+
+func (h *CareTeamHandler) end(c *gin.Context, manager bool) {
+	assignmentID, ok := assignmentIDParam(c)
+	if !ok {
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.recordService.EndCareAssignment(c.Request.Context(), userID, assignmentID, manager); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Care assignment ended"})
+}
+
+// assignmentIDParam parses :assignment_id, responding with 400 when it is
+// malformed
+func assignmentIDParam(c *gin.Context) (uint, bool) {
+	assignmentID, err := strconv.ParseUint(c.Param("assignment_id"), 10, 64)
+	if err != nil || assignmentID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment ID"})
+		return 0, false
+	}
+	return uint(assignmentID), true
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	record "github.com/khawsic/health/internal/records"
+)
+
+type ConsentHandler struct {
+	recordService *record.Service
+}
+
+func NewConsentHandler(recordService *record.Service) *ConsentHandler {
+	return &ConsentHandler{
+		recordService: recordService,
+	}
+}
+
+// =========================
+// LIST MY CONSENTS (Patient)
+// =========================
+func (h *ConsentHandler) ListConsents(c *gin.Context) {
+	patientID := getUserID(c)
+	if patientID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	grants, err := h.recordService.ListConsents(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents"})
+		return
+	}
+
+	now := time.Now()
+	views := make([]gin.H, len(grants))
+	for i, grant := range grants {
+		views[i] = gin.H{
+			"consent": grant,
+			"status":  consentStatus(grant, now),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       views,
+		"categories": record.Categories,
+	})
+}
+
+// =========================
+// GRANT CONSENT (Patient)
+// =========================
+func (h *ConsentHandler) GrantConsent(c *gin.Context) {
+
+	var req struct {
+		GranteeType string    `json:"grantee_type" binding:"required"`
+		ClinicianID *uint     `json:"clinician_id"`
+		Department  string    `json:"department"`
+		Categories  []string  `json:"categories"`
+		Actions     []string  `json:"actions" binding:"required"`
+		Purpose     string    `json:"purpose"`
+		ExpiresAt   time.Time `json:"expires_at" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	patientID := getUserID(c)
+	if patientID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	grant, err := h.recordService.GrantConsent(c.Request.Context(), patientID, record.ConsentGrant{
+		GranteeType: req.GranteeType,
+		ClinicianID: req.ClinicianID,
+		Department:  req.Department,
+		Categories:  req.Categories,
+		Actions:     req.Actions,
+		Purpose:     req.Purpose,
+		ExpiresAt:   req.ExpiresAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// =========================
+// REVOKE CONSENT (Patient)
+// =========================
+func (h *ConsentHandler) RevokeConsent(c *gin.Context) {
+
+	consentID, err := strconv.ParseUint(c.Param("consent_id"), 10, 64)
+	if err != nil || consentID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid consent ID"})
+		return
+	}
+
+	patientID := getUserID(c)
+	if patientID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.recordService.RevokeConsent(c.Request.Context(), patientID, uint(consentID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Consent revoked"})
+}
+
+// =========================
+// CONSENT ACTIVITY (Patient)
+// =========================
+func (h *ConsentHandler) Activity(c *gin.Context) {
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	patientID := getUserID(c)
+	if patientID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	events, total, err := h.recordService.ConsentActivity(c.Request.Context(), patientID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      events,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+func consentStatus(grant record.ConsentGrant, now time.Time) string {
+	switch {
+	case grant.RevokedAt != nil:
+		return "revoked"
+	case !grant.ExpiresAt.After(now):
+		return "expired"
+	default:
+		return "active"
+	}
+}
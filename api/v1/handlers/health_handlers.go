@@ -5,18 +5,21 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/audit"
 	"gorm.io/gorm"
 )
 
 type HealthHandler struct {
-	db        *gorm.DB
-	startTime time.Time
+	db           *gorm.DB
+	auditService *audit.Service
+	startTime    time.Time
 }
 
-func NewHealthHandler(db *gorm.DB) *HealthHandler {
+func NewHealthHandler(db *gorm.DB, auditService *audit.Service) *HealthHandler {
 	return &HealthHandler{
-		db:        db,
-		startTime: time.Now(),
+		db:           db,
+		auditService: auditService,
+		startTime:    time.Now(),
 	}
 }
 
@@ -36,15 +39,42 @@ func (h *HealthHandler) Check(c *gin.Context) {
 	uptime := time.Since(h.startTime).Round(time.Second).String()
 
 	status := http.StatusOK
+	overall := "ok"
 	if dbStatus != "ok" {
 		status = http.StatusServiceUnavailable
+		overall = "degraded"
+	}
+
+	// Last scheduled audit chain verification
+	auditChain := gin.H{"status": "pending"}
+	if dbStatus == "ok" {
+		last, err := h.auditService.LastVerification()
+		if err != nil {
+			auditChain = gin.H{"status": "unknown"}
+		} else if last != nil {
+			chainStatus := "verified"
+			if !last.Valid {
+				// A chain that fails verification cannot be trusted as evidence
+				chainStatus = "tampered"
+				status = http.StatusServiceUnavailable
+				overall = "degraded"
+			}
+			auditChain = gin.H{
+				"status":          chainStatus,
+				"verified_at":     last.VerifiedAt,
+				"last_entry_id":   last.ToEntryID,
+				"failure_kind":    last.FailureKind,
+				"failed_entry_id": last.FailedEntryID,
+			}
+		}
 	}
 
 	c.JSON(status, gin.H{
-		"status":   "ok",
-		"uptime":   uptime,
-		"database": dbStatus,
-		"version":  "1.0.0",
-		"time":     time.Now().UTC(),
+		"status":      overall,
+		"uptime":      uptime,
+		"database":    dbStatus,
+		"audit_chain": auditChain,
+		"version":     "1.0.0",
+		"time":        time.Now().UTC(),
 	})
 }
\ No newline at end of file
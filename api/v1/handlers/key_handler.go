@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/keyring"
+)
+
+type KeyHandler struct {
+	keys *keyring.Keyring
+}
+
+func NewKeyHandler(keys *keyring.Keyring) *KeyHandler {
+	return &KeyHandler{
+		keys: keys,
+	}
+}
+
+// =========================
+// JWKS (public)
+// =========================
+func (h *KeyHandler) JWKS(c *gin.Context) {
+	// Cache no longer than the publish lead so verifiers always see a new
+	// key before tokens signed with it appear
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(keyring.PublishLead.Seconds())))
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// =========================
+// LIST SIGNING KEYS (Admin)
+// =========================
+func (h *KeyHandler) ListKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, h.keys.Keys())
+}
+
+// =========================
+// ROTATE SIGNING KEY (Admin)
+// =========================
+func (h *KeyHandler) Rotate(c *gin.Context) {
+	key, err := h.keys.Rotate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate signing key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Signing key rotated",
+		"kid":          key.ID,
+		"activates_at": key.ActivatesAt,
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/auth"
+)
+
+// oidcStateCookie ties an SSO login to the browser that started it, so a
+// callback URL from someone else's login cannot sign this browser in
+const oidcStateCookie = "hv_oidc_state"
+
+type OIDCHandler struct {
+	authService *auth.Service
+}
+
+func NewOIDCHandler(authService *auth.Service) *OIDCHandler {
+	return &OIDCHandler{
+		authService: authService,
+	}
+}
+
+// =========================
+// LIST SSO PROVIDERS
+// =========================
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authService.OIDCProviders())
+}
+
+// =========================
+// START SSO LOGIN
+// =========================
+func (h *OIDCHandler) BeginLogin(c *gin.Context) {
+	authURL, state, err := h.authService.BeginOIDCLogin(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Lax, so the cookie still arrives on the redirect back from the IdP
+	setOIDCStateCookie(c, state, int(auth.OIDCLoginTimeout.Seconds()))
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// =========================
+// SSO CALLBACK (redirect target registered with the IdP)
+// =========================
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if idpError := c.Query("error"); idpError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider refused login: " + idpError})
+		return
+	}
+
+	code := c.Query("code")
+	state := c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+
+	// The state must come back to the browser that started the login
+	browserState, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(browserState), []byte(state)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "single sign-on was started in another browser — start again"})
+		return
+	}
+
+	result, err := h.authService.FinishOIDCLogin(c.Request.Context(), c.Param("provider"), state, code)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/oidc/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
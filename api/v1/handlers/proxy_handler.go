@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	record "github.com/khawsic/health/internal/records"
+)
+
+type ProxyHandler struct {
+	recordService *record.Service
+}
+
+func NewProxyHandler(recordService *record.Service) *ProxyHandler {
+	return &ProxyHandler{
+		recordService: recordService,
+	}
+}
+
+// =========================
+// REQUEST PROXY ACCESS (Proxy)
+// =========================
+func (h *ProxyHandler) RequestProxy(c *gin.Context) {
+
+	var req struct {
+		SubjectEmail string `json:"subject_email" binding:"required,email"`
+		Relationship string `json:"relationship" binding:"required"`
+		Evidence     string `json:"evidence" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proxyID := getUserID(c)
+	if proxyID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	// Same answer whether or not the patient exists
+	if _, err := h.recordService.RequestProxy(c.Request.Context(), proxyID, req.SubjectEmail, req.Relationship, req.Evidence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If a patient with that email exists, staff will verify your request before you can act for them",
+	})
+}
+
+// =========================
+// PATIENTS I ACT FOR (Proxy)
+// =========================
+func (h *ProxyHandler) MySubjects(c *gin.Context) {
+	proxyID := getUserID(c)
+	if proxyID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	relationships, _, err := h.recordService.ListProxyRelationships(record.ProxyFilter{ProxyID: proxyID, PageSize: 100})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proxy relationships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, relationships)
+}
+
+// =========================
+// MY PROXIES (Patient)
+// =========================
+func (h *ProxyHandler) MyProxies(c *gin.Context) {
+	patientID := getUserID(c)
+	if patientID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	relationships, _, err := h.recordService.ListProxyRelationships(record.ProxyFilter{SubjectID: patientID, PageSize: 100})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proxy relationships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, relationships)
+}
+
+// =========================
+// END A RELATIONSHIP (Proxy or Patient)
+// =========================
+func (h *ProxyHandler) Revoke(c *gin.Context) {
+	h.revoke(c, false)
+}
+
+// =========================
+// LIST RELATIONSHIPS (Admin)
+// =========================
+func (h *ProxyHandler) ListRelationships(c *gin.Context) {
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	proxyID, err := parseOptionalID(c, "proxy_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proxy_id"})
+		return
+	}
+	subjectID, err := parseOptionalID(c, "subject_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subject_id"})
+		return
+	}
+
+	filter := record.ProxyFilter{
+		Status:   c.Query("status"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if proxyID != nil {
+		filter.ProxyID = *proxyID
+	}
+	if subjectID != nil {
+		filter.SubjectID = *subjectID
+	}
+
+	relationships, total, err := h.recordService.ListProxyRelationships(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proxy relationships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      relationships,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// =========================
+// VERIFY / DECLINE REQUEST (Admin)
+// =========================
+func (h *ProxyHandler) Approve(c *gin.Context) {
+	h.review(c, true)
+}
+
+func (h *ProxyHandler) Decline(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *ProxyHandler) review(c *gin.Context, approve bool) {
+	relationshipID, ok := relationshipIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Notes     string     `json:"notes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	// The body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	verifierID := getUserID(c)
+	if verifierID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	relationship, err := h.recordService.ReviewProxy(c.Request.Context(), verifierID, relationshipID, approve, req.Notes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, relationship)
+}
+
+// =========================
+// REVOKE RELATIONSHIP (Admin)
+// =========================
+func (h *ProxyHandler) RevokeAny(c *gin.Context) {
+	h.revoke(c, true)
+}
+
+func (h *ProxyHandler) revoke(c *gin.Context, staff bool) {
+	relationshipID, ok := relationshipIDParam(c)
+	if !ok {
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.recordService.RevokeProxy(c.Request.Context(), userID, relationshipID, staff); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Proxy relationship ended"})
+}
+
+// relationshipIDParam parses :relationship_id, responding with 400 when it
+// is malformed
+func relationshipIDParam(c *gin.Context) (uint, bool) {
+	relationshipID, err := strconv.ParseUint(c.Param("relationship_id"), 10, 64)
+	if err != nil || relationshipID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relationship ID"})
+		return 0, false
+	}
+	return uint(relationshipID), true
+}
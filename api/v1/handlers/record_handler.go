@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/authz"
 	record "github.com/khawsic/health/internal/records"
 )
 
@@ -47,6 +49,7 @@ func (h *RecordHandler) CreateRecord(c *gin.Context) {
 
 	var req struct {
 		PatientID uint   `json:"patient_id" binding:"required"`
+		Category  string `json:"category"`
 		Diagnosis string `json:"diagnosis" binding:"required"`
 		Treatment string `json:"treatment" binding:"required"`
 	}
@@ -62,8 +65,16 @@ func (h *RecordHandler) CreateRecord(c *gin.Context) {
 		return
 	}
 
-	err := h.recordService.Create(req.PatientID, doctorID, req.Diagnosis, req.Treatment)
+	if req.Category != "" && !record.ValidCategory(req.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category", "categories": record.Categories})
+		return
+	}
+
+	err := h.recordService.Create(c.Request.Context(), req.PatientID, doctorID, req.Category, req.Diagnosis, req.Treatment)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create record"})
 		return
 	}
@@ -99,8 +110,11 @@ func (h *RecordHandler) UpdateRecord(c *gin.Context) {
 		return
 	}
 
-	err = h.recordService.Update(uint(recordIDUint), doctorID, req.Diagnosis, req.Treatment)
+	err = h.recordService.Update(c.Request.Context(), uint(recordIDUint), doctorID, req.Diagnosis, req.Treatment)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -120,8 +134,17 @@ func (h *RecordHandler) GetVersionHistory(c *gin.Context) {
 		return
 	}
 
-	versions, err := h.recordService.GetVersionHistory(uint(recordIDUint))
+	doctorID := getUserID(c)
+	if doctorID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	versions, err := h.recordService.GetVersionHistory(c.Request.Context(), uint(recordIDUint), doctorID)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch version history"})
 		return
 	}
@@ -147,8 +170,11 @@ func (h *RecordHandler) SearchPatientRecords(c *gin.Context) {
 		return
 	}
 
-	records, err := h.recordService.SearchByPatient(uint(patientIDUint), doctorID)
+	records, err := h.recordService.SearchByPatient(c.Request.Context(), uint(patientIDUint), doctorID)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -174,8 +200,11 @@ func (h *RecordHandler) DeleteRecord(c *gin.Context) {
 		return
 	}
 
-	err = h.recordService.SoftDelete(uint(recordIDUint), doctorID)
+	err = h.recordService.SoftDelete(c.Request.Context(), uint(recordIDUint), doctorID)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -188,14 +217,17 @@ func (h *RecordHandler) DeleteRecord(c *gin.Context) {
 // =========================
 func (h *RecordHandler) GetPatientRecords(c *gin.Context) {
 
-	patientID := getUserID(c)
+	patientID := patientSubject(c)
 	if patientID == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
 		return
 	}
 
-	records, err := h.recordService.GetByPatient(patientID)
+	records, err := h.recordService.GetByPatient(c.Request.Context(), patientID)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
 		return
 	}
@@ -221,8 +253,11 @@ func (h *RecordHandler) EmergencyAccess(c *gin.Context) {
 		return
 	}
 
-	recordData, err := h.recordService.EmergencyAccess(uint(recordIDUint), userID)
+	recordData, err := h.recordService.EmergencyAccess(c.Request.Context(), uint(recordIDUint), userID)
 	if err != nil {
+		if respondDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -249,4 +284,42 @@ func getUserID(c *gin.Context) uint {
 	default:
 		return 0
 	}
+}
+
+// patientSubject is the patient a patient route acts on: the one a proxy
+// is acting for, or else the caller
+func patientSubject(c *gin.Context) uint {
+	if subject, ok := c.Get("acting_as"); ok {
+		if id, ok := subject.(uint); ok {
+			return id
+		}
+	}
+	return getUserID(c)
+}
+
+// respondDenied answers 403 when err is a permission, care relationship,
+// consent or proxy refusal from a service
+func respondDenied(c *gin.Context, err error) bool {
+	if errors.Is(err, record.ErrNoProxyAccess) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return true
+	}
+	if errors.Is(err, record.ErrNoCareRelationship) || errors.Is(err, record.ErrNoConsent) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            err.Error(),
+			"emergency_access": "POST /api/v1/doctor/records/emergency/:record_id",
+		})
+		return true
+	}
+
+	var denied *authz.DeniedError
+	if !errors.As(err, &denied) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":      "Access denied",
+		"permission": denied.Decision.Permission,
+		"reason":     denied.Decision.Reason,
+	})
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/auth"
+)
+
+type RegistrationHandler struct {
+	authService *auth.Service
+}
+
+func NewRegistrationHandler(authService *auth.Service) *RegistrationHandler {
+	return &RegistrationHandler{
+		authService: authService,
+	}
+}
+
+// =========================
+// VERIFY EMAIL
+// =========================
+func (h *RegistrationHandler) VerifyEmail(c *gin.Context) {
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.authService.VerifyEmail(req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Email verified — you can now sign in"
+	if status == auth.StatusPendingApproval {
+		message = "Email verified — your account is awaiting administrator approval"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"status":  status,
+	})
+}
+
+// =========================
+// RESEND VERIFICATION EMAIL
+// =========================
+func (h *RegistrationHandler) ResendVerification(c *gin.Context) {
+
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Same response whether or not the address is registered
+	h.authService.ResendVerification(req.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If this email awaits verification, a new link has been sent"})
+}
+
+// =========================
+// ADMIN — DOCTOR APPLICATION QUEUE
+// =========================
+func (h *RegistrationHandler) ListDoctorApplications(c *gin.Context) {
+
+	applications, err := h.authService.ListDoctorApplications(c.DefaultQuery("status", auth.ApplicationPending))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, len(applications))
+	for i, application := range applications {
+		response[i] = gin.H{
+			"id":             application.ID,
+			"user_id":        application.UserID,
+			"name":           application.User.Name,
+			"email":          application.User.Email,
+			"email_verified": application.User.EmailVerifiedAt != nil,
+			"license_number": application.LicenseNumber,
+			"notes":          application.Notes,
+			"status":         application.Status,
+			"reviewed_by":    application.ReviewedBy,
+			"review_notes":   application.ReviewNotes,
+			"reviewed_at":    application.ReviewedAt,
+			"created_at":     application.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// =========================
+// ADMIN — APPROVE DOCTOR
+// =========================
+func (h *RegistrationHandler) ApproveDoctor(c *gin.Context) {
+	h.review(c, true)
+}
+
+// =========================
+// ADMIN — REJECT DOCTOR
+// =========================
+func (h *RegistrationHandler) RejectDoctor(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *RegistrationHandler) review(c *gin.Context, approve bool) {
+	applicationID, err := strconv.ParseUint(c.Param("application_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+
+	// The body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.ReviewDoctorApplication(uint(applicationID), adminID, approve, req.Notes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Application rejected"
+	if approve {
+		message = "Application approved"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/auth"
+)
+
+type ServiceAccountHandler struct {
+	authService *auth.Service
+}
+
+func NewServiceAccountHandler(authService *auth.Service) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		authService: authService,
+	}
+}
+
+// =========================
+// LIST SERVICE ACCOUNTS (Admin)
+// =========================
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	accounts, total, err := h.authService.ListUsers(c.Request.Context(), auth.UserFilter{
+		Kind:     auth.KindService,
+		Status:   c.Query("status"),
+		Search:   c.Query("q"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      accounts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// =========================
+// CREATE SERVICE ACCOUNT (Admin)
+// =========================
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+
+	var req struct {
+		Name       string `json:"name" binding:"required"`
+		Role       string `json:"role" binding:"required"`
+		Department string `json:"department"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.authService.CreateServiceAccount(c.Request.Context(), req.Name, req.Role, req.Department)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// =========================
+// LIST API KEYS (Admin)
+// =========================
+func (h *ServiceAccountHandler) ListAPIKeys(c *gin.Context) {
+	accountID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.authService.ListAPIKeys(accountID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// =========================
+// ISSUE API KEY (Admin)
+// =========================
+func (h *ServiceAccountHandler) CreateAPIKey(c *gin.Context) {
+	accountID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name       string    `json:"name" binding:"required"`
+		Scopes     []string  `json:"scopes" binding:"required"`
+		AllowedIPs []string  `json:"allowed_ips"`
+		ExpiresAt  time.Time `json:"expires_at" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	plaintext, key, err := h.authService.CreateAPIKey(c.Request.Context(), adminID, accountID, auth.NewAPIKey{
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		AllowedIPs: req.AllowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": plaintext,
+		"key":     key,
+		"message": "Store this key now — it will not be shown again",
+	})
+}
+
+// =========================
+// REVOKE API KEY (Admin)
+// =========================
+func (h *ServiceAccountHandler) RevokeAPIKey(c *gin.Context) {
+
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 64)
+	if err != nil || keyID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), adminID, uint(keyID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/auth"
+)
+
+type SessionHandler struct {
+	authService *auth.Service
+}
+
+func NewSessionHandler(authService *auth.Service) *SessionHandler {
+	return &SessionHandler{
+		authService: authService,
+	}
+}
+
+// =========================
+// LIST MY SESSIONS
+// =========================
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	currentID := c.GetString("session_id")
+	response := make([]gin.H, len(sessions))
+	for i, session := range sessions {
+		response[i] = gin.H{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == currentID,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// =========================
+// REVOKE ONE SESSION (sign a device out)
+// =========================
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, c.Param("session_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// =========================
+// REVOKE ALL OTHER SESSIONS
+// =========================
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	count, err := h.authService.RevokeOtherSessions(userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions revoked",
+		"revoked": count,
+	})
+}
+
+// =========================
+// STEP UP (confirm identity for sensitive actions)
+// =========================
+func (h *SessionHandler) StepUp(c *gin.Context) {
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	accessToken, err := h.authService.StepUp(c.Request.Context(), userID, c.GetString("session_id"), req.Password, req.Code, req.RecoveryCode)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"access_token": accessToken})
+}
+
+// =========================
+// MY LOGIN HISTORY
+// =========================
+func (h *SessionHandler) LoginHistory(c *gin.Context) {
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	events, total, err := h.authService.LoginHistory(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      events,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (int(total) + pageSize - 1) / pageSize,
+	})
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/auth"
+	record "github.com/khawsic/health/internal/records"
+)
+
+type UserAdminHandler struct {
+	authService   *auth.Service
+	recordService *record.Service
+}
+
+func NewUserAdminHandler(authService *auth.Service, recordService *record.Service) *UserAdminHandler {
+	return &UserAdminHandler{
+		authService:   authService,
+		recordService: recordService,
+	}
+}
+
+// =========================
+// LIST USERS (Admin)
+// =========================
+func (h *UserAdminHandler) ListUsers(c *gin.Context) {
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	users, total, err := h.authService.ListUsers(c.Request.Context(), auth.UserFilter{
+		Role:     c.Query("role"),
+		Kind:     c.Query("kind"),
+		Status:   c.Query("status"),
+		Search:   c.Query("q"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      users,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// =========================
+// GET USER (Admin)
+// =========================
+func (h *UserAdminHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, identities, sessions, err := h.authService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	linked := make([]gin.H, len(identities))
+	for i, identity := range identities {
+		linked[i] = gin.H{
+			"provider":      identity.Provider,
+			"email":         identity.Email,
+			"last_login_at": identity.LastLoginAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":            user,
+		"identities":      linked,
+		"active_sessions": sessions,
+	})
+}
+
+// =========================
+// UNLOCK USER (Admin)
+// =========================
+func (h *UserAdminHandler) UnlockUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.UnlockUser(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
+
+// =========================
+// DISABLE USER (Admin)
+// =========================
+func (h *UserAdminHandler) DisableUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.DisableUser(c.Request.Context(), adminID, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User disabled and signed out"})
+}
+
+// =========================
+// ENABLE USER (Admin)
+// =========================
+func (h *UserAdminHandler) EnableUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.EnableUser(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User enabled"})
+}
+
+// =========================
+// CHANGE ROLE (Admin)
+// =========================
+func (h *UserAdminHandler) ChangeRole(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.ChangeUserRole(c.Request.Context(), adminID, userID, req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated — the user must sign in again"})
+}
+
+// =========================
+// SET DEPARTMENT (Admin)
+// =========================
+func (h *UserAdminHandler) SetDepartment(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	// An empty department clears it
+	var req struct {
+		Department string `json:"department"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.SetUserDepartment(c.Request.Context(), userID, req.Department); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Department updated"})
+}
+
+// =========================
+// SET DATE OF BIRTH (Admin)
+// =========================
+func (h *UserAdminHandler) SetDateOfBirth(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	// An empty date clears it
+	var req struct {
+		DateOfBirth string `json:"date_of_birth"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var dateOfBirth *time.Time
+	if req.DateOfBirth != "" {
+		parsed, err := time.Parse("2006-01-02", req.DateOfBirth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date_of_birth must be YYYY-MM-DD"})
+			return
+		}
+		dateOfBirth = &parsed
+	}
+
+	if err := h.authService.SetUserDateOfBirth(c.Request.Context(), userID, dateOfBirth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Date of birth updated"})
+}
+
+// =========================
+// FORCE LOGOUT (Admin)
+// =========================
+func (h *UserAdminHandler) ForceLogout(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.ForceLogout(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
+}
+
+// =========================
+// DELETE USER (Admin) — soft delete
+// =========================
+func (h *UserAdminHandler) DeleteUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	// Deleting would strand their patients; offboarding hands them over
+	open, err := h.recordService.OpenCareAssignments(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check care assignments"})
+		return
+	}
+	if open > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this user still has care assignments — offboard them to a successor first"})
+		return
+	}
+
+	if err := h.authService.DeleteUser(c.Request.Context(), adminID, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// =========================
+// OFFBOARD USER (Admin) — disable, sign out and hand over patients
+// =========================
+func (h *UserAdminHandler) OffboardUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		SuccessorID uint `json:"successor_id"`
+	}
+
+	// The body is optional for users with no patients or care assignments
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	adminID := getUserID(c)
+	if adminID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	// Any staff role can sit on a care team, so anyone still assigned to
+	// patients needs someone to hand them to
+	if req.SuccessorID == 0 {
+		open, err := h.recordService.OpenCareAssignments(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check care assignments"})
+			return
+		}
+		if open > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a successor is required to hand over this user's care assignments"})
+			return
+		}
+	}
+
+	user, err := h.authService.OffboardUser(c.Request.Context(), adminID, userID, req.SuccessorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "User offboarded"}
+	if user.Role == "doctor" {
+		moved, err := h.recordService.ReassignDoctor(c.Request.Context(), user.ID, req.SuccessorID)
+		if err != nil {
+			// The account is already disabled; repeating the request retries the transfer
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User disabled but patient transfer failed — retry offboarding"})
+			return
+		}
+		response["records_reassigned"] = moved
+	}
+	if req.SuccessorID != 0 {
+		handover, err := h.recordService.TransferCareAssignments(c.Request.Context(), user.ID, req.SuccessorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User disabled but care team transfer failed — retry offboarding"})
+			return
+		}
+		response["care_assignments_transferred"] = handover.Assignments
+		response["consents_transferred"] = handover.Consents
+		// The successor cannot open these patients' records until they consent
+		response["patients_without_consent"] = handover.WithoutConsent
+		response["successor_id"] = req.SuccessorID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// userIDParam parses :user_id, responding with 400 when it is malformed
+func userIDParam(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(userID), true
+}
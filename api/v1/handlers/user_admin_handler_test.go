@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/khawsic/health/internal/auth"
+	record "github.com/khawsic/health/internal/records"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestDeleteUserRefusesOpenCareAssignments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:delete_user?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&auth.User{}, &auth.Session{}, &auth.RefreshToken{}, &record.CareAssignment{}); err != nil {
+		t.Fatal(err)
+	}
+
+	nurse := auth.User{Name: "Nurse", Email: "nurse@example.com", Role: "nurse", Status: auth.StatusActive}
+	db.Create(&nurse)
+	assignment := record.CareAssignment{
+		PatientID:   1,
+		ClinicianID: nurse.ID,
+		Status:      record.CareActive,
+		StartsAt:    time.Now().Add(-time.Hour),
+		RequestedBy: nurse.ID,
+	}
+	db.Create(&assignment)
+
+	h := NewUserAdminHandler(auth.NewService(db, "secret", "", nil), record.NewService(db, "", nil))
+	r := gin.New()
+	r.DELETE("/users/:user_id", func(c *gin.Context) {
+		c.Set("user_id", uint(999))
+	}, h.DeleteUser)
+
+	deleteNurse := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/"+strconv.FormatUint(uint64(nurse.ID), 10), nil))
+		return w.Code
+	}
+
+	if code := deleteNurse(); code != http.StatusBadRequest {
+		t.Fatalf("delete with an open care assignment answered %d, want %d", code, http.StatusBadRequest)
+	}
+	var remaining int64
+	db.Model(&auth.User{}).Where("id = ?", nurse.ID).Count(&remaining)
+	if remaining != 1 {
+		t.Fatal("user was deleted while still assigned to a patient")
+	}
+
+	db.Model(&assignment).Update("status", record.CareEnded)
+	if code := deleteNurse(); code != http.StatusOK {
+		t.Fatalf("delete after the assignment ended answered %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/auth"
+)
+
+type WebAuthnHandler struct {
+	authService *auth.Service
+}
+
+func NewWebAuthnHandler(authService *auth.Service) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		authService: authService,
+	}
+}
+
+// =========================
+// START PASSKEY REGISTRATION
+// =========================
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	options, sessionToken, err := h.authService.BeginPasskeyRegistration(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"options":       options,
+		"session_token": sessionToken,
+	})
+}
+
+// =========================
+// FINISH PASSKEY REGISTRATION
+// =========================
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+
+	var req struct {
+		SessionToken string          `json:"session_token" binding:"required"`
+		Name         string          `json:"name"`
+		Credential   json.RawMessage `json:"credential" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	credential, err := h.authService.FinishPasskeyRegistration(userID, req.SessionToken, req.Name, req.Credential)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Passkey registered",
+		"credential": passkeyResponse(credential),
+	})
+}
+
+// =========================
+// START PASSKEY LOGIN
+// =========================
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	options, sessionToken, err := h.authService.BeginPasskeyLogin()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"options":       options,
+		"session_token": sessionToken,
+	})
+}
+
+// =========================
+// FINISH PASSKEY LOGIN
+// =========================
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+
+	var req struct {
+		SessionToken string          `json:"session_token" binding:"required"`
+		Credential   json.RawMessage `json:"credential" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.authService.FinishPasskeyLogin(c.Request.Context(), req.SessionToken, req.Credential)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+// =========================
+// LIST PASSKEYS
+// =========================
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	credentials, err := h.authService.ListPasskeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch passkeys"})
+		return
+	}
+
+	response := make([]gin.H, len(credentials))
+	for i := range credentials {
+		response[i] = passkeyResponse(&credentials[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// =========================
+// DELETE PASSKEY
+// =========================
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	credentialIDUint, err := strconv.ParseUint(c.Param("credential_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential ID"})
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.DeletePasskey(userID, uint(credentialIDUint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey removed"})
+}
+
+// =========================
+// TOGGLE PASSKEY-ONLY LOGIN (staff)
+// =========================
+func (h *WebAuthnHandler) SetPasskeyOnly(c *gin.Context) {
+
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := h.authService.SetPasskeyOnly(userID, *req.Enabled); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey-only login updated"})
+}
+
+// =========================
+// Helper: Passkey response
+// =========================
+func passkeyResponse(credential *auth.WebAuthnCredential) gin.H {
+	return gin.H{
+		"id":            credential.ID,
+		"name":          credential.Name,
+		"clone_warning": credential.CloneWarning,
+		"last_used_at":  credential.LastUsedAt,
+		"created_at":    credential.CreatedAt,
+	}
+}
@@ -1,15 +1,12 @@
 package v1
 
 import (
-	"time"
-
 	"github.com/gin-gonic/gin"
 	"github.com/khawsic/health/api/v1/handlers"
 	"github.com/khawsic/health/internal/app"
+	"github.com/khawsic/health/internal/authz"
 	"github.com/khawsic/health/internal/middleware"
-	"github.com/ulule/limiter/v3"
-	ginlimiter "github.com/ulule/limiter/v3/drivers/middleware/gin"
-	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/khawsic/health/internal/throttle"
 )
 
 func RegisterRoutes(r *gin.Engine, application *app.App) {
@@ -17,18 +14,34 @@ func RegisterRoutes(r *gin.Engine, application *app.App) {
 	authHandler := handlers.NewAuthHandler(application.AuthService)
 	recordHandler := handlers.NewRecordHandler(application.RecordService)
 	adminHandler := handlers.NewAdminHandler(application.AuditService, application.RecordService)
-	healthHandler := handlers.NewHealthHandler(application.DB)
+	mfaHandler := handlers.NewMFAHandler(application.AuthService)
+	webAuthnHandler := handlers.NewWebAuthnHandler(application.AuthService)
+	healthHandler := handlers.NewHealthHandler(application.DB, application.AuditService)
+	keyHandler := handlers.NewKeyHandler(application.Keyring)
+	sessionHandler := handlers.NewSessionHandler(application.AuthService)
+	oidcHandler := handlers.NewOIDCHandler(application.AuthService)
+	registrationHandler := handlers.NewRegistrationHandler(application.AuthService)
+	userAdminHandler := handlers.NewUserAdminHandler(application.AuthService, application.RecordService)
+	authzHandler := handlers.NewAuthzHandler(application.Authorizer)
+	careTeamHandler := handlers.NewCareTeamHandler(application.RecordService)
+	consentHandler := handlers.NewConsentHandler(application.RecordService)
+	proxyHandler := handlers.NewProxyHandler(application.RecordService)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(application.AuthService)
 
 	// =========================
 	// Rate Limiter Setup
 	// =========================
-	rate := limiter.Rate{
-		Period: 1 * time.Minute,
-		Limit:  10,
-	}
-	store := memory.NewStore()
-	instance := limiter.New(store, rate)
-	rateLimitMiddleware := ginlimiter.NewMiddleware(instance)
+	// Each public route is limited by its class, per client address and per
+	// address and account; see throttle.DefaultRules
+	limit := application.Throttler.Limit
+
+	// Routes that can finish a login name the browser with a device cookie
+	device := middleware.DeviceCookieMiddleware()
+
+	// =========================
+	// JWKS — public keys for verifying access tokens
+	// =========================
+	r.GET("/.well-known/jwks.json", keyHandler.JWKS)
 
 	v1Group := r.Group("/api/v1")
 
@@ -41,52 +54,180 @@ func RegisterRoutes(r *gin.Engine, application *app.App) {
 	// PUBLIC ROUTES — rate limited
 	// =========================
 	public := v1Group.Group("/")
-	public.Use(rateLimitMiddleware)
 
-	public.POST("/register", authHandler.Register)
-	public.POST("/login", authHandler.Login)
-	public.POST("/refresh", authHandler.Refresh)
-	public.POST("/logout", authHandler.Logout)
-	public.POST("/password-reset/request", authHandler.RequestPasswordReset)
-	public.POST("/password-reset/confirm", authHandler.ResetPassword)
+	public.POST("/register", limit(throttle.ClassRegister), authHandler.Register)
+	public.POST("/verify-email", limit(throttle.ClassRegister), registrationHandler.VerifyEmail)
+	public.POST("/verify-email/resend", limit(throttle.ClassRegister), registrationHandler.ResendVerification)
+	public.POST("/login", limit(throttle.ClassLogin), device, authHandler.Login)
+	public.POST("/login/mfa", limit(throttle.ClassLogin), device, authHandler.VerifyMFA)
+	public.POST("/login/mfa/enroll", limit(throttle.ClassLogin), authHandler.BeginMFAEnrollment)
+	public.POST("/login/mfa/enroll/confirm", limit(throttle.ClassLogin), device, authHandler.ConfirmMFAEnrollment)
+	public.POST("/webauthn/login/begin", limit(throttle.ClassLogin), webAuthnHandler.BeginLogin)
+	public.POST("/webauthn/login/finish", limit(throttle.ClassLogin), device, webAuthnHandler.FinishLogin)
+	public.GET("/oidc/providers", limit(throttle.ClassDefault), oidcHandler.ListProviders)
+	public.GET("/oidc/:provider/login", limit(throttle.ClassLogin), oidcHandler.BeginLogin)
+	public.GET("/oidc/:provider/callback", limit(throttle.ClassLogin), device, oidcHandler.Callback)
+	public.POST("/refresh", limit(throttle.ClassRefresh), authHandler.Refresh)
+	public.POST("/logout", limit(throttle.ClassRefresh), authHandler.Logout)
+	public.POST("/password-reset/request", limit(throttle.ClassPasswordReset), authHandler.RequestPasswordReset)
+	public.POST("/password-reset/confirm", limit(throttle.ClassPasswordReset), authHandler.ResetPassword)
+	public.GET("/password-policy", limit(throttle.ClassDefault), authHandler.PasswordPolicy)
 
 	// =========================
 	// PROTECTED ROUTES
 	// =========================
 	protected := v1Group.Group("/")
-	protected.Use(middleware.AuthMiddleware(application.Config.JWTSecret))
+	protected.Use(middleware.AuthMiddleware(application.Keyring, application.AuthService, application.AuthService))
+
+	// Routes for a person's own sign-in are closed to API keys
+	self := protected.Group("/")
+	self.Use(middleware.SessionOnly())
+
+	// -------------------------
+	// MFA SELF-SERVICE (any role)
+	// -------------------------
+	self.POST("/mfa/totp/enroll", mfaHandler.BeginEnrollment)
+	self.POST("/mfa/totp/confirm", mfaHandler.ConfirmEnrollment)
+	self.POST("/mfa/totp/disable", mfaHandler.Disable)
+	self.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+	// -------------------------
+	// SESSIONS (any role)
+	// -------------------------
+	self.POST("/me/step-up", sessionHandler.StepUp)
+	self.GET("/me/sessions", sessionHandler.ListSessions)
+	self.DELETE("/me/sessions", sessionHandler.RevokeOtherSessions)
+	self.DELETE("/me/sessions/:session_id", sessionHandler.RevokeSession)
+	self.GET("/me/login-history", sessionHandler.LoginHistory)
+
+	// -------------------------
+	// PASSKEYS (any role; passkey-only is staff only)
+	// -------------------------
+	self.POST("/webauthn/register/begin", webAuthnHandler.BeginRegistration)
+	self.POST("/webauthn/register/finish", webAuthnHandler.FinishRegistration)
+	self.GET("/webauthn/credentials", webAuthnHandler.ListCredentials)
+	self.DELETE("/webauthn/credentials/:credential_id", webAuthnHandler.DeleteCredential)
+	self.PUT("/webauthn/passkey-only", webAuthnHandler.SetPasskeyOnly)
+
+	// -------------------------
+	// PERMISSIONS (any role)
+	// -------------------------
+	self.GET("/me/permissions", authzHandler.MyPermissions)
+	self.POST("/me/permissions/explain", authzHandler.ExplainMine)
+
+	// Every route below names the permission it needs; roles grant
+	// permissions and access policies add attribute conditions
+	can := func(permission string) gin.HandlerFunc {
+		return middleware.RequirePermission(application.Authorizer, permission)
+	}
+
+	// Sensitive routes also need the user to have authenticated within
+	// STEP_UP_MAX_AGE, at login or through /me/step-up
+	recentAuth := middleware.RequireRecentAuth(application.AuthService, application.StepUpMaxAge)
 
 	// -------------------------
 	// ADMIN ROUTES
 	// -------------------------
 	admin := protected.Group("/admin")
-	admin.Use(middleware.RoleMiddleware("admin"))
 
-	admin.GET("/records", adminHandler.GetAllRecords)
-	admin.GET("/audit-logs", adminHandler.GetAuditLogs)
-	admin.GET("/audit-logs/filter", adminHandler.FilterAuditLogs)
-	admin.GET("/audit-logs/verify", adminHandler.VerifyAuditChain)
+	admin.GET("/records", can(authz.PermRecordReadAll), recentAuth, adminHandler.GetAllRecords)
+	admin.GET("/audit-logs", can(authz.PermAuditRead), adminHandler.GetAuditLogs)
+	admin.GET("/audit-logs/filter", can(authz.PermAuditRead), adminHandler.FilterAuditLogs)
+	admin.GET("/audit-logs/search", can(authz.PermAuditRead), adminHandler.SearchAuditLogs)
+	admin.GET("/audit-logs/export", can(authz.PermAuditExport), adminHandler.ExportAuditLogs)
+	admin.GET("/audit-logs/verify", can(authz.PermAuditRead), adminHandler.VerifyAuditChain)
+	admin.GET("/mfa-policies", can(authz.PermMFAPolicyManage), mfaHandler.GetPolicies)
+	admin.PUT("/mfa-policies/:role", can(authz.PermMFAPolicyManage), mfaHandler.SetPolicy)
+	admin.GET("/jwt-keys", can(authz.PermKeyManage), keyHandler.ListKeys)
+	admin.POST("/jwt-keys/rotate", can(authz.PermKeyManage), keyHandler.Rotate)
+	admin.GET("/doctor-applications", can(authz.PermDoctorApplicationReview), registrationHandler.ListDoctorApplications)
+	admin.POST("/doctor-applications/:application_id/approve", can(authz.PermDoctorApplicationReview), registrationHandler.ApproveDoctor)
+	admin.POST("/doctor-applications/:application_id/reject", can(authz.PermDoctorApplicationReview), registrationHandler.RejectDoctor)
+	admin.GET("/users", can(authz.PermUserRead), userAdminHandler.ListUsers)
+	admin.GET("/users/:user_id", can(authz.PermUserRead), userAdminHandler.GetUser)
+	admin.POST("/users/:user_id/unlock", can(authz.PermUserManage), userAdminHandler.UnlockUser)
+	admin.POST("/users/:user_id/disable", can(authz.PermUserManage), userAdminHandler.DisableUser)
+	admin.POST("/users/:user_id/enable", can(authz.PermUserManage), userAdminHandler.EnableUser)
+	admin.PUT("/users/:user_id/role", can(authz.PermUserManage), userAdminHandler.ChangeRole)
+	admin.PUT("/users/:user_id/department", can(authz.PermUserManage), userAdminHandler.SetDepartment)
+	admin.PUT("/users/:user_id/date-of-birth", can(authz.PermUserManage), userAdminHandler.SetDateOfBirth)
+	admin.POST("/users/:user_id/logout", can(authz.PermUserManage), userAdminHandler.ForceLogout)
+	admin.POST("/users/:user_id/offboard", can(authz.PermUserManage), userAdminHandler.OffboardUser)
+	admin.DELETE("/users/:user_id", can(authz.PermUserManage), userAdminHandler.DeleteUser)
+	admin.GET("/permissions", can(authz.PermRoleManage), authzHandler.ListPermissions)
+	admin.GET("/roles", can(authz.PermRoleManage), authzHandler.ListRoles)
+	admin.POST("/roles", can(authz.PermRoleManage), authzHandler.CreateRole)
+	admin.PUT("/roles/:role", can(authz.PermRoleManage), authzHandler.UpdateRole)
+	admin.DELETE("/roles/:role", can(authz.PermRoleManage), authzHandler.DeleteRole)
+	admin.GET("/access-policies", can(authz.PermPolicyManage), authzHandler.ListPolicies)
+	admin.POST("/access-policies", can(authz.PermPolicyManage), authzHandler.CreatePolicy)
+	admin.PUT("/access-policies/:policy_id", can(authz.PermPolicyManage), authzHandler.UpdatePolicy)
+	admin.DELETE("/access-policies/:policy_id", can(authz.PermPolicyManage), authzHandler.DeletePolicy)
+	admin.POST("/access-policies/explain", can(authz.PermPolicyManage), authzHandler.Explain)
+	admin.GET("/care-assignments", can(authz.PermCareTeamManage), careTeamHandler.ListAssignments)
+	admin.POST("/care-assignments", can(authz.PermCareTeamManage), careTeamHandler.Assign)
+	admin.POST("/care-assignments/:assignment_id/approve", can(authz.PermCareTeamManage), careTeamHandler.Approve)
+	admin.POST("/care-assignments/:assignment_id/decline", can(authz.PermCareTeamManage), careTeamHandler.Decline)
+	admin.POST("/care-assignments/:assignment_id/end", can(authz.PermCareTeamManage), careTeamHandler.EndAssignment)
+	admin.GET("/proxy-relationships", can(authz.PermProxyVerify), proxyHandler.ListRelationships)
+	admin.POST("/proxy-relationships/:relationship_id/approve", can(authz.PermProxyVerify), proxyHandler.Approve)
+	admin.POST("/proxy-relationships/:relationship_id/decline", can(authz.PermProxyVerify), proxyHandler.Decline)
+	admin.POST("/proxy-relationships/:relationship_id/revoke", can(authz.PermProxyVerify), proxyHandler.RevokeAny)
+	admin.GET("/service-accounts", can(authz.PermServiceAccountManage), serviceAccountHandler.ListServiceAccounts)
+	admin.POST("/service-accounts", can(authz.PermServiceAccountManage), serviceAccountHandler.CreateServiceAccount)
+	admin.GET("/service-accounts/:user_id/api-keys", can(authz.PermServiceAccountManage), serviceAccountHandler.ListAPIKeys)
+	admin.POST("/service-accounts/:user_id/api-keys", can(authz.PermServiceAccountManage), serviceAccountHandler.CreateAPIKey)
+	admin.POST("/api-keys/:key_id/revoke", can(authz.PermServiceAccountManage), serviceAccountHandler.RevokeAPIKey)
 
 	// -------------------------
-	// DOCTOR ROUTES
+	// CLINICAL ROUTES — doctors, nurses, pharmacists and any role granted
+	// record permissions
 	// -------------------------
 	doctor := protected.Group("/doctor")
-	doctor.Use(middleware.RoleMiddleware("doctor"))
 
-	doctor.GET("/dashboard", recordHandler.DoctorDashboard)
-	doctor.POST("/records", recordHandler.CreateRecord)
-	doctor.PUT("/records/:record_id", recordHandler.UpdateRecord)
-	doctor.DELETE("/records/:record_id", recordHandler.DeleteRecord)
-	doctor.GET("/records/:record_id/history", recordHandler.GetVersionHistory)
-	doctor.GET("/patients/:patient_id/records", recordHandler.SearchPatientRecords)
-	doctor.POST("/records/emergency/:record_id", recordHandler.EmergencyAccess)
+	doctor.GET("/dashboard", can(authz.PermRecordRead), recordHandler.DoctorDashboard)
+	doctor.POST("/records", can(authz.PermRecordWrite), recordHandler.CreateRecord)
+	doctor.PUT("/records/:record_id", can(authz.PermRecordWrite), recordHandler.UpdateRecord)
+	doctor.DELETE("/records/:record_id", can(authz.PermRecordDelete), recentAuth, recordHandler.DeleteRecord)
+	doctor.GET("/records/:record_id/history", can(authz.PermRecordHistory), recordHandler.GetVersionHistory)
+	doctor.GET("/patients/:patient_id/records", can(authz.PermRecordRead), recordHandler.SearchPatientRecords)
+	doctor.POST("/records/emergency/:record_id", can(authz.PermRecordEmergency), recentAuth, recordHandler.EmergencyAccess)
+
+	// -------------------------
+	// CARE TEAM — record access outside an active assignment needs
+	// break-glass
+	// -------------------------
+	care := protected.Group("/care")
+
+	care.GET("/patients", can(authz.PermCareTeamRead), careTeamHandler.MyPatients)
+	care.POST("/assignments", can(authz.PermCareTeamRequest), careTeamHandler.RequestAssignment)
+	care.POST("/assignments/:assignment_id/end", can(authz.PermCareTeamRequest), careTeamHandler.EndMyAssignment)
+
+	// -------------------------
+	// PROXIES — parents, guardians and caregivers acting for a patient
+	// -------------------------
+	proxy := protected.Group("/proxy")
+
+	proxy.POST("/requests", can(authz.PermProxyRequest), proxyHandler.RequestProxy)
+	proxy.GET("/relationships", can(authz.PermProxyRequest), proxyHandler.MySubjects)
+	proxy.POST("/relationships/:relationship_id/revoke", can(authz.PermProxyRequest), proxyHandler.Revoke)
 
 	// -------------------------
 	// PATIENT ROUTES
 	// -------------------------
 	patient := protected.Group("/patient")
-	patient.Use(middleware.RoleMiddleware("patient"))
 
-	patient.GET("/dashboard", recordHandler.PatientDashboard)
-	patient.GET("/records", recordHandler.GetPatientRecords)
+	// Routes with actingAs serve a verified proxy acting for the patient
+	// named in X-Acting-As
+	actingAs := middleware.ActingAs(application.RecordService)
+
+	patient.GET("/dashboard", can(authz.PermRecordReadOwn), recordHandler.PatientDashboard)
+	patient.GET("/records", can(authz.PermRecordReadOwn), actingAs, recordHandler.GetPatientRecords)
+	patient.GET("/care-team", can(authz.PermRecordReadOwn), actingAs, careTeamHandler.PatientCareTeam)
+	patient.GET("/proxies", can(authz.PermRecordReadOwn), proxyHandler.MyProxies)
+	patient.POST("/proxies/:relationship_id/revoke", can(authz.PermRecordReadOwn), proxyHandler.Revoke)
+	patient.GET("/consents", can(authz.PermConsentManage), consentHandler.ListConsents)
+	patient.POST("/consents", can(authz.PermConsentManage), consentHandler.GrantConsent)
+	patient.DELETE("/consents/:consent_id", can(authz.PermConsentManage), consentHandler.RevokeConsent)
+	patient.GET("/consents/activity", can(authz.PermConsentManage), consentHandler.Activity)
 }
\ No newline at end of file
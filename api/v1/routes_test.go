@@ -0,0 +1,222 @@
+package v1
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/khawsic/health/internal/app"
+	"github.com/khawsic/health/internal/auth"
+	"github.com/khawsic/health/internal/authz"
+	"github.com/khawsic/health/internal/keyring"
+	"github.com/khawsic/health/internal/middleware"
+	"github.com/khawsic/health/internal/security"
+	"github.com/khawsic/health/internal/throttle"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testEncryptionKey = "0123456789abcdef0123456789abcdef"
+
+// publicRoutes need no credentials at all
+var publicRoutes = map[string]bool{
+	"GET /.well-known/jwks.json":            true,
+	"GET /api/v1/health":                    true,
+	"POST /api/v1/register":                 true,
+	"POST /api/v1/verify-email":             true,
+	"POST /api/v1/verify-email/resend":      true,
+	"POST /api/v1/login":                    true,
+	"POST /api/v1/login/mfa":                true,
+	"POST /api/v1/login/mfa/enroll":         true,
+	"POST /api/v1/login/mfa/enroll/confirm": true,
+	"POST /api/v1/webauthn/login/begin":     true,
+	"POST /api/v1/webauthn/login/finish":    true,
+	"GET /api/v1/oidc/providers":            true,
+	"GET /api/v1/oidc/:provider/login":      true,
+	"GET /api/v1/oidc/:provider/callback":   true,
+	"POST /api/v1/refresh":                  true,
+	"POST /api/v1/logout":                   true,
+	"POST /api/v1/password-reset/request":   true,
+	"POST /api/v1/password-reset/confirm":   true,
+	"GET /api/v1/password-policy":           true,
+}
+
+// selfRoutes need a signed-in user but no permission: they only touch the
+// caller's own sign-in settings
+var selfRoutes = map[string]bool{
+	"POST /api/v1/mfa/totp/enroll":                       true,
+	"POST /api/v1/mfa/totp/confirm":                      true,
+	"POST /api/v1/mfa/totp/disable":                      true,
+	"POST /api/v1/mfa/recovery-codes":                    true,
+	"POST /api/v1/me/step-up":                            true,
+	"GET /api/v1/me/sessions":                            true,
+	"DELETE /api/v1/me/sessions":                         true,
+	"DELETE /api/v1/me/sessions/:session_id":             true,
+	"GET /api/v1/me/login-history":                       true,
+	"POST /api/v1/webauthn/register/begin":               true,
+	"POST /api/v1/webauthn/register/finish":              true,
+	"GET /api/v1/webauthn/credentials":                   true,
+	"DELETE /api/v1/webauthn/credentials/:credential_id": true,
+	"PUT /api/v1/webauthn/passkey-only":                  true,
+	"GET /api/v1/me/permissions":                         true,
+	"POST /api/v1/me/permissions/explain":                true,
+}
+
+var pathParam = regexp.MustCompile(`:[a-z_]+`)
+
+// TestEveryRouteHasAnAuthorizationRule fails when a route is added without
+// naming the permission it needs. Every route other than the public and
+// self-service ones above must refuse a signed-in user whose role grants
+// nothing, citing a permission from the catalogue.
+func TestEveryRouteHasAnAuthorizationRule(t *testing.T) {
+	r, token := newTestRouter(t)
+
+	catalogue := make(map[string]bool, len(authz.Catalogue))
+	for _, info := range authz.Catalogue {
+		catalogue[info.Name] = true
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if publicRoutes[key] {
+			continue
+		}
+
+		url := pathParam.ReplaceAllString(route.Path, "1")
+
+		w := serve(r, route.Method, url, "")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: anonymous request answered %d, want 401", key, w.Code)
+		}
+
+		if selfRoutes[key] {
+			continue
+		}
+
+		w = serve(r, route.Method, url, token)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: caller without permissions answered %d, want 403 — does the route name a permission?", key, w.Code)
+			continue
+		}
+		var body struct {
+			Permission string `json:"permission"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !catalogue[body.Permission] {
+			t.Errorf("%s: refused without a catalogued permission (got %q)", key, body.Permission)
+		}
+	}
+
+	// Stale entries would silently exempt a route added later at that path
+	for key := range publicRoutes {
+		if !registered[key] {
+			t.Errorf("public route %s is not registered", key)
+		}
+	}
+	for key := range selfRoutes {
+		if !registered[key] {
+			t.Errorf("self-service route %s is not registered", key)
+		}
+	}
+}
+
+func serve(r *gin.Engine, method, url, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// newTestRouter registers the routes over an in-memory database and
+// returns an access token for a user whose role grants no permissions
+func newTestRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:routes?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&auth.User{}, &auth.DeniedToken{}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := keyring.New(db, testEncryptionKey, auth.AccessTokenExpiry)
+	if err := keys.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	seed, err := security.Encrypt(testEncryptionKey, hex.EncodeToString(private.Seed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&keyring.SigningKey{ID: "test", PrivateKey: seed, PublicKey: public, ActivatesAt: time.Now().Add(-time.Minute)})
+	if err := keys.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	authorizer := authz.NewService(db)
+	if err := authorizer.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&authz.Role{Name: "no-access"})
+	if err := authorizer.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	user := auth.User{Name: "Nobody", Email: "nobody@example.com", Role: "no-access", Status: auth.StatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	throttler, err := throttle.New(memory.NewStore(), throttle.DefaultRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware())
+	RegisterRoutes(r, &app.App{
+		DB:           db,
+		AuthService:  auth.NewService(db, "secret", testEncryptionKey, keys),
+		Authorizer:   authorizer,
+		Keyring:      keys,
+		Throttler:    throttler,
+		StepUpMaxAge: 5 * time.Minute,
+	})
+
+	kid, signer, err := keys.Signer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"user_id": user.ID,
+		"role":    user.Role,
+		"sid":     "session",
+		"jti":     "token",
+		"ver":     user.TokenVersion,
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, signed
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var sha1Line = regexp.MustCompile(`^[0-9A-Fa-f]{40}(:\d+)?$`)
+
+// Builds a BREACHED_PASSWORDS_DIR from a small word list for development.
+// BREACH_SOURCE is a file with one plaintext password per line, or with
+// "SHA1:COUNT" lines when BREACH_SOURCE_FORMAT=sha1. Production corpora
+// should be fetched directly in range format with the HIBP downloader.
+func main() {
+	source := os.Getenv("BREACH_SOURCE")
+	if source == "" {
+		log.Fatal("❌ BREACH_SOURCE is required")
+	}
+	dir := env("BREACHED_PASSWORDS_DIR", ".breached-passwords")
+	hashed := env("BREACH_SOURCE_FORMAT", "plain") == "sha1"
+
+	file, err := os.Open(source)
+	if err != nil {
+		log.Fatal("❌ Failed to open BREACH_SOURCE:", err)
+	}
+	defer file.Close()
+
+	ranges := make(map[string]map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		digest, count := "", "1"
+		if hashed {
+			if !sha1Line.MatchString(line) {
+				log.Printf("⚠️  Skipping malformed line %q", line)
+				continue
+			}
+			digest, count, _ = strings.Cut(line, ":")
+			if count == "" {
+				count = "1"
+			}
+		} else {
+			sum := sha1.Sum([]byte(line))
+			digest = hex.EncodeToString(sum[:])
+		}
+
+		digest = strings.ToUpper(digest)
+		prefix, suffix := digest[:5], digest[5:]
+		if ranges[prefix] == nil {
+			ranges[prefix] = make(map[string]string)
+		}
+		ranges[prefix][suffix] = count
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal("❌ Failed to read BREACH_SOURCE:", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatal("❌ Failed to create output directory:", err)
+	}
+
+	total := 0
+	for prefix, suffixes := range ranges {
+		lines := make([]string, 0, len(suffixes))
+		for suffix, count := range suffixes {
+			lines = append(lines, fmt.Sprintf("%s:%s", suffix, count))
+		}
+		sort.Strings(lines)
+		total += len(lines)
+
+		path := filepath.Join(dir, prefix+".txt")
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			log.Fatal("❌ Failed to write range file:", err)
+		}
+	}
+
+	log.Printf("✅ Wrote %d hash(es) in %d range file(s) to %s", total, len(ranges), dir)
+}
+
+func env(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/khawsic/health/internal/mockidp"
+)
+
+// Standalone OpenID Connect provider for local development. It signs in a
+// single fixed identity; point an OIDC_PROVIDERS_FILE entry at its issuer.
+func main() {
+	port := env("MOCK_IDP_PORT", "8319")
+
+	idp, err := mockidp.New(mockidp.Config{
+		Issuer:        env("MOCK_IDP_ISSUER", "http://localhost:"+port),
+		ClientID:      env("MOCK_IDP_CLIENT_ID", "healthvault"),
+		ClientSecret:  env("MOCK_IDP_CLIENT_SECRET", "mock-secret"),
+		RedirectURL:   env("MOCK_IDP_REDIRECT_URL", "http://localhost:8080/api/v1/oidc/mock/callback"),
+		Subject:       env("MOCK_IDP_SUBJECT", "mock-user-1"),
+		Email:         env("MOCK_IDP_EMAIL", "sso.doctor@healthvault.com"),
+		EmailVerified: env("MOCK_IDP_EMAIL_VERIFIED", "true") == "true",
+		Name:          env("MOCK_IDP_NAME", "Dr. Mock Single Sign-On"),
+		Groups:        strings.Split(env("MOCK_IDP_GROUPS", "clinicians"), ","),
+		AMR:           strings.Split(env("MOCK_IDP_AMR", "pwd"), ","),
+	})
+	if err != nil {
+		log.Fatal("❌ Failed to start mock IdP:", err)
+	}
+
+	log.Printf("🪪 Mock IdP listening on :%s", port)
+	log.Println("⚠️  Development use only — every login is approved automatically")
+
+	if err := http.ListenAndServe(":"+port, idp); err != nil {
+		log.Fatal("❌ Mock IdP stopped:", err)
+	}
+}
+
+func env(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
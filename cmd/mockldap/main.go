@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/khawsic/health/internal/mockldap"
+)
+
+// Standalone Active Directory-shaped LDAP server for local development.
+// Point an LDAP_DIRECTORIES_FILE entry at ldap://localhost:<port>.
+// MOCK_LDAP_USERS_FILE may name a JSON array of users to replace the defaults.
+func main() {
+	port := env("MOCK_LDAP_PORT", "10389")
+
+	users := []mockldap.User{
+		{Username: "jclinician", Password: "Clinician@123", Email: "jclinician@healthvault.com", Name: "Dr. Jo Clinician", Groups: []string{"Clinicians"}},
+		{Username: "itadmin", Password: "ItAdmin@123", Email: "itadmin@healthvault.com", Name: "IT Administrator", Groups: []string{"HealthVault Admins"}},
+		{Username: "reception", Password: "Reception@123", Email: "reception@healthvault.com", Name: "Front Desk", Groups: []string{"Reception"}},
+	}
+	if path := os.Getenv("MOCK_LDAP_USERS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal("❌ Failed to read MOCK_LDAP_USERS_FILE:", err)
+		}
+		if err := json.Unmarshal(data, &users); err != nil {
+			log.Fatal("❌ Invalid MOCK_LDAP_USERS_FILE:", err)
+		}
+	}
+
+	directory, err := mockldap.New(mockldap.Config{
+		BaseDN:       env("MOCK_LDAP_BASE_DN", "DC=healthvault,DC=local"),
+		BindDN:       env("MOCK_LDAP_BIND_DN", "CN=svc-healthvault,OU=Service,DC=healthvault,DC=local"),
+		BindPassword: env("MOCK_LDAP_BIND_PASSWORD", "mock-bind-secret"),
+		Users:        users,
+	})
+	if err != nil {
+		log.Fatal("❌ Failed to start mock LDAP:", err)
+	}
+
+	log.Printf("📇 Mock LDAP listening on :%s with %d user(s)", port, len(users))
+	log.Println("⚠️  Development use only — passwords are stored in plain text")
+
+	if err := directory.Run(":" + port); err != nil {
+		log.Fatal("❌ Mock LDAP stopped:", err)
+	}
+}
+
+func env(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
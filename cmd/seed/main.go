@@ -3,9 +3,10 @@ package main
 import (
 	"log"
 
+	"github.com/khawsic/health/internal/app"
 	"github.com/khawsic/health/internal/config"
+	"github.com/khawsic/health/internal/crypto"
 	"github.com/khawsic/health/pkg/database"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -18,12 +19,15 @@ type User struct {
 	FailedAttempts int    `gorm:"default:0"`
 }
 
+// passwords hashes seeded passwords the same way the server does
+var passwords *crypto.Passwords
+
 func hashPassword(password string) string {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	hash, err := passwords.Hash(password)
 	if err != nil {
 		log.Fatal("Failed to hash password:", err)
 	}
-	return string(hash)
+	return hash
 }
 
 func seedUser(db *gorm.DB, name, email, password, role string) {
@@ -57,6 +61,8 @@ func main() {
 		log.Fatal("❌ DB_URL is required")
 	}
 
+	passwords = app.PasswordHasher(cfg)
+
 	db := database.Connect(cfg.DBUrl)
 
 	sqlDB, err := db.DB()
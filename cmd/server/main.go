@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/khawsic/health/api/v1"
@@ -13,8 +15,22 @@ func main() {
 
 	application := app.New()
 
+	// Background audit chain verification
+	application.AuditMonitor.Start(context.Background())
+
+	// Scheduled JWT signing key rotation
+	application.Keyring.StartRotation(context.Background(), application.KeyRotation)
+
+	// Expired rate limit counters
+	application.Throttler.StartPruning(context.Background(), 10*time.Minute)
+
 	r := gin.New()
 
+	// Only listed proxies may report the client address; by default none are
+	if err := r.SetTrustedProxies(application.TrustedProxies); err != nil {
+		log.Fatal("❌ Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// =========================
 	// Global Middleware
 	// =========================
@@ -26,7 +42,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "http://localhost:5174")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID, X-Acting-As")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
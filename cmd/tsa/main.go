@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/khawsic/health/internal/tsa"
+)
+
+// Standalone RFC 3161 Time Stamping Authority for local development.
+// Point TSA_URL at it and TSA_CA_CERT at the printed certificate path.
+func main() {
+	dir := os.Getenv("TSA_LOCAL_DIR")
+	if dir == "" {
+		dir = ".tsa"
+	}
+	port := os.Getenv("TSA_PORT")
+	if port == "" {
+		port = "8318"
+	}
+
+	authority, err := tsa.LoadOrCreateLocalAuthority(dir)
+	if err != nil {
+		log.Fatal("❌ Failed to load local TSA:", err)
+	}
+
+	log.Printf("🕒 Local TSA listening on :%s (certificate: %s/tsa-cert.pem)", port, dir)
+	log.Println("⚠️  Development use only — this TSA provides no trusted time source")
+
+	if err := http.ListenAndServe(":"+port, authority); err != nil {
+		log.Fatal("❌ Local TSA stopped:", err)
+	}
+}
@@ -2,6 +2,8 @@ import axios from 'axios'
 
 const API = axios.create({
   baseURL: 'http://localhost:8080/api/v1',
+  // Sends the device cookie, which login history uses to recognise this browser
+  withCredentials: true,
   headers: {
     'Content-Type': 'application/json',
   },
@@ -16,29 +18,48 @@ API.interceptors.request.use((config) => {
   return config
 })
 
+// Refresh tokens rotate on every use, so concurrent 401s must share one
+// refresh call — replaying the old token would revoke the whole session
+let refreshPromise = null
+
+const refreshSession = async () => {
+  const refreshToken = sessionStorage.getItem('refresh_token')
+  if (!refreshToken) {
+    throw new Error('No refresh token')
+  }
+
+  const res = await axios.post('http://localhost:8080/api/v1/refresh', {
+    refresh_token: refreshToken,
+  })
+
+  sessionStorage.setItem('access_token', res.data.access_token)
+  sessionStorage.setItem('refresh_token', res.data.refresh_token)
+  return res.data.access_token
+}
+
+// Sensitive routes answer 401 with code step_up_required when the login is
+// not recent enough. Refreshing cannot help — the caller should prompt with
+// error.response.data.methods, call stepUp, and retry.
+const isStepUp = (error) =>
+  error.response?.data?.code === 'step_up_required' || error.config?.url === '/me/step-up'
+
 // Auto refresh token on 401
 API.interceptors.response.use(
   (response) => response,
   async (error) => {
     const original = error.config
 
-    if (error.response?.status === 401 && !original._retry) {
+    if (error.response?.status === 401 && !original._retry && !isStepUp(error)) {
       original._retry = true
 
       try {
-        const refreshToken = sessionStorage.getItem('refresh_token')
-        if (!refreshToken) {
-          sessionStorage.clear()
-          window.location.href = '/login'
-          return Promise.reject(error)
+        if (!refreshPromise) {
+          refreshPromise = refreshSession().finally(() => {
+            refreshPromise = null
+          })
         }
 
-        const res = await axios.post('http://localhost:8080/api/v1/refresh', {
-          refresh_token: refreshToken,
-        })
-
-        const newToken = res.data.access_token
-        sessionStorage.setItem('access_token', newToken)
+        const newToken = await refreshPromise
         original.headers.Authorization = `Bearer ${newToken}`
 
         return API(original)
@@ -56,8 +77,14 @@ API.interceptors.response.use(
 export const loginUser = (email, password) =>
   API.post('/login', { email, password })
 
-export const registerUser = (name, email, password, role) =>
-  API.post('/register', { name, email, password, role })
+export const registerUser = (name, email, password, role, license_number = '', notes = '') =>
+  API.post('/register', { name, email, password, role, license_number, notes })
+
+export const verifyEmail = (token) =>
+  API.post('/verify-email', { token })
+
+export const resendVerification = (email) =>
+  API.post('/verify-email/resend', { email })
 
 export const logoutUser = (refreshToken) =>
   API.post('/logout', { refresh_token: refreshToken })
@@ -68,9 +95,21 @@ export const requestPasswordReset = (email) =>
 export const resetPassword = (token, new_password) =>
   API.post('/password-reset/confirm', { token, new_password })
 
+// Step-up — pass { password } or, with MFA enrolled, { code } or
+// { recovery_code }. The new access token replaces the stored one.
+export const stepUp = async (confirmation) => {
+  const res = await API.post('/me/step-up', confirmation)
+  sessionStorage.setItem('access_token', res.data.access_token)
+  return res
+}
+
+// Sign-in attempts on this account, newest first — params: page, page_size
+export const getLoginHistory = (params) =>
+  API.get('/me/login-history', { params })
+
 // Record endpoints
-export const createRecord = (patient_id, diagnosis, treatment) =>
-  API.post('/doctor/records', { patient_id, diagnosis, treatment })
+export const createRecord = (patient_id, diagnosis, treatment, category) =>
+  API.post('/doctor/records', { patient_id, diagnosis, treatment, category })
 
 export const updateRecord = (record_id, diagnosis, treatment) =>
   API.put(`/doctor/records/${record_id}`, { diagnosis, treatment })
@@ -103,6 +142,176 @@ export const filterAuditLogs = (params) =>
 export const verifyAuditChain = () =>
   API.get('/admin/audit-logs/verify')
 
+export const getDoctorApplications = (status = 'pending') =>
+  API.get('/admin/doctor-applications', { params: { status } })
+
+export const approveDoctorApplication = (application_id, notes = '') =>
+  API.post(`/admin/doctor-applications/${application_id}/approve`, { notes })
+
+export const rejectDoctorApplication = (application_id, notes = '') =>
+  API.post(`/admin/doctor-applications/${application_id}/reject`, { notes })
+
+export const getUsers = (params) =>
+  API.get('/admin/users', { params })
+
+export const getUser = (user_id) =>
+  API.get(`/admin/users/${user_id}`)
+
+export const unlockUser = (user_id) =>
+  API.post(`/admin/users/${user_id}/unlock`)
+
+export const disableUser = (user_id) =>
+  API.post(`/admin/users/${user_id}/disable`)
+
+export const enableUser = (user_id) =>
+  API.post(`/admin/users/${user_id}/enable`)
+
+export const changeUserRole = (user_id, role) =>
+  API.put(`/admin/users/${user_id}/role`, { role })
+
+export const forceLogoutUser = (user_id) =>
+  API.post(`/admin/users/${user_id}/logout`)
+
+export const offboardUser = (user_id, successor_id) =>
+  API.post(`/admin/users/${user_id}/offboard`, successor_id ? { successor_id } : {})
+
+export const deleteUser = (user_id) =>
+  API.delete(`/admin/users/${user_id}`)
+
+export const setUserDepartment = (user_id, department) =>
+  API.put(`/admin/users/${user_id}/department`, { department })
+
+// Roles, permissions and access policies
+export const getMyPermissions = () =>
+  API.get('/me/permissions')
+
+export const explainMyAccess = (permission, patient_id) =>
+  API.post('/me/permissions/explain', { permission, patient_id })
+
+export const getPermissionCatalogue = () =>
+  API.get('/admin/permissions')
+
+export const getRoles = () =>
+  API.get('/admin/roles')
+
+export const createRole = (name, description, permissions) =>
+  API.post('/admin/roles', { name, description, permissions })
+
+export const updateRole = (name, description, permissions) =>
+  API.put(`/admin/roles/${name}`, { description, permissions })
+
+export const deleteRole = (name) =>
+  API.delete(`/admin/roles/${name}`)
+
+export const getAccessPolicies = () =>
+  API.get('/admin/access-policies')
+
+export const createAccessPolicy = (policy) =>
+  API.post('/admin/access-policies', policy)
+
+export const updateAccessPolicy = (policy_id, policy) =>
+  API.put(`/admin/access-policies/${policy_id}`, policy)
+
+export const deleteAccessPolicy = (policy_id) =>
+  API.delete(`/admin/access-policies/${policy_id}`)
+
+export const explainAccess = (params) =>
+  API.post('/admin/access-policies/explain', params)
+
+// Care team
+export const getMyPatients = () =>
+  API.get('/care/patients')
+
+export const requestCareAssignment = (patient_id, reason, starts_at, ends_at) =>
+  API.post('/care/assignments', { patient_id, reason, starts_at, ends_at })
+
+export const leaveCareTeam = (assignment_id) =>
+  API.post(`/care/assignments/${assignment_id}/end`)
+
+export const getMyCareTeam = () =>
+  API.get('/patient/care-team')
+
+export const getCareAssignments = (params) =>
+  API.get('/admin/care-assignments', { params })
+
+export const assignCare = (assignment) =>
+  API.post('/admin/care-assignments', assignment)
+
+export const approveCareAssignment = (assignment_id, notes) =>
+  API.post(`/admin/care-assignments/${assignment_id}/approve`, { notes })
+
+export const declineCareAssignment = (assignment_id, notes) =>
+  API.post(`/admin/care-assignments/${assignment_id}/decline`, { notes })
+
+export const endCareAssignment = (assignment_id) =>
+  API.post(`/admin/care-assignments/${assignment_id}/end`)
+
+// Patient consent
+export const getMyConsents = () =>
+  API.get('/patient/consents')
+
+export const grantConsent = (consent) =>
+  API.post('/patient/consents', consent)
+
+export const revokeConsent = (consent_id) =>
+  API.delete(`/patient/consents/${consent_id}`)
+
+export const getConsentActivity = (page = 1, page_size = 20) =>
+  API.get('/patient/consents/activity', { params: { page, page_size } })
+
+// Proxy access — pass acting_as to read a dependent's records
+export const getPatientRecordsAs = (acting_as) =>
+  API.get('/patient/records', { headers: { 'X-Acting-As': acting_as } })
+
+export const getCareTeamAs = (acting_as) =>
+  API.get('/patient/care-team', { headers: { 'X-Acting-As': acting_as } })
+
+export const requestProxyAccess = (subject_email, relationship, evidence) =>
+  API.post('/proxy/requests', { subject_email, relationship, evidence })
+
+export const getProxySubjects = () =>
+  API.get('/proxy/relationships')
+
+export const stepDownAsProxy = (relationship_id) =>
+  API.post(`/proxy/relationships/${relationship_id}/revoke`)
+
+export const getMyProxies = () =>
+  API.get('/patient/proxies')
+
+export const revokeMyProxy = (relationship_id) =>
+  API.post(`/patient/proxies/${relationship_id}/revoke`)
+
+export const getProxyRelationships = (params) =>
+  API.get('/admin/proxy-relationships', { params })
+
+export const approveProxy = (relationship_id, notes, expires_at) =>
+  API.post(`/admin/proxy-relationships/${relationship_id}/approve`, { notes, expires_at })
+
+export const declineProxy = (relationship_id, notes) =>
+  API.post(`/admin/proxy-relationships/${relationship_id}/decline`, { notes })
+
+export const revokeProxy = (relationship_id) =>
+  API.post(`/admin/proxy-relationships/${relationship_id}/revoke`)
+
+export const setUserDateOfBirth = (user_id, date_of_birth) =>
+  API.put(`/admin/users/${user_id}/date-of-birth`, { date_of_birth })
+
+// Service accounts and API keys for integrations
+export const getServiceAccounts = (params) =>
+  API.get('/admin/service-accounts', { params })
+
+export const createServiceAccount = (name, role, department) =>
+  API.post('/admin/service-accounts', { name, role, department })
+
+export const getAPIKeys = (user_id) =>
+  API.get(`/admin/service-accounts/${user_id}/api-keys`)
+
+export const createAPIKey = (user_id, key) =>
+  API.post(`/admin/service-accounts/${user_id}/api-keys`, key)
+
+export const revokeAPIKey = (key_id) =>
+  API.post(`/admin/api-keys/${key_id}/revoke`)
+
 export const checkHealth = () =>
   API.get('/health')
 
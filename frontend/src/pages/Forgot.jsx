@@ -1,5 +1,5 @@
 import { useState } from 'react'
-import { useNavigate, Link } from 'react-router-dom'
+import { useNavigate, useSearchParams, Link } from 'react-router-dom'
 import { requestPasswordReset, resetPassword } from '../api/axios'
 import {
   Box, Paper, TextField, Button, Typography, Alert,
@@ -14,10 +14,13 @@ const steps = ['Enter Email', 'Reset Password']
 
 const ForgotPassword = () => {
   const navigate = useNavigate()
+  const [searchParams] = useSearchParams()
 
-  const [activeStep, setActiveStep] = useState(0)
+  // The emailed link carries the token, so arriving from it skips step one
+  const linkToken = searchParams.get('token') || ''
+  const [activeStep, setActiveStep] = useState(linkToken ? 1 : 0)
   const [email, setEmail] = useState('')
-  const [resetToken, setResetToken] = useState('')
+  const [resetToken, setResetToken] = useState(linkToken)
   const [newPassword, setNewPassword] = useState('')
   const [confirmPassword, setConfirmPassword] = useState('')
   const [error, setError] = useState('')
@@ -30,11 +33,7 @@ const ForgotPassword = () => {
     setLoading(true)
     try {
       const res = await requestPasswordReset(email)
-      // In production this would be emailed
-      // For demo we show the token directly
-      if (res.data.reset_token) {
-        setResetToken(res.data.reset_token)
-      }
+      setSuccess(res.data.message)
       setActiveStep(1)
     } catch {
       setError('Failed to send reset request')
@@ -52,12 +51,8 @@ const ForgotPassword = () => {
       return
     }
 
-    if (newPassword.length < 6) {
-      setError('Password must be at least 6 characters')
-      return
-    }
-
     setLoading(true)
+    setSuccess('')
     try {
       await resetPassword(resetToken, newPassword)
       setSuccess('Password reset successfully! Redirecting to login...')
@@ -173,7 +168,7 @@ const ForgotPassword = () => {
         {activeStep === 0 && (
           <Box component="form" onSubmit={handleRequestReset}>
             <Typography variant="body2" color="text.secondary" sx={{ mb: 2 }}>
-              Enter your email address and we'll send you a reset token.
+              Enter your email address and we'll email you a reset link.
             </Typography>
             <TextField
               fullWidth label="Email Address" type="email"
@@ -200,7 +195,7 @@ const ForgotPassword = () => {
             >
               {loading
                 ? <CircularProgress size={22} sx={{ color: '#0a0d14' }} />
-                : 'Send Reset Token'
+                : 'Send Reset Link'
               }
             </Button>
           </Box>
@@ -210,7 +205,7 @@ const ForgotPassword = () => {
         {activeStep === 1 && (
           <Box component="form" onSubmit={handleResetPassword}>
             <Typography variant="body2" color="text.secondary" sx={{ mb: 2 }}>
-              Enter your reset token and choose a new password.
+              Open the link in your email, or paste the token from it, and choose a new password.
             </Typography>
 
             <TextField
@@ -218,7 +213,7 @@ const ForgotPassword = () => {
               value={resetToken}
               onChange={(e) => setResetToken(e.target.value)}
               required sx={{ mb: 2 }}
-              helperText="Check your email or use the token shown above"
+              helperText="The link expires after 30 minutes"
               InputProps={{
                 startAdornment: (
                   <InputAdornment position="start">
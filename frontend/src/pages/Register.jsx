@@ -8,7 +8,7 @@ import {
 } from '@mui/material'
 import {
   Lock, Email, Visibility, VisibilityOff,
-  LocalHospital, Shield, Person, MedicalServices
+  LocalHospital, Shield, Person, MedicalServices, Badge
 } from '@mui/icons-material'
 
 const Register = () => {
@@ -19,6 +19,8 @@ const Register = () => {
   const [password, setPassword] = useState('')
   const [confirmPassword, setConfirmPassword] = useState('')
   const [role, setRole] = useState('patient')
+  const [licenseNumber, setLicenseNumber] = useState('')
+  const [notes, setNotes] = useState('')
   const [showPassword, setShowPassword] = useState(false)
   const [error, setError] = useState('')
   const [success, setSuccess] = useState('')
@@ -34,16 +36,11 @@ const Register = () => {
       return
     }
 
-    if (password.length < 6) {
-      setError('Password must be at least 6 characters')
-      return
-    }
-
     setLoading(true)
     try {
-      await registerUser(name, email, password, role)
-      setSuccess('Account created successfully! Redirecting to login...')
-      setTimeout(() => navigate('/login'), 2000)
+      const res = await registerUser(name, email, password, role, licenseNumber, notes)
+      setSuccess(res.data.message)
+      setTimeout(() => navigate('/login'), 6000)
     } catch (err) {
       setError(err.response?.data?.error || 'Registration failed. Please try again.')
     } finally {
@@ -220,6 +217,30 @@ const Register = () => {
             }}
           />
 
+          {role === 'doctor' && (
+            <>
+              <TextField
+                fullWidth label="Medical License Number" value={licenseNumber}
+                onChange={(e) => setLicenseNumber(e.target.value)}
+                required sx={{ mb: 2 }}
+                InputProps={{
+                  startAdornment: (
+                    <InputAdornment position="start">
+                      <Badge sx={{ color: 'text.secondary', fontSize: 20 }} />
+                    </InputAdornment>
+                  )
+                }}
+              />
+
+              <TextField
+                fullWidth label="Notes for the reviewer" value={notes}
+                onChange={(e) => setNotes(e.target.value)}
+                multiline minRows={2} sx={{ mb: 2 }}
+                helperText="Hospital, department or anything that helps an administrator verify you"
+              />
+            </>
+          )}
+
           <TextField
             fullWidth label="Password"
             type={showPassword ? 'text' : 'password'}
@@ -0,0 +1,188 @@
+import { useEffect, useRef, useState } from 'react'
+import { useSearchParams, Link } from 'react-router-dom'
+import { verifyEmail, resendVerification } from '../api/axios'
+import {
+  Box, Paper, TextField, Button, Typography, Alert,
+  CircularProgress, InputAdornment, Divider
+} from '@mui/material'
+import { Email, LocalHospital, Shield } from '@mui/icons-material'
+
+const VerifyEmail = () => {
+  const [searchParams] = useSearchParams()
+  const token = searchParams.get('token') || ''
+
+  const [verifying, setVerifying] = useState(!!token)
+  const [email, setEmail] = useState('')
+  const [error, setError] = useState(token ? '' : 'This verification link is incomplete.')
+  const [success, setSuccess] = useState('')
+  const [loading, setLoading] = useState(false)
+
+  // Links are single-use, so make sure StrictMode's double effect run
+  // does not spend the token twice
+  const submitted = useRef(false)
+
+  useEffect(() => {
+    if (!token || submitted.current) return
+    submitted.current = true
+
+    verifyEmail(token)
+      .then((res) => setSuccess(res.data.message))
+      .catch((err) => setError(err.response?.data?.error || 'Verification failed.'))
+      .finally(() => setVerifying(false))
+  }, [token])
+
+  const handleResend = async (e) => {
+    e.preventDefault()
+    setError('')
+    setLoading(true)
+    try {
+      const res = await resendVerification(email)
+      setSuccess(res.data.message)
+    } catch {
+      setError('Failed to send a new link')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <Box sx={{
+      minHeight: '100vh',
+      background: 'linear-gradient(135deg, #0a0d14 0%, #0f1a2e 50%, #0a0d14 100%)',
+      display: 'flex', alignItems: 'center', justifyContent: 'center',
+      position: 'relative', overflow: 'hidden',
+    }}>
+
+      {/* Background grid */}
+      <Box sx={{
+        position: 'absolute', inset: 0,
+        backgroundImage: `
+          linear-gradient(rgba(0,229,255,0.03) 1px, transparent 1px),
+          linear-gradient(90deg, rgba(0,229,255,0.03) 1px, transparent 1px)
+        `,
+        backgroundSize: '40px 40px', pointerEvents: 'none',
+      }} />
+
+      <Paper elevation={0} sx={{
+        width: '100%', maxWidth: '420px', p: 4, mx: 2,
+        background: 'rgba(15, 21, 32, 0.95)',
+        border: '1px solid rgba(0,229,255,0.15)',
+        borderRadius: '16px', backdropFilter: 'blur(20px)',
+        position: 'relative', zIndex: 1,
+      }}>
+
+        {/* Header */}
+        <Box sx={{ textAlign: 'center', mb: 3 }}>
+          <Box sx={{
+            display: 'inline-flex', alignItems: 'center',
+            justifyContent: 'center',
+            width: 64, height: 64, borderRadius: '16px',
+            background: 'linear-gradient(135deg, rgba(0,229,255,0.15), rgba(0,255,136,0.15))',
+            border: '1px solid rgba(0,229,255,0.2)', mb: 2,
+          }}>
+            <LocalHospital sx={{ fontSize: 32, color: '#00e5ff' }} />
+          </Box>
+          <Typography variant="h5" fontWeight={700} color="white" gutterBottom>
+            Verify Email
+          </Typography>
+          <Typography variant="body2" color="text.secondary">
+            HealthVault — Encrypted Health Data System
+          </Typography>
+          <Box sx={{
+            display: 'inline-flex', alignItems: 'center', gap: 0.5,
+            mt: 1, px: 1.5, py: 0.5, borderRadius: '20px',
+            background: 'rgba(0,255,136,0.08)',
+            border: '1px solid rgba(0,255,136,0.2)',
+          }}>
+            <Shield sx={{ fontSize: 12, color: '#00ff88' }} />
+            <Typography variant="caption" color="#00ff88" fontWeight={600}>
+              Account Activation
+            </Typography>
+          </Box>
+        </Box>
+
+        <Divider sx={{ mb: 3, borderColor: 'rgba(255,255,255,0.06)' }} />
+
+        {verifying && (
+          <Box sx={{ textAlign: 'center', py: 2 }}>
+            <CircularProgress size={28} sx={{ color: '#00e5ff' }} />
+          </Box>
+        )}
+
+        {error && (
+          <Alert severity="error" sx={{
+            mb: 2,
+            background: 'rgba(255,71,87,0.1)',
+            border: '1px solid rgba(255,71,87,0.3)',
+            color: '#ff4757',
+            '& .MuiAlert-icon': { color: '#ff4757' }
+          }}>
+            {error}
+          </Alert>
+        )}
+
+        {success && (
+          <Alert severity="success" sx={{
+            mb: 2,
+            background: 'rgba(0,255,136,0.08)',
+            border: '1px solid rgba(0,255,136,0.2)',
+            color: '#00ff88',
+            '& .MuiAlert-icon': { color: '#00ff88' }
+          }}>
+            {success}
+          </Alert>
+        )}
+
+        {/* A failed or missing link can be replaced */}
+        {!verifying && !success && (
+          <Box component="form" onSubmit={handleResend}>
+            <Typography variant="body2" color="text.secondary" sx={{ mb: 2 }}>
+              Enter your email address and we'll send a new verification link.
+            </Typography>
+            <TextField
+              fullWidth label="Email Address" type="email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              required sx={{ mb: 3 }}
+              InputProps={{
+                startAdornment: (
+                  <InputAdornment position="start">
+                    <Email sx={{ color: 'text.secondary', fontSize: 20 }} />
+                  </InputAdornment>
+                )
+              }}
+            />
+            <Button
+              type="submit" fullWidth variant="contained"
+              disabled={loading}
+              sx={{
+                py: 1.5,
+                background: 'linear-gradient(135deg, #00e5ff, #00b4d8)',
+                color: '#0a0d14', fontWeight: 700,
+                '&:hover': { background: 'linear-gradient(135deg, #00ff88, #00e5ff)' },
+              }}
+            >
+              {loading
+                ? <CircularProgress size={22} sx={{ color: '#0a0d14' }} />
+                : 'Send New Link'
+              }
+            </Button>
+          </Box>
+        )}
+
+        {/* Footer */}
+        <Box sx={{
+          mt: 3, pt: 3,
+          borderTop: '1px solid rgba(255,255,255,0.06)',
+          textAlign: 'center'
+        }}>
+          <Link to="/login" style={{ color: '#00e5ff', textDecoration: 'none', fontWeight: 600 }}>
+            Back to Sign In
+          </Link>
+        </Box>
+      </Paper>
+    </Box>
+  )
+}
+
+export default VerifyEmail
@@ -0,0 +1,96 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Severity levels for alerts
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Alert is a single security notification
+type Alert struct {
+	Severity string                 `json:"severity"`
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// Notifier delivers alerts to an external channel
+type Notifier interface {
+	Notify(a Alert) error
+}
+
+// LogNotifier writes alerts to the server log
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(a Alert) error {
+	log.Printf("🚨 [%s] %s — %s %v", a.Severity, a.Title, a.Message, a.Details)
+	return nil
+}
+
+// WebhookNotifier POSTs alerts as JSON to a configured URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Notify(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// MultiNotifier fans an alert out to several channels
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{notifiers: notifiers}
+}
+
+// Notify delivers to every channel and returns the first error, if any
+func (m *MultiNotifier) Notify(a Alert) error {
+	var firstErr error
+	for _, n := range m.notifiers {
+		if err := n.Notify(a); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
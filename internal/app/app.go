@@ -1,14 +1,28 @@
 package app
 
 import (
+	"context"
 	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/khawsic/health/internal/alert"
 	"github.com/khawsic/health/internal/audit"
 	"github.com/khawsic/health/internal/auth"
+	"github.com/khawsic/health/internal/authz"
 	"github.com/khawsic/health/internal/config"
 	"github.com/khawsic/health/internal/crypto"
+	"github.com/khawsic/health/internal/geoip"
+	"github.com/khawsic/health/internal/keyring"
+	"github.com/khawsic/health/internal/mail"
 	record "github.com/khawsic/health/internal/records"
+	"github.com/khawsic/health/internal/throttle"
+	"github.com/khawsic/health/internal/tsa"
 	"github.com/khawsic/health/pkg/database"
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
 	"gorm.io/gorm"
 )
 
@@ -18,6 +32,17 @@ type App struct {
 	AuthService   *auth.Service
 	RecordService *record.Service
 	AuditService  *audit.Service
+	Authorizer    *authz.Service
+	AuditMonitor  *audit.Monitor
+	Keyring       *keyring.Keyring
+	KeyRotation   time.Duration
+	Throttler     *throttle.Throttler
+	StepUpMaxAge  time.Duration
+
+	// TrustedProxies may set X-Forwarded-For. With none, the client address
+	// is the TCP peer, so API key allowlists, per-IP limits, network
+	// policies and login locations cannot be spoofed with a header.
+	TrustedProxies []string
 }
 
 func New() *App {
@@ -29,6 +54,9 @@ func New() *App {
 	if cfg.DBUrl == "" {
 		log.Fatal("❌ DB_URL is required")
 	}
+	if cfg.AuditDBUrl == "" {
+		log.Fatal("❌ AUDIT_DB_URL is required (connection as the audit_writer role)")
+	}
 	if cfg.JWTSecret == "" {
 		log.Fatal("❌ JWT_SECRET is required")
 	}
@@ -42,6 +70,21 @@ func New() *App {
 		log.Fatal("❌ ED25519_PUBLIC_KEY is required")
 	}
 
+	verifyInterval, err := time.ParseDuration(cfg.AuditVerifyInterval)
+	if err != nil || verifyInterval <= 0 {
+		log.Fatal("❌ AUDIT_VERIFY_INTERVAL must be a positive duration (e.g. 5m)")
+	}
+
+	checkpointInterval, err := time.ParseDuration(cfg.TSACheckpointInterval)
+	if err != nil || checkpointInterval <= 0 {
+		log.Fatal("❌ TSA_CHECKPOINT_INTERVAL must be a positive duration (e.g. 1h)")
+	}
+
+	keyRotation, err := time.ParseDuration(cfg.JWTKeyRotation)
+	if err != nil || keyRotation <= 0 {
+		log.Fatal("❌ JWT_KEY_ROTATION_INTERVAL must be a positive duration (e.g. 720h)")
+	}
+
 	// 3️⃣ Load Ed25519 keys
 	privateKey, err := crypto.LoadPrivateKey(cfg.ED25519PrivateKey)
 	if err != nil {
@@ -74,15 +117,68 @@ func New() *App {
 	auth.Migrate(db)
 	record.Migrate(db)
 
+	// Access tokens are signed with rotating Ed25519 keys
+	keys := keyring.New(db, cfg.EncryptionKey, auth.AccessTokenExpiry)
+	if err := keys.Migrate(); err != nil {
+		log.Fatal("❌ JWT keyring migration failed:", err)
+	}
+	if err := keys.Load(); err != nil {
+		log.Fatal("❌ Failed to load JWT signing keys:", err)
+	}
+	log.Println("✅ JWT signing keys loaded")
+
+	// Roles and access policies must be loaded before identity providers
+	// map groups onto roles
+	authorizer := authz.NewService(db)
+	if err := authorizer.Migrate(); err != nil {
+		log.Fatal("❌ Role and policy migration failed:", err)
+	}
+	if err := authorizer.Load(); err != nil {
+		log.Fatal("❌ Failed to load roles and access policies:", err)
+	}
+	log.Println("✅ Roles and access policies loaded")
+
 	// 7️⃣ Initialize services
-	authService := auth.NewService(db, cfg.JWTSecret)
+	authService := auth.NewService(db, cfg.JWTSecret, cfg.EncryptionKey, keys)
+	authService.UseAuthorizer(authorizer)
+	if err := authService.EnableWebAuthn(cfg.WebAuthnRPID, cfg.WebAuthnRPName, splitList(cfg.WebAuthnOrigins)); err != nil {
+		log.Fatal("❌ Invalid WebAuthn configuration:", err)
+	}
+	passwords := PasswordHasher(cfg)
+	authService.UsePasswordHasher(passwords)
+	configurePasswordPolicy(cfg, authService, passwords)
+	configureOIDC(cfg, authService)
+	configureLDAP(cfg, authService)
+	configureLoginBackoff(cfg, authService)
+	configureGeoIP(cfg, authService)
+	authService.UseMailer(newMailer(cfg), cfg.AppBaseURL)
+	throttler := newThrottler(cfg, db)
 
+	// The audit tables come only from the SQL migrations, run as a role
+	// that can hand them to audit_owner; the application role never
+	// creates or alters them
 	auditService := audit.NewService(db, privateKey, publicKey)
-	if err := auditService.Migrate(); err != nil {
-		log.Fatal("❌ Audit migration failed:", err)
+
+	// Appends go through the low-privilege audit writer role
+	auditService.UseWriter(database.Connect(cfg.AuditDBUrl))
+	if err := auditService.CheckProtections(); err != nil {
+		log.Fatal("❌ Audit tables are not append-only protected:", err)
 	}
+	log.Println("✅ Audit append-only protections verified")
+	configureTimestamping(cfg, auditService)
+	authService.UseAuditLog(auditService)
+	authorizer.UseAuditLog(auditService)
 
 	recordService := record.NewService(db, cfg.EncryptionKey, auditService)
+	recordService.UseAuthorizer(authorizer)
+	configureProxyRules(cfg, recordService)
+
+	// Tamper alerts always go to the log, and to a webhook when configured
+	notifiers := []alert.Notifier{alert.NewLogNotifier()}
+	if cfg.AlertWebhookURL != "" {
+		notifiers = append(notifiers, alert.NewWebhookNotifier(cfg.AlertWebhookURL))
+	}
+	auditMonitor := audit.NewMonitor(auditService, alert.NewMultiNotifier(notifiers...), verifyInterval, checkpointInterval)
 
 	log.Println("✅ Services initialized successfully")
 
@@ -93,5 +189,301 @@ func New() *App {
 		AuthService:   authService,
 		RecordService: recordService,
 		AuditService:  auditService,
+		Authorizer:    authorizer,
+		AuditMonitor:  auditMonitor,
+		Keyring:       keys,
+		KeyRotation:   keyRotation,
+		Throttler:     throttler,
+		StepUpMaxAge:  positiveDuration("STEP_UP_MAX_AGE", cfg.StepUpMaxAge),
+
+		TrustedProxies: splitList(cfg.TrustedProxies),
+	}
+}
+
+// configureTimestamping wires RFC 3161 checkpoints. TSA_URL=local uses the
+// in-process development stand-in; any other value is a remote TSA endpoint.
+func configureTimestamping(cfg *config.Config, auditService *audit.Service) {
+	switch cfg.TSAURL {
+	case "":
+		log.Println("⚠️  TSA_URL not set — audit checkpoints will not be timestamped")
+
+	case "local":
+		authority, err := tsa.LoadOrCreateLocalAuthority(cfg.TSALocalDir)
+		if err != nil {
+			log.Fatal("❌ Failed to start local TSA:", err)
+		}
+		auditService.EnableTimestamping(authority, authority.Roots())
+		log.Println("⚠️  Using local development TSA — not a trusted time source")
+
+	default:
+		if cfg.TSACACert == "" {
+			log.Fatal("❌ TSA_CA_CERT is required when TSA_URL is set")
+		}
+		pemData, err := os.ReadFile(cfg.TSACACert)
+		if err != nil {
+			log.Fatal("❌ Failed to read TSA_CA_CERT:", err)
+		}
+		roots, err := tsa.LoadRoots(pemData)
+		if err != nil {
+			log.Fatal("❌ Invalid TSA_CA_CERT:", err)
+		}
+		auditService.EnableTimestamping(tsa.NewHTTPAuthority(cfg.TSAURL), roots)
+		log.Println("✅ Trusted timestamping enabled via", cfg.TSAURL)
+	}
+}
+
+// configurePasswordPolicy loads PASSWORD_POLICY_FILE over the default
+// policy, refusing a max_length the password hasher cannot accept, and
+// enables the breached password check for BREACHED_PASSWORDS_DIR
+func configurePasswordPolicy(cfg *config.Config, authService *auth.Service, passwords *crypto.Passwords) {
+	if cfg.PasswordPolicyFile != "" {
+		policy, err := auth.LoadPasswordPolicy(cfg.PasswordPolicyFile)
+		if err != nil {
+			log.Fatal("❌ Invalid PASSWORD_POLICY_FILE:", err)
+		}
+		authService.UsePasswordPolicy(policy)
+		log.Println("✅ Password policy loaded from", cfg.PasswordPolicyFile)
+	}
+
+	limit := authService.PasswordPolicy().MaxLength
+	if max := passwords.MaxPasswordBytes(); max > 0 && limit > max {
+		log.Fatalf("❌ Password policy max_length %d exceeds the %d bytes %s accepts", limit, max, cfg.PasswordHasher)
+	}
+
+	if cfg.BreachedPasswordsDir == "" {
+		log.Println("⚠️  BREACHED_PASSWORDS_DIR not set — passwords are not checked against breach corpora")
+		return
+	}
+	breached, err := auth.NewBreachedPasswords(cfg.BreachedPasswordsDir)
+	if err != nil {
+		log.Fatal("❌ Invalid BREACHED_PASSWORDS_DIR:", err)
+	}
+	authService.UseBreachedPasswords(breached)
+	log.Println("✅ Breached password check enabled")
+}
+
+// PasswordHasher builds the password hashing setup from PASSWORD_HASHER.
+// New hashes use the chosen algorithm; hashes made with the other one are
+// still accepted and upgraded on the user's next login.
+func PasswordHasher(cfg *config.Config) *crypto.Passwords {
+	params := crypto.DefaultArgon2Params()
+	params.Memory = uint32(positiveInt("ARGON2_MEMORY_KIB", cfg.Argon2Memory, 1<<22))
+	params.Iterations = uint32(positiveInt("ARGON2_ITERATIONS", cfg.Argon2Iterations, 100))
+	params.Parallelism = uint8(positiveInt("ARGON2_PARALLELISM", cfg.Argon2Parallelism, 255))
+
+	argon2id, err := crypto.NewArgon2idHasher(params)
+	if err != nil {
+		log.Fatal("❌ Invalid Argon2 parameters:", err)
+	}
+	bcryptHasher, err := crypto.NewBcryptHasher(positiveInt("BCRYPT_COST", cfg.BcryptCost, 31))
+	if err != nil {
+		log.Fatal("❌ Invalid BCRYPT_COST:", err)
+	}
+
+	switch cfg.PasswordHasher {
+	case "argon2id":
+		return crypto.NewPasswords(argon2id, bcryptHasher)
+	case "bcrypt":
+		return crypto.NewPasswords(bcryptHasher, argon2id)
+	default:
+		log.Fatal("❌ PASSWORD_HASHER must be argon2id or bcrypt")
+		return nil
+	}
+}
+
+// configureProxyRules sets when parents lose access to a minor's records
+// and which categories adolescents keep confidential from them
+func configureProxyRules(cfg *config.Config, recordService *record.Service) {
+	rules := record.ProxyRules{
+		AdultAge:               positiveInt("PROXY_ADULT_AGE", cfg.ProxyAdultAge, 150),
+		AdolescentAge:          positiveInt("ADOLESCENT_AGE", cfg.AdolescentAge, 150),
+		ConfidentialCategories: splitList(cfg.AdolescentCategories),
+	}
+	if err := recordService.UseProxyRules(rules); err != nil {
+		log.Fatal("❌ Invalid proxy age rules:", err)
+	}
+}
+
+// configureLoginBackoff sets how quickly repeated failed logins lock an
+// account and how long the lockouts grow
+func configureLoginBackoff(cfg *config.Config, authService *auth.Service) {
+	backoff := auth.LoginBackoff{
+		FreeAttempts: positiveInt("LOGIN_FREE_ATTEMPTS", cfg.LoginFreeAttempts, 100),
+		Base:         positiveDuration("LOGIN_BACKOFF_BASE", cfg.LoginBackoffBase),
+		Max:          positiveDuration("LOGIN_BACKOFF_MAX", cfg.LoginBackoffMax),
+		ResetAfter:   positiveDuration("LOGIN_BACKOFF_RESET", cfg.LoginBackoffReset),
+	}
+	if err := authService.UseLoginBackoff(backoff); err != nil {
+		log.Fatal("❌ Invalid login backoff:", err)
 	}
-}
\ No newline at end of file
+}
+
+// configureGeoIP loads the GEOIP_DATABASE file (a MaxMind .mmdb such as
+// GeoLite2-City) used to place logins. Without one, login history records
+// no location and new-sign-in notices consider only the device.
+func configureGeoIP(cfg *config.Config, authService *auth.Service) {
+	if cfg.GeoIPDatabase == "" {
+		log.Println("⚠️  GEOIP_DATABASE not set — logins will not be located")
+		return
+	}
+
+	reader, err := geoip.Open(cfg.GeoIPDatabase)
+	if err != nil {
+		log.Fatal("❌ Invalid GEOIP_DATABASE:", err)
+	}
+	authService.UseGeoIP(reader)
+	log.Println("✅ GeoIP database loaded from", cfg.GeoIPDatabase)
+}
+
+// newThrottler builds the public route rate limits from THROTTLE_RULES_FILE
+// over the defaults. THROTTLE_STORE=postgres shares counters between
+// instances; memory keeps them per process and loses them on restart.
+func newThrottler(cfg *config.Config, db *gorm.DB) *throttle.Throttler {
+	rules := throttle.DefaultRules()
+	if cfg.ThrottleRulesFile != "" {
+		loaded, err := throttle.LoadRules(cfg.ThrottleRulesFile)
+		if err != nil {
+			log.Fatal("❌ Invalid THROTTLE_RULES_FILE:", err)
+		}
+		rules = loaded
+		log.Println("✅ Rate limits loaded from", cfg.ThrottleRulesFile)
+	}
+
+	var store limiter.Store
+	switch cfg.ThrottleStore {
+	case "postgres":
+		postgres := throttle.NewPostgresStore(db)
+		if err := postgres.Migrate(); err != nil {
+			log.Fatal("❌ Throttle counter migration failed:", err)
+		}
+		store = postgres
+
+	case "memory":
+		log.Println("⚠️  THROTTLE_STORE=memory — rate limits are per instance and reset on restart")
+		store = memory.NewStore()
+
+	default:
+		log.Fatal("❌ THROTTLE_STORE must be postgres or memory")
+	}
+
+	throttler, err := throttle.New(store, rules)
+	if err != nil {
+		log.Fatal("❌ Invalid rate limits:", err)
+	}
+	return throttler
+}
+
+// configureOIDC enables single sign-on for the providers listed in
+// OIDC_PROVIDERS_FILE. Discovery runs now, so the IdPs must be reachable.
+func configureOIDC(cfg *config.Config, authService *auth.Service) {
+	if cfg.OIDCProvidersFile == "" {
+		return
+	}
+
+	providers, err := auth.LoadOIDCProviders(cfg.OIDCProvidersFile)
+	if err != nil {
+		log.Fatal("❌ Failed to read OIDC_PROVIDERS_FILE:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := authService.EnableOIDC(ctx, providers); err != nil {
+		log.Fatal("❌ Invalid OIDC configuration:", err)
+	}
+	log.Printf("✅ Single sign-on enabled for %d provider(s)", len(providers))
+}
+
+// configureLDAP adds a password authenticator for each directory listed in
+// LDAP_DIRECTORIES_FILE. Directories are contacted only when users log in.
+func configureLDAP(cfg *config.Config, authService *auth.Service) {
+	if cfg.LDAPDirectoriesFile == "" {
+		return
+	}
+
+	directories, err := auth.LoadLDAPDirectories(cfg.LDAPDirectoriesFile)
+	if err != nil {
+		log.Fatal("❌ Failed to read LDAP_DIRECTORIES_FILE:", err)
+	}
+
+	for _, directory := range directories {
+		if err := authService.ValidateRoleMapping(directory.RoleMapping); err != nil {
+			log.Fatalf("❌ Invalid LDAP configuration for %s: %v", directory.Name, err)
+		}
+		authenticator, err := auth.NewLDAPAuthenticator(directory)
+		if err != nil {
+			log.Fatal("❌ Invalid LDAP configuration:", err)
+		}
+		authService.AddAuthenticator(authenticator)
+	}
+	log.Printf("✅ LDAP authentication enabled for %d directory(ies)", len(directories))
+}
+
+// newMailer picks email delivery from MAIL_DRIVER: smtp for real delivery,
+// file or log for development. There is no default, since the development
+// drivers expose reset and verification links to whoever reads the disk or
+// the log.
+func newMailer(cfg *config.Config) mail.Mailer {
+	switch cfg.MailDriver {
+	case "":
+		log.Fatal("❌ MAIL_DRIVER is required — smtp, or file or log for development")
+		return nil
+
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			log.Fatal("❌ SMTP_HOST is required when MAIL_DRIVER=smtp")
+		}
+		log.Println("✅ Email delivery via SMTP relay", cfg.SMTPHost)
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+
+	case "file":
+		mailer, err := mail.NewFileMailer(cfg.MailFileDir, cfg.MailFrom)
+		if err != nil {
+			log.Fatal("❌ Failed to create MAIL_FILE_DIR:", err)
+		}
+		log.Println("⚠️  Emails are written to", cfg.MailFileDir, "— not delivered")
+		return mailer
+
+	case "log":
+		log.Println("⚠️  MAIL_DRIVER=log — emails, including reset links, are written to the log")
+		return mail.NewLogMailer()
+
+	default:
+		log.Fatal("❌ MAIL_DRIVER must be smtp, file or log")
+		return nil
+	}
+}
+
+// positiveInt parses a numeric config value between 1 and max
+func positiveInt(name, value string, max int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		log.Fatalf("❌ %s must be a whole number between 1 and %d", name, max)
+	}
+	return n
+}
+
+// positiveDuration parses a duration config value that must be above zero
+func positiveDuration(name, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("❌ %s must be a positive duration (e.g. 1m)", name)
+	}
+	return d
+}
+
+// splitList parses a comma-separated config value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/khawsic/health/internal/tsa"
+)
+
+// checkpointSkew tolerates clock drift between this server and the TSA
+const checkpointSkew = 2 * time.Minute
+
+// AuditCheckpoint anchors the chain head at a point in time using an
+// RFC 3161 token from an external Time Stamping Authority
+type AuditCheckpoint struct {
+	ID        uint      `gorm:"primaryKey"`
+	EntryID   uint      `gorm:"not null;uniqueIndex"`
+	EntryHash string    `gorm:"not null"`
+	Token     []byte    `gorm:"not null"`
+	GenTime   time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// EnableTimestamping makes the service anchor checkpoints with authority and
+// verify stored tokens against roots
+func (s *Service) EnableTimestamping(authority tsa.Authority, roots *x509.CertPool) {
+	s.tsa = authority
+	s.tsaRoots = roots
+}
+
+// Checkpoint timestamps the current chain head. It returns nil when
+// timestamping is disabled or the head is already checkpointed.
+func (s *Service) Checkpoint() (*AuditCheckpoint, error) {
+	if s.tsa == nil {
+		return nil, nil
+	}
+
+	var head AuditLog
+	if err := s.db.Order("id DESC").Limit(1).Find(&head).Error; err != nil {
+		return nil, err
+	}
+	if head.ID == 0 {
+		return nil, nil
+	}
+
+	var existing int64
+	if err := s.db.Model(&AuditCheckpoint{}).Where("entry_id = ?", head.ID).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, nil
+	}
+
+	// The entry hash is already a SHA-256 digest, so it is the message imprint
+	digest, err := hex.DecodeString(head.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid head hash: %w", err)
+	}
+
+	token, err := s.tsa.Timestamp(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	genTime, err := tsa.Verify(token, digest, s.tsaRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := AuditCheckpoint{
+		EntryID:   head.ID,
+		EntryHash: head.Hash,
+		Token:     token,
+		GenTime:   genTime.UTC(),
+	}
+	if err := s.writer.Create(&checkpoint).Error; err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// verifyCheckpoints checks every stored token against the chain in logs
+// (ordered by ID). Each entry's timestamp must fall between the previous
+// checkpoint's TSA time and the next one's, so backdated or post-dated
+// entries are caught, and every checkpoint must still point at its entry.
+func (s *Service) verifyCheckpoints(logs []AuditLog) *ChainError {
+	var checkpoints []AuditCheckpoint
+	if err := s.db.Order("entry_id ASC").Find(&checkpoints).Error; err != nil {
+		return &ChainError{Kind: FailureTimestamp, msg: fmt.Sprintf("⚠️  failed to load checkpoints: %v", err)}
+	}
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	next := 0
+	var lower *time.Time
+
+	for _, entry := range logs {
+		if next < len(checkpoints) && checkpoints[next].EntryID < entry.ID {
+			cp := checkpoints[next]
+			return &ChainError{
+				Kind:    FailureTimestamp,
+				EntryID: cp.EntryID,
+				msg:     fmt.Sprintf("⚠️  checkpoint %d references missing entry %d", cp.ID, cp.EntryID),
+			}
+		}
+
+		if lower != nil && entry.Timestamp.Before(lower.Add(-checkpointSkew)) {
+			return &ChainError{
+				Kind:    FailureTimestamp,
+				EntryID: entry.ID,
+				msg:     fmt.Sprintf("⚠️  entry %d is dated before an earlier trusted timestamp", entry.ID),
+			}
+		}
+
+		if next < len(checkpoints) {
+			cp := checkpoints[next]
+			if entry.Timestamp.After(cp.GenTime.Add(checkpointSkew)) {
+				return &ChainError{
+					Kind:    FailureTimestamp,
+					EntryID: entry.ID,
+					msg:     fmt.Sprintf("⚠️  entry %d is dated after trusted timestamp %d", entry.ID, cp.ID),
+				}
+			}
+
+			if cp.EntryID == entry.ID {
+				if err := s.verifyCheckpointToken(cp, entry); err != nil {
+					return err
+				}
+				genTime := cp.GenTime
+				lower = &genTime
+				next++
+			}
+		}
+	}
+
+	// Checkpoints beyond the last entry mean the tail of the chain was removed
+	if next < len(checkpoints) {
+		cp := checkpoints[next]
+		return &ChainError{
+			Kind:    FailureTimestamp,
+			EntryID: cp.EntryID,
+			msg:     fmt.Sprintf("⚠️  checkpoint %d references missing entry %d", cp.ID, cp.EntryID),
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) verifyCheckpointToken(cp AuditCheckpoint, entry AuditLog) *ChainError {
+	if cp.EntryHash != entry.Hash {
+		return &ChainError{
+			Kind:    FailureTimestamp,
+			EntryID: entry.ID,
+			msg:     fmt.Sprintf("⚠️  entry %d no longer matches trusted timestamp %d", entry.ID, cp.ID),
+		}
+	}
+
+	digest, err := hex.DecodeString(entry.Hash)
+	if err != nil {
+		return &ChainError{Kind: FailureHash, EntryID: entry.ID, msg: fmt.Sprintf("⚠️  malformed hash at entry ID %d", entry.ID)}
+	}
+
+	genTime, err := tsa.Verify(cp.Token, digest, s.tsaRoots)
+	if err != nil || !genTime.Equal(cp.GenTime) {
+		return &ChainError{
+			Kind:    FailureTimestamp,
+			EntryID: entry.ID,
+			msg:     fmt.Sprintf("⚠️  invalid trusted timestamp %d for entry %d", cp.ID, entry.ID),
+		}
+	}
+
+	return nil
+}
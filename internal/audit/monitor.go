@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/khawsic/health/internal/alert"
+)
+
+// Monitor periodically verifies the audit chain in the background and
+// raises an alert as soon as tampering is detected. When timestamping is
+// enabled it also anchors the verified head with a trusted timestamp.
+type Monitor struct {
+	service            *Service
+	notifier           alert.Notifier
+	interval           time.Duration
+	checkpointInterval time.Duration
+	lastCheckpoint     time.Time
+	alerted            bool
+}
+
+func NewMonitor(service *Service, notifier alert.Notifier, interval, checkpointInterval time.Duration) *Monitor {
+	return &Monitor{
+		service:            service,
+		notifier:           notifier,
+		interval:           interval,
+		checkpointInterval: checkpointInterval,
+	}
+}
+
+// Start runs verification immediately and then on every interval until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		m.runOnce()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.runOnce()
+			}
+		}
+	}()
+}
+
+func (m *Monitor) runOnce() {
+	result, err := m.service.VerifyIncremental()
+	if err != nil {
+		log.Printf("⚠️  Scheduled audit verification failed to run: %v", err)
+		return
+	}
+
+	if result.Valid {
+		m.alerted = false
+		m.checkpointIfDue()
+		return
+	}
+
+	// Alert once per tamper incident rather than on every tick
+	if m.alerted {
+		log.Printf("⚠️  Audit chain still failing verification: %s", result.Error)
+		return
+	}
+
+	details := map[string]interface{}{
+		"failure_kind":    result.FailureKind,
+		"failed_entry_id": result.FailedEntryID,
+		"verified_at":     result.VerifiedAt,
+	}
+
+	err = m.notifier.Notify(alert.Alert{
+		Severity: alert.SeverityHigh,
+		Title:    "Audit chain tampering detected",
+		Message:  result.Error,
+		Details:  details,
+		Time:     time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to deliver tamper alert: %v", err)
+		return
+	}
+
+	m.alerted = true
+}
+
+// checkpointIfDue timestamps the head — only ever called after a clean
+// verification so a tampered chain is never anchored
+func (m *Monitor) checkpointIfDue() {
+	if time.Since(m.lastCheckpoint) < m.checkpointInterval {
+		return
+	}
+
+	checkpoint, err := m.service.Checkpoint()
+	if err != nil {
+		log.Printf("⚠️  Audit checkpoint timestamping failed: %v", err)
+		return
+	}
+
+	m.lastCheckpoint = time.Now()
+	if checkpoint != nil {
+		log.Printf("✅ Audit head %d anchored with trusted timestamp %s", checkpoint.EntryID, checkpoint.GenTime.Format(time.RFC3339))
+	}
+}
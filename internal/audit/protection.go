@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// appendOnlyTables are protected by the 000008 migration triggers
+var appendOnlyTables = []string{"audit_logs", "chain_verifications", "audit_checkpoints"}
+
+// CheckProtections confirms the database enforces append-only audit tables
+// and that neither the application nor the audit writer can rewrite
+// history. Triggers only bind roles that cannot drop or disable them, so
+// neither connection's role may own the tables (see migration 000025). The
+// server refuses to start when this fails.
+func (s *Service) CheckProtections() error {
+	if s.writer == s.db {
+		return errors.New("no dedicated audit writer connection configured")
+	}
+
+	for _, table := range appendOnlyTables {
+		var triggers []string
+		err := s.db.Raw(`
+			SELECT tgname FROM pg_trigger
+			WHERE tgrelid = to_regclass(?) AND NOT tgisinternal AND tgenabled <> 'D'
+		`, table).Scan(&triggers).Error
+		if err != nil {
+			return fmt.Errorf("failed to inspect triggers on %s: %w", table, err)
+		}
+
+		enabled := map[string]bool{}
+		for _, name := range triggers {
+			enabled[name] = true
+		}
+		for _, required := range []string{table + "_append_only", table + "_no_truncate"} {
+			if !enabled[required] {
+				return fmt.Errorf("trigger %s missing or disabled on %s — apply migration 000008", required, table)
+			}
+		}
+	}
+
+	// The owner, or any member of the owning role, can ALTER TABLE ...
+	// DISABLE TRIGGER, so the application's own role must not own them
+	for _, table := range appendOnlyTables {
+		var owner struct {
+			Name    string
+			Current bool
+		}
+		err := s.db.Raw(`
+			SELECT pg_get_userbyid(c.relowner) AS name, pg_has_role(c.relowner, 'USAGE') AS current
+			FROM pg_class c WHERE c.oid = to_regclass(?)
+		`, table).Scan(&owner).Error
+		if err != nil {
+			return fmt.Errorf("failed to inspect owner of %s: %w", table, err)
+		}
+		if owner.Current {
+			return fmt.Errorf("application role can act as %q, the owner of %s, and could disable its triggers — apply migration 000025", owner.Name, table)
+		}
+	}
+
+	var writer struct {
+		Name      string
+		Superuser bool
+		BypassRLS bool
+	}
+	err := s.writer.Raw(`
+		SELECT rolname AS name, rolsuper AS superuser, rolbypassrls AS bypass_rls
+		FROM pg_roles WHERE rolname = current_user
+	`).Scan(&writer).Error
+	if err != nil {
+		return fmt.Errorf("failed to inspect audit writer role: %w", err)
+	}
+	if writer.Superuser || writer.BypassRLS {
+		return fmt.Errorf("audit writer role %q must not be a superuser", writer.Name)
+	}
+
+	for _, table := range appendOnlyTables {
+		var privileges struct {
+			Owner    bool
+			Insert   bool
+			Update   bool
+			Delete   bool
+			Truncate bool
+		}
+		err := s.writer.Raw(`
+			SELECT
+				pg_has_role(c.relowner, 'USAGE') AS owner,
+				has_table_privilege(c.oid, 'INSERT') AS insert,
+				has_table_privilege(c.oid, 'UPDATE') AS update,
+				has_table_privilege(c.oid, 'DELETE') AS delete,
+				has_table_privilege(c.oid, 'TRUNCATE') AS truncate
+			FROM pg_class c WHERE c.oid = to_regclass(?)
+		`, table).Scan(&privileges).Error
+		if err != nil {
+			return fmt.Errorf("failed to inspect privileges on %s: %w", table, err)
+		}
+
+		if privileges.Owner {
+			return fmt.Errorf("audit writer role %q owns %s and could drop its triggers", writer.Name, table)
+		}
+		if !privileges.Insert {
+			return fmt.Errorf("audit writer role %q cannot INSERT into %s", writer.Name, table)
+		}
+		if privileges.Update || privileges.Delete || privileges.Truncate {
+			return fmt.Errorf("audit writer role %q may modify %s — it must be INSERT/SELECT only", writer.Name, table)
+		}
+	}
+
+	return nil
+}
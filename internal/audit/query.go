@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxActions      = 20
+	exportBatchSize = 500
+)
+
+var actionPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// FilterOptions holds all possible audit log filters
+type FilterOptions struct {
+	UserID    *uint
+	RecordID  *uint
+	PatientID *uint
+	Actions   []string
+	Role      string
+	IPAddress string
+	RequestID string
+	FromDate  *time.Time
+	ToDate    *time.Time
+	Page      int
+	PageSize  int
+	// Cursor is the ID of the last entry already seen — keyset pagination
+	// returns only entries after it, so pages stay stable while the chain grows
+	Cursor uint
+}
+
+// ValidationError reports a single invalid filter field
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// Validate rejects malformed filters and fills in paging defaults
+func (o *FilterOptions) Validate() error {
+	if o.Page < 0 {
+		return &ValidationError{Field: "page", Message: "must be 1 or greater"}
+	}
+	if o.Page == 0 {
+		o.Page = 1
+	}
+
+	if o.PageSize < 0 || o.PageSize > maxPageSize {
+		return &ValidationError{Field: "page_size", Message: fmt.Sprintf("must be between 1 and %d", maxPageSize)}
+	}
+	if o.PageSize == 0 {
+		o.PageSize = defaultPageSize
+	}
+
+	if len(o.Actions) > maxActions {
+		return &ValidationError{Field: "action", Message: fmt.Sprintf("at most %d actions may be given", maxActions)}
+	}
+	for _, action := range o.Actions {
+		if !actionPattern.MatchString(action) {
+			return &ValidationError{Field: "action", Message: fmt.Sprintf("%q is not a valid action name", action)}
+		}
+	}
+
+	if o.IPAddress != "" && net.ParseIP(o.IPAddress) == nil {
+		return &ValidationError{Field: "ip", Message: "must be a valid IPv4 or IPv6 address"}
+	}
+
+	if o.FromDate != nil && o.ToDate != nil && o.FromDate.After(*o.ToDate) {
+		return &ValidationError{Field: "from_date", Message: "must not be after to_date"}
+	}
+
+	return nil
+}
+
+// applyFilters narrows a query by every filter that is set
+func (o *FilterOptions) applyFilters(query *gorm.DB) *gorm.DB {
+	if o.UserID != nil {
+		query = query.Where("user_id = ?", *o.UserID)
+	}
+	if o.RecordID != nil {
+		query = query.Where("record_id = ?", *o.RecordID)
+	}
+	if o.PatientID != nil {
+		query = query.Where("patient_id = ?", *o.PatientID)
+	}
+	if len(o.Actions) > 0 {
+		query = query.Where("action IN ?", o.Actions)
+	}
+	if o.Role != "" {
+		query = query.Where("role = ?", strings.ToLower(o.Role))
+	}
+	if o.IPAddress != "" {
+		query = query.Where("ip_address = ?", o.IPAddress)
+	}
+	if o.RequestID != "" {
+		query = query.Where("request_id = ?", o.RequestID)
+	}
+	if o.FromDate != nil {
+		query = query.Where("timestamp >= ?", *o.FromDate)
+	}
+	if o.ToDate != nil {
+		query = query.Where("timestamp <= ?", *o.ToDate)
+	}
+	return query
+}
+
+// describe summarises the filters that are set, for the access audit trail
+func (o *FilterOptions) describe() map[string]interface{} {
+	filters := map[string]interface{}{}
+	if o.UserID != nil {
+		filters["user_id"] = *o.UserID
+	}
+	if o.RecordID != nil {
+		filters["record_id"] = *o.RecordID
+	}
+	if o.PatientID != nil {
+		filters["patient_id"] = *o.PatientID
+	}
+	if len(o.Actions) > 0 {
+		filters["actions"] = o.Actions
+	}
+	if o.Role != "" {
+		filters["role"] = o.Role
+	}
+	if o.IPAddress != "" {
+		filters["ip"] = o.IPAddress
+	}
+	if o.RequestID != "" {
+		filters["request_id"] = o.RequestID
+	}
+	if o.FromDate != nil {
+		filters["from_date"] = o.FromDate.UTC().Format(time.RFC3339)
+	}
+	if o.ToDate != nil {
+		filters["to_date"] = o.ToDate.UTC().Format(time.RFC3339)
+	}
+	if o.Cursor != 0 {
+		filters["cursor"] = o.Cursor
+	}
+	return filters
+}
+
+// FilterLogs returns filtered and paginated audit log entries
+func (s *Service) FilterLogs(ctx context.Context, opts FilterOptions) ([]AuditLog, int64, error) {
+	var logs []AuditLog
+	var total int64
+
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (opts.Page - 1) * opts.PageSize
+
+	// Build query dynamically based on filters
+	query := opts.applyFilters(s.db.Model(&AuditLog{}))
+
+	// Get total count with filters applied
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get filtered paginated results
+	if err := query.Order("id ASC").
+		Limit(opts.PageSize).
+		Offset(offset).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	s.logAccess(ctx, "FILTER_AUDIT_LOGS", map[string]interface{}{
+		"filters":   opts.describe(),
+		"entry_ids": entryIDs(logs),
+	})
+
+	return logs, total, nil
+}
+
+// QueryLogs returns one keyset page of filtered entries after opts.Cursor.
+// The returned cursor is nil when there are no further entries.
+func (s *Service) QueryLogs(ctx context.Context, opts FilterOptions) ([]AuditLog, *uint, error) {
+	var logs []AuditLog
+
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	// Fetch one extra row to know whether another page exists
+	err := opts.applyFilters(s.db.Model(&AuditLog{})).
+		Where("id > ?", opts.Cursor).
+		Order("id ASC").
+		Limit(opts.PageSize + 1).
+		Find(&logs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *uint
+	if len(logs) > opts.PageSize {
+		logs = logs[:opts.PageSize]
+		last := logs[len(logs)-1].ID
+		next = &last
+	}
+
+	s.logAccess(ctx, "SEARCH_AUDIT_LOGS", map[string]interface{}{
+		"filters":   opts.describe(),
+		"entry_ids": entryIDs(logs),
+	})
+
+	return logs, next, nil
+}
+
+// ExportLogs streams every entry matching opts to fn in ID order,
+// reading in batches so large case files never sit in memory at once
+func (s *Service) ExportLogs(ctx context.Context, opts FilterOptions, fn func(AuditLog) error) (err error) {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	// Record what actually left the system once streaming ends, including
+	// an export the client abandoned part way through
+	var exported int
+	var first, last *AuditLog
+	defer func() {
+		details := map[string]interface{}{
+			"filters": opts.describe(),
+			"count":   exported,
+		}
+		if first != nil {
+			details["first_entry_id"] = first.ID
+			details["first_entry_hash"] = first.Hash
+			details["last_entry_id"] = last.ID
+			details["last_entry_hash"] = last.Hash
+		}
+		if err != nil {
+			details["aborted"] = true
+			details["error"] = err.Error()
+		}
+		s.logAccess(ctx, "EXPORT_AUDIT_LOGS", details)
+	}()
+
+	cursor := opts.Cursor
+	for {
+		var batch []AuditLog
+		err := opts.applyFilters(s.db.Model(&AuditLog{})).
+			Where("id > ?", cursor).
+			Order("id ASC").
+			Limit(exportBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			if err := fn(batch[i]); err != nil {
+				return err
+			}
+			if first == nil {
+				first = &batch[i]
+			}
+			last = &batch[i]
+			exported++
+		}
+
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		cursor = batch[len(batch)-1].ID
+	}
+}
@@ -1,64 +1,190 @@
 package audit
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/khawsic/health/internal/crypto"
+	"github.com/khawsic/health/internal/requestctx"
+	"github.com/khawsic/health/internal/tsa"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type AuditLog struct {
-	ID        uint           `gorm:"primaryKey"`
-	UserID    uint           `gorm:"not null"`
-	Action    string         `gorm:"not null"`
-	RecordID  *uint
-	Timestamp time.Time      `gorm:"not null"`
-	PrevHash  string         `gorm:"not null"`
-	Hash      string         `gorm:"not null;uniqueIndex"`
-	Signature string         `gorm:"not null"`
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;index"`
+	Role        string    `gorm:"index"`
+	Action      string    `gorm:"not null;index"`
+	RecordID    *uint     `gorm:"index"`
+	PatientID   *uint     `gorm:"index"`
+	IPAddress   string    `gorm:"index"`
+	RequestID   string    `gorm:"index"`
+	Timestamp   time.Time `gorm:"not null;index"`
+	Details     string    `gorm:"type:text"` // JSON, e.g. record IDs disclosed
+	HashVersion int       `gorm:"not null;default:1"`
+	PrevHash    string    `gorm:"not null"`
+	Hash        string    `gorm:"not null;uniqueIndex"`
+	Signature   string    `gorm:"not null"`
 }
 
-// FilterOptions holds all possible audit log filters
-type FilterOptions struct {
-	UserID    *uint
+// Entry describes a single event to be appended to the audit chain
+type Entry struct {
+	UserID    uint
+	Role      string
 	Action    string
-	FromDate  *time.Time
-	ToDate    *time.Time
-	Page      int
-	PageSize  int
+	RecordID  *uint
+	PatientID *uint
+	IPAddress string
+	RequestID string
+	Details   map[string]interface{}
+}
+
+// currentHashVersion is the canonical hash format used for new entries.
+// Version 1 entries predate the request metadata columns and version 2
+// entries predate Details.
+const currentHashVersion = 3
+
+// chainLockKey identifies the advisory lock held while appending to the chain
+const chainLockKey = 7_302_026
+
+// ChainVerification is the persisted result of one verification run
+type ChainVerification struct {
+	ID             uint   `gorm:"primaryKey"`
+	FromEntryID    uint   `gorm:"not null"`
+	ToEntryID      uint   `gorm:"not null"`
+	EntriesChecked int    `gorm:"not null"`
+	Valid          bool   `gorm:"not null;index"`
+	FailureKind    string // hash, link, signature, timestamp
+	FailedEntryID  *uint
+	Error          string
+	VerifiedAt     time.Time `gorm:"not null;index"`
+}
+
+// Failure kinds reported by chain verification
+const (
+	FailureHash      = "hash"
+	FailureLink      = "link"
+	FailureSignature = "signature"
+	FailureTimestamp = "timestamp"
+)
+
+// ChainError describes where and how the audit chain failed verification
+type ChainError struct {
+	Kind    string
+	EntryID uint
+	msg     string
+}
+
+func (e *ChainError) Error() string {
+	return e.msg
 }
 
 type Service struct {
 	db         *gorm.DB
+	writer     *gorm.DB // low-privilege INSERT-only connection for appends
 	privateKey ed25519.PrivateKey
 	publicKey  ed25519.PublicKey
+	tsa        tsa.Authority
+	tsaRoots   *x509.CertPool
 }
 
 func NewService(db *gorm.DB, privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) *Service {
 	return &Service{
 		db:         db,
+		writer:     db,
 		privateKey: privateKey,
 		publicKey:  publicKey,
 	}
 }
 
-func (s *Service) Migrate() error {
-	return s.db.AutoMigrate(&AuditLog{})
+// UseWriter routes all appends through a connection authenticated as the
+// dedicated audit writer role, which may only INSERT and SELECT
+func (s *Service) UseWriter(writer *gorm.DB) {
+	s.writer = writer
 }
 
 // Log adds a new tamper-proof signed entry to the audit chain
 func (s *Service) Log(userID uint, action string, recordID *uint) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return s.LogEntry(context.Background(), Entry{
+		UserID:   userID,
+		Action:   action,
+		RecordID: recordID,
+	})
+}
+
+// LogEntry appends an entry, filling actor and request metadata that the
+// caller left empty from the request context
+func (s *Service) LogEntry(ctx context.Context, e Entry) error {
+	info := requestctx.From(ctx)
+	if e.UserID == 0 {
+		e.UserID = info.UserID
+	}
+	if e.Role == "" {
+		e.Role = info.Role
+	}
+	if e.IPAddress == "" {
+		e.IPAddress = info.IPAddress
+	}
+	if e.RequestID == "" {
+		e.RequestID = info.RequestID
+	}
+
+	// Entries made while acting for a patient name both the proxy, as the
+	// actor, and the patient
+	if info.ActingAs != 0 {
+		if e.PatientID == nil {
+			subject := info.ActingAs
+			e.PatientID = &subject
+		}
+		details := map[string]interface{}{
+			"acting_as":             info.ActingAs,
+			"proxy_relationship_id": info.ProxyID,
+		}
+		for key, value := range e.Details {
+			details[key] = value
+		}
+		e.Details = details
+	}
+
+	// Requests made with an API key name the key as well as its service
+	// account
+	if info.APIKeyID != 0 {
+		details := map[string]interface{}{"api_key_id": info.APIKeyID}
+		for key, value := range e.Details {
+			details[key] = value
+		}
+		e.Details = details
+	}
+
+	var details string
+	if len(e.Details) > 0 {
+		encoded, err := json.Marshal(e.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = string(encoded)
+	}
+
+	return s.writer.Transaction(func(tx *gorm.DB) error {
 		var last AuditLog
 		var prevHash string
 
-		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Order("id DESC").
+		// Serialise appends with an advisory lock — unlike SELECT ... FOR UPDATE
+		// it needs no UPDATE privilege and also covers the empty-table case
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+			return fmt.Errorf("failed to lock audit chain: %w", err)
+		}
+
+		err := tx.Order("id DESC").
 			Limit(1).
 			Find(&last).Error
 
@@ -66,40 +192,93 @@ func (s *Service) Log(userID uint, action string, recordID *uint) error {
 			prevHash = last.Hash
 		}
 
-		timestamp := time.Now().UTC()
+		// Postgres stores microseconds — truncate so the hash can be recomputed
+		timestamp := time.Now().UTC().Truncate(time.Microsecond)
 
-		data := fmt.Sprintf("%d|%s|%v|%s|%s",
-			userID,
-			action,
-			recordID,
-			timestamp.String(),
-			prevHash,
-		)
-
-		hash := sha256.Sum256([]byte(data))
-		hashHex := hex.EncodeToString(hash[:])
+		logEntry := AuditLog{
+			UserID:      e.UserID,
+			Role:        e.Role,
+			Action:      e.Action,
+			RecordID:    e.RecordID,
+			PatientID:   e.PatientID,
+			IPAddress:   e.IPAddress,
+			RequestID:   e.RequestID,
+			Details:     details,
+			Timestamp:   timestamp,
+			HashVersion: currentHashVersion,
+			PrevHash:    prevHash,
+		}
+		logEntry.Hash = logEntry.computeHash()
 
-		signature, err := crypto.SignData(s.privateKey, []byte(hashHex))
+		signature, err := crypto.SignData(s.privateKey, []byte(logEntry.Hash))
 		if err != nil {
 			return fmt.Errorf("failed to sign audit entry: %w", err)
 		}
-
-		logEntry := AuditLog{
-			UserID:    userID,
-			Action:    action,
-			RecordID:  recordID,
-			Timestamp: timestamp,
-			PrevHash:  prevHash,
-			Hash:      hashHex,
-			Signature: signature,
-		}
+		logEntry.Signature = signature
 
 		return tx.Create(&logEntry).Error
 	})
 }
 
+// computeHash returns the hex SHA-256 of the entry's canonical form
+func (a *AuditLog) computeHash() string {
+	var data string
+
+	switch a.HashVersion {
+	case 0, 1:
+		// Legacy format — kept byte-for-byte so existing entries still verify
+		data = fmt.Sprintf("%d|%s|%v|%s|%s",
+			a.UserID,
+			a.Action,
+			a.RecordID,
+			a.Timestamp.UTC().String(),
+			a.PrevHash,
+		)
+	case 2:
+		data = fmt.Sprintf("v%d|%d|%s|%s|%s|%s|%s|%s|%s|%s",
+			a.HashVersion,
+			a.UserID,
+			a.Role,
+			a.Action,
+			formatOptionalID(a.RecordID),
+			formatOptionalID(a.PatientID),
+			a.IPAddress,
+			a.RequestID,
+			a.Timestamp.UTC().Format(time.RFC3339Nano),
+			a.PrevHash,
+		)
+	default:
+		// JSON array so client-supplied values (request ID, details) cannot
+		// shift field boundaries the way a delimiter could
+		encoded, _ := json.Marshal([]interface{}{
+			a.HashVersion,
+			a.UserID,
+			a.Role,
+			a.Action,
+			a.RecordID,
+			a.PatientID,
+			a.IPAddress,
+			a.RequestID,
+			a.Details,
+			a.Timestamp.UTC().Format(time.RFC3339Nano),
+			a.PrevHash,
+		})
+		data = string(encoded)
+	}
+
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+func formatOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
 // GetLogs returns paginated audit log entries
-func (s *Service) GetLogs(page, pageSize int) ([]AuditLog, int64, error) {
+func (s *Service) GetLogs(ctx context.Context, page, pageSize int) ([]AuditLog, int64, error) {
 	var logs []AuditLog
 	var total int64
 
@@ -123,90 +302,185 @@ func (s *Service) GetLogs(page, pageSize int) ([]AuditLog, int64, error) {
 		return nil, 0, err
 	}
 
+	s.logAccess(ctx, "READ_AUDIT_LOGS", map[string]interface{}{
+		"page":      page,
+		"page_size": pageSize,
+		"entry_ids": entryIDs(logs),
+	})
+
 	return logs, total, nil
 }
 
-// FilterLogs returns filtered and paginated audit log entries
-func (s *Service) FilterLogs(opts FilterOptions) ([]AuditLog, int64, error) {
-	var logs []AuditLog
-	var total int64
+// VerifyChain walks every entry and verifies both hash chain and Ed25519 signatures,
+// then checks the chain against its RFC 3161 checkpoints
+func (s *Service) VerifyChain(ctx context.Context) (bool, error) {
+	valid, err := s.verifyChain()
 
-	if opts.Page < 1 {
-		opts.Page = 1
-	}
-	if opts.PageSize < 1 || opts.PageSize > 100 {
-		opts.PageSize = 20
+	details := map[string]interface{}{"valid": valid}
+	if err != nil {
+		details["error"] = err.Error()
 	}
+	s.logAccess(ctx, "VERIFY_AUDIT_CHAIN", details)
 
-	offset := (opts.Page - 1) * opts.PageSize
+	return valid, err
+}
 
-	// Build query dynamically based on filters
-	query := s.db.Model(&AuditLog{})
+func (s *Service) verifyChain() (bool, error) {
+	var logs []AuditLog
+	if err := s.db.Order("id ASC").Find(&logs).Error; err != nil {
+		return false, err
+	}
 
-	if opts.UserID != nil {
-		query = query.Where("user_id = ?", *opts.UserID)
+	for i, entry := range logs {
+		var prev *AuditLog
+		if i > 0 {
+			prev = &logs[i-1]
+		}
+		if err := s.verifyEntry(entry, prev); err != nil {
+			return false, err
+		}
 	}
 
-	if opts.Action != "" {
-		query = query.Where("action = ?", opts.Action)
+	if err := s.verifyCheckpoints(logs); err != nil {
+		return false, err
 	}
 
-	if opts.FromDate != nil {
-		query = query.Where("timestamp >= ?", *opts.FromDate)
+	return true, nil
+}
+
+// VerifyIncremental verifies only the entries appended since the last
+// successful run, plus the entry that run ended on, and persists the
+// result. It does not re-check entries behind that checkpoint: a valid
+// result says nothing new was tampered with, not that the whole chain is
+// intact. Use VerifyChain for a full check.
+func (s *Service) VerifyIncremental() (*ChainVerification, error) {
+	var checkpoint ChainVerification
+	err := s.db.Where("valid = ?", true).
+		Order("id DESC").
+		Limit(1).
+		Find(&checkpoint).Error
+	if err != nil {
+		return nil, err
 	}
 
-	if opts.ToDate != nil {
-		query = query.Where("timestamp <= ?", *opts.ToDate)
+	result := &ChainVerification{
+		FromEntryID: checkpoint.ToEntryID,
+		ToEntryID:   checkpoint.ToEntryID,
+		Valid:       true,
 	}
 
-	// Get total count with filters applied
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	// Re-check the anchor entry so tampering behind the checkpoint is still caught
+	var prev *AuditLog
+	if checkpoint.ToEntryID != 0 {
+		var anchor AuditLog
+		if err := s.db.First(&anchor, checkpoint.ToEntryID).Error; err != nil {
+			result.fail(&ChainError{
+				Kind:    FailureLink,
+				EntryID: checkpoint.ToEntryID,
+				msg:     fmt.Sprintf("⚠️  checkpoint entry %d is missing", checkpoint.ToEntryID),
+			})
+			return result, s.saveVerification(result)
+		}
+		if chainErr := s.verifyEntry(anchor, nil); chainErr != nil {
+			result.fail(chainErr)
+			return result, s.saveVerification(result)
+		}
+		prev = &anchor
 	}
 
-	// Get filtered paginated results
-	if err := query.Order("id ASC").
-		Limit(opts.PageSize).
-		Offset(offset).
-		Find(&logs).Error; err != nil {
-		return nil, 0, err
+	var batch []AuditLog
+	err = s.db.Where("id > ?", checkpoint.ToEntryID).
+		Order("id ASC").
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				entry := batch[i]
+				if chainErr := s.verifyEntry(entry, prev); chainErr != nil {
+					result.fail(chainErr)
+					return errStopVerification
+				}
+				result.EntriesChecked++
+				result.ToEntryID = entry.ID
+				prev = &entry
+			}
+			return nil
+		}).Error
+	if err != nil && !errors.Is(err, errStopVerification) {
+		return nil, err
 	}
 
-	return logs, total, nil
+	return result, s.saveVerification(result)
 }
 
-// VerifyChain walks every entry and verifies both hash chain and Ed25519 signatures
-func (s *Service) VerifyChain() (bool, error) {
-	var logs []AuditLog
-	if err := s.db.Order("id ASC").Find(&logs).Error; err != nil {
-		return false, err
+// LastVerification returns the most recent persisted verification result
+func (s *Service) LastVerification() (*ChainVerification, error) {
+	var last ChainVerification
+	err := s.db.Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return nil, err
+	}
+	if last.ID == 0 {
+		return nil, nil
+	}
+	return &last, nil
+}
+
+var errStopVerification = errors.New("stop verification")
+
+// logAccess records a read of the audit trail itself. Failures are logged
+// rather than returned, matching how record services treat audit errors.
+func (s *Service) logAccess(ctx context.Context, action string, details map[string]interface{}) {
+	if err := s.LogEntry(ctx, Entry{Action: action, Details: details}); err != nil {
+		log.Printf("⚠️  Audit log failed for %s: %v", action, err)
 	}
+}
 
+func entryIDs(logs []AuditLog) []uint {
+	ids := make([]uint, len(logs))
 	for i, entry := range logs {
-		data := fmt.Sprintf("%d|%s|%v|%s|%s",
-			entry.UserID,
-			entry.Action,
-			entry.RecordID,
-			entry.Timestamp.UTC().String(),
-			entry.PrevHash,
-		)
+		ids[i] = entry.ID
+	}
+	return ids
+}
 
-		hash := sha256.Sum256([]byte(data))
-		expected := hex.EncodeToString(hash[:])
+func (s *Service) saveVerification(result *ChainVerification) error {
+	result.VerifiedAt = time.Now().UTC()
+	return s.writer.Create(result).Error
+}
 
-		if expected != entry.Hash {
-			return false, fmt.Errorf("⚠️  hash chain broken at entry ID %d", entry.ID)
+func (r *ChainVerification) fail(err *ChainError) {
+	id := err.EntryID
+	r.Valid = false
+	r.FailureKind = err.Kind
+	r.FailedEntryID = &id
+	r.Error = err.Error()
+}
+
+// verifyEntry checks an entry's hash, its link to prev (if given) and its signature
+func (s *Service) verifyEntry(entry AuditLog, prev *AuditLog) *ChainError {
+	if entry.computeHash() != entry.Hash {
+		return &ChainError{
+			Kind:    FailureHash,
+			EntryID: entry.ID,
+			msg:     fmt.Sprintf("⚠️  hash chain broken at entry ID %d", entry.ID),
 		}
+	}
 
-		if i > 0 && entry.PrevHash != logs[i-1].Hash {
-			return false, fmt.Errorf("⚠️  chain link broken between entry %d and %d", logs[i-1].ID, entry.ID)
+	if prev != nil && entry.PrevHash != prev.Hash {
+		return &ChainError{
+			Kind:    FailureLink,
+			EntryID: entry.ID,
+			msg:     fmt.Sprintf("⚠️  chain link broken between entry %d and %d", prev.ID, entry.ID),
 		}
+	}
 
-		valid, err := crypto.VerifySignature(s.publicKey, []byte(entry.Hash), entry.Signature)
-		if err != nil || !valid {
-			return false, fmt.Errorf("⚠️  invalid signature at entry ID %d", entry.ID)
+	valid, err := crypto.VerifySignature(s.publicKey, []byte(entry.Hash), entry.Signature)
+	if err != nil || !valid {
+		return &ChainError{
+			Kind:    FailureSignature,
+			EntryID: entry.ID,
+			msg:     fmt.Sprintf("⚠️  invalid signature at entry ID %d", entry.ID),
 		}
 	}
 
-	return true, nil
-}
\ No newline at end of file
+	return nil
+}
@@ -0,0 +1,463 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/khawsic/health/internal/audit"
+	"github.com/khawsic/health/internal/authz"
+	"gorm.io/gorm"
+)
+
+// StatusDisabled marks an account an administrator switched off. It keeps
+// its data and can be enabled again.
+const StatusDisabled = "disabled"
+
+// auditRoleSystem is the actor role on audit entries no user initiated
+const auditRoleSystem = "system"
+
+var (
+	errUserNotFound = errors.New("user not found")
+	errSelfAction   = errors.New("administrators cannot perform this action on their own account")
+)
+
+// UserFilter narrows the admin user list
+type UserFilter struct {
+	Role     string
+	Kind     string
+	Status   string
+	Search   string // matched against name and email
+	Page     int
+	PageSize int
+}
+
+// UserSummary is what administrators see of an account. It leaves out
+// credentials and secrets.
+type UserSummary struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	Kind            string     `json:"kind"`
+	Department      string     `json:"department"`
+	DateOfBirth     *time.Time `json:"date_of_birth"`
+	Status          string     `json:"status"`
+	MFAEnabled      bool       `json:"mfa_enabled"`
+	PasskeyOnly     bool       `json:"passkey_only"`
+	FailedAttempts  int        `json:"failed_attempts"`
+	LockedUntil     *time.Time `json:"locked_until"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func summarize(user *User) UserSummary {
+	return UserSummary{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            user.Role,
+		Kind:            user.Kind,
+		Department:      user.Department,
+		DateOfBirth:     user.DateOfBirth,
+		Status:          user.Status,
+		MFAEnabled:      user.MFAEnabled,
+		PasskeyOnly:     user.PasskeyOnly,
+		FailedAttempts:  user.FailedAttempts,
+		LockedUntil:     user.LockedUntil,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+	}
+}
+
+// UseAuditLog records administrative account changes on the audit chain
+func (s *Service) UseAuditLog(auditService *audit.Service) {
+	s.auditLog = auditService
+}
+
+// recordAdminAction appends an entry about target to the audit chain. The
+// acting admin and request metadata come from ctx. Failures are logged
+// rather than returned, as in the record services.
+func (s *Service) recordAdminAction(ctx context.Context, action string, target *User, details map[string]interface{}) {
+	s.recordAccountAction(ctx, audit.Entry{Action: action, Details: details}, target)
+}
+
+// recordSystemAction records an account change nobody signed in made, such
+// as a role synced from an identity provider during login
+func (s *Service) recordSystemAction(ctx context.Context, action string, target *User, details map[string]interface{}) {
+	s.recordAccountAction(ctx, audit.Entry{Action: action, Role: auditRoleSystem, Details: details}, target)
+}
+
+func (s *Service) recordAccountAction(ctx context.Context, entry audit.Entry, target *User) {
+	if s.auditLog == nil {
+		return
+	}
+
+	if entry.Details == nil {
+		entry.Details = map[string]interface{}{}
+	}
+	entry.Details["target_user_id"] = target.ID
+	entry.Details["target_role"] = target.Role
+
+	if target.Role == "patient" {
+		entry.PatientID = &target.ID
+	}
+	if err := s.auditLog.LogEntry(ctx, entry); err != nil {
+		log.Printf("⚠️  Audit log failed for %s: %v", entry.Action, err)
+	}
+}
+
+// 👥 ListUsers — one page of accounts, newest first
+func (s *Service) ListUsers(ctx context.Context, filter UserFilter) ([]UserSummary, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	query := s.DB.Model(&User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []User
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	summaries := make([]UserSummary, len(users))
+	ids := make([]uint, len(users))
+	for i := range users {
+		summaries[i] = summarize(&users[i])
+		ids[i] = users[i].ID
+	}
+
+	if s.auditLog != nil {
+		if err := s.auditLog.LogEntry(ctx, audit.Entry{
+			Action: "LIST_USERS",
+			Details: map[string]interface{}{
+				"role":     filter.Role,
+				"status":   filter.Status,
+				"search":   filter.Search,
+				"page":     filter.Page,
+				"user_ids": ids,
+			},
+		}); err != nil {
+			log.Printf("⚠️  Audit log failed for LIST_USERS: %v", err)
+		}
+	}
+
+	return summaries, total, nil
+}
+
+// GetUser returns one account with its linked sign-in identities and
+// active session count
+func (s *Service) GetUser(ctx context.Context, userID uint) (*UserSummary, []UserIdentity, int64, error) {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, nil, 0, errUserNotFound
+	}
+
+	var identities []UserIdentity
+	s.DB.Where("user_id = ?", user.ID).Find(&identities)
+
+	var sessions int64
+	s.DB.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Count(&sessions)
+
+	s.recordAdminAction(ctx, "READ_USER", &user, nil)
+
+	summary := summarize(&user)
+	return &summary, identities, sessions, nil
+}
+
+// 🔓 UnlockUser — clears a lockout and the failed attempt counter
+func (s *Service) UnlockUser(ctx context.Context, userID uint) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"failed_attempts": 0,
+		"locked_until":    nil,
+		"last_failed_at":  nil,
+	}).Error; err != nil {
+		return err
+	}
+	s.forgetUser(user.ID)
+
+	s.recordAdminAction(ctx, "UNLOCK_USER", user, map[string]interface{}{
+		"failed_attempts": user.FailedAttempts,
+		"locked_until":    user.LockedUntil,
+	})
+	return nil
+}
+
+// ⛔ DisableUser — blocks sign-in and ends every session
+func (s *Service) DisableUser(ctx context.Context, adminID, userID uint) error {
+	if adminID == userID {
+		return errSelfAction
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.disable(user); err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, "DISABLE_USER", user, map[string]interface{}{
+		"previous_status": user.Status,
+	})
+	return nil
+}
+
+func (s *Service) disable(user *User) error {
+	if err := s.DB.Model(&User{}).Where("id = ?", user.ID).Update("status", StatusDisabled).Error; err != nil {
+		return err
+	}
+	return s.InvalidateUserTokens(user.ID)
+}
+
+// ✅ EnableUser — lets a disabled account sign in again
+func (s *Service) EnableUser(ctx context.Context, userID uint) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.Status != StatusDisabled {
+		return errors.New("only disabled accounts can be enabled")
+	}
+
+	if err := s.DB.Model(user).Update("status", StatusActive).Error; err != nil {
+		return err
+	}
+	s.forgetUser(user.ID)
+
+	s.recordAdminAction(ctx, "ENABLE_USER", user, nil)
+	return nil
+}
+
+// 🎭 ChangeUserRole — a new role takes effect immediately; existing tokens
+// carry the old role and are invalidated. The last active account that can
+// manage roles cannot be moved to a role that cannot.
+func (s *Service) ChangeUserRole(ctx context.Context, adminID, userID uint, role string) error {
+	if !s.roleExists(role) {
+		return errors.New("unknown role")
+	}
+	if adminID == userID {
+		return errSelfAction
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+	if s.canManageRoles(user.Role) && !s.canManageRoles(role) {
+		others, err := s.otherRoleManagers(user.ID)
+		if err != nil {
+			return err
+		}
+		if !others {
+			return errors.New("this is the last account that can manage roles — give another account that permission first")
+		}
+	}
+
+	if err := s.DB.Model(&User{}).Where("id = ?", user.ID).Update("role", role).Error; err != nil {
+		return err
+	}
+	if err := s.InvalidateUserTokens(user.ID); err != nil {
+		return err
+	}
+	if s.authorizer != nil {
+		s.authorizer.ForgetUser(user.ID)
+	}
+
+	s.recordAdminAction(ctx, "CHANGE_USER_ROLE", user, map[string]interface{}{
+		"previous_role": user.Role,
+		"new_role":      role,
+	})
+	return nil
+}
+
+// 🏥 SetUserDepartment — the department access policies compare against.
+// It applies to the user's next request without signing them out.
+func (s *Service) SetUserDepartment(ctx context.Context, userID uint, department string) error {
+	department = authz.NormalizeDepartment(department)
+	if len(department) > 100 {
+		return errors.New("department must be at most 100 characters")
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.Department == department {
+		return nil
+	}
+
+	if err := s.DB.Model(&User{}).Where("id = ?", user.ID).Update("department", department).Error; err != nil {
+		return err
+	}
+	if s.authorizer != nil {
+		s.authorizer.ForgetUser(user.ID)
+	}
+
+	s.recordAdminAction(ctx, "CHANGE_USER_DEPARTMENT", user, map[string]interface{}{
+		"previous_department": user.Department,
+		"new_department":      department,
+	})
+	return nil
+}
+
+// 🎂 SetUserDateOfBirth — recorded by staff from identity documents; proxy
+// access for parents ends when the patient comes of age. Nil clears it.
+func (s *Service) SetUserDateOfBirth(ctx context.Context, userID uint, dateOfBirth *time.Time) error {
+	if dateOfBirth != nil {
+		day := time.Date(dateOfBirth.Year(), dateOfBirth.Month(), dateOfBirth.Day(), 0, 0, 0, 0, time.UTC)
+		if day.After(time.Now()) {
+			return errors.New("date of birth cannot be in the future")
+		}
+		dateOfBirth = &day
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.Model(&User{}).Where("id = ?", user.ID).Update("date_of_birth", dateOfBirth).Error; err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, "CHANGE_USER_DATE_OF_BIRTH", user, map[string]interface{}{
+		"previous_date_of_birth": formatDate(user.DateOfBirth),
+		"new_date_of_birth":      formatDate(dateOfBirth),
+	})
+	return nil
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// 🚪 ForceLogout — ends every session the user has
+func (s *Service) ForceLogout(ctx context.Context, userID uint) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.InvalidateUserTokens(user.ID); err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, "FORCE_LOGOUT_USER", user, nil)
+	return nil
+}
+
+// 🗑️ DeleteUser — soft-deletes the account. Its records and audit history
+// remain; the account can no longer sign in or be found.
+func (s *Service) DeleteUser(ctx context.Context, adminID, userID uint) error {
+	if adminID == userID {
+		return errSelfAction
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.InvalidateUserTokens(user.ID); err != nil {
+		return err
+	}
+	if err := s.DB.Delete(user).Error; err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, "DELETE_USER", user, map[string]interface{}{
+		"email": user.Email,
+	})
+	return nil
+}
+
+// 📦 OffboardUser — disables a departing user and ends their sessions. A
+// departing doctor needs an active doctor as successor; any other staff
+// successor must be an active non-patient account. The caller moves their
+// patients and care assignments once this succeeds. Offboarding an already
+// disabled user is allowed so a failed transfer can be retried.
+func (s *Service) OffboardUser(ctx context.Context, adminID, userID, successorID uint) (*User, error) {
+	if adminID == userID {
+		return nil, errSelfAction
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role == "doctor" && successorID == 0 {
+		return nil, errors.New("a successor doctor is required to offboard a doctor")
+	}
+	if successorID != 0 {
+		if successorID == user.ID {
+			return nil, errors.New("successor must be a different user")
+		}
+		successor, err := s.findUser(successorID)
+		switch {
+		case user.Role == "doctor" && (err != nil || successor.Role != "doctor" || successor.Status != StatusActive):
+			return nil, errors.New("successor must be an active doctor")
+		case err != nil || successor.Role == "patient" || successor.Status != StatusActive:
+			return nil, errors.New("successor must be an active staff member")
+		}
+	}
+
+	if err := s.disable(user); err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{"previous_status": user.Status}
+	if successorID != 0 {
+		details["successor_id"] = successorID
+	}
+	s.recordAdminAction(ctx, "OFFBOARD_USER", user, details)
+
+	user.Status = StatusDisabled
+	return user, nil
+}
+
+func (s *Service) findUser(userID uint) (*User, error) {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
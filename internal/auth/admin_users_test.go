@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChangeUserRoleKeepsARoleManager(t *testing.T) {
+	s := newTestService(t)
+	const actingAdmin = 999
+	admin := createUser(t, s, "admin@example.com", "admin")
+
+	if err := s.ChangeUserRole(context.Background(), actingAdmin, admin.ID, "doctor"); err == nil {
+		t.Fatal("demoted the last account that can manage roles")
+	}
+
+	other := createUser(t, s, "admin2@example.com", "admin")
+	if err := s.ChangeUserRole(context.Background(), actingAdmin, admin.ID, "doctor"); err != nil {
+		t.Fatalf("demoting one of two role managers: %v", err)
+	}
+
+	// A disabled account cannot stand in for the last active manager
+	s.DB.Model(admin).Update("status", StatusDisabled)
+	if err := s.ChangeUserRole(context.Background(), actingAdmin, other.ID, "doctor"); err == nil {
+		t.Fatal("demoted the last active account that can manage roles")
+	}
+}
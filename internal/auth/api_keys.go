@@ -0,0 +1,438 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/khawsic/health/internal/audit"
+	"github.com/khawsic/health/internal/authz"
+	"github.com/khawsic/health/internal/crypto"
+	"github.com/khawsic/health/internal/requestctx"
+	"gorm.io/gorm"
+)
+
+// Account kinds. Service accounts belong to integrations such as the lab
+// and billing systems; they cannot sign in and authenticate with API keys.
+const (
+	KindHuman   = "human"
+	KindService = "service"
+)
+
+const (
+	// APIKeyPrefix starts every key so it is recognisable in a header, a
+	// log or a secret scanner
+	APIKeyPrefix = "hv_"
+
+	maxAPIKeyLifetime = 365 * 24 * time.Hour
+
+	// lastUsedGranularity bounds how often a busy key's last use is written
+	lastUsedGranularity = time.Minute
+
+	// serviceAccountDomain is reserved, so service account emails can never
+	// receive mail or collide with a person's address
+	serviceAccountDomain = "@service-accounts.invalid"
+
+	// unusablePassword matches no hasher, so password login always fails
+	unusablePassword = "!"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+
+	errNotServiceAccount = errors.New("not a service account")
+	errAPIKeyNotFound    = errors.New("API key not found")
+
+	serviceAccountNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{1,48}[a-z0-9]$`)
+)
+
+// APIKey is a credential for a service account. Only the SHA-256 hash of
+// the key is stored; Prefix is the public part that identifies it. A key
+// may use only the permissions in Scopes, and only those the account's
+// role still grants.
+type APIKey struct {
+	ID               uint             `gorm:"primaryKey" json:"id"`
+	ServiceAccountID uint             `gorm:"not null;index" json:"service_account_id"`
+	Name             string           `gorm:"not null" json:"name"`
+	Prefix           string           `gorm:"not null;uniqueIndex" json:"prefix"`
+	KeyHash          string           `gorm:"not null" json:"-"`
+	Scopes           authz.StringList `gorm:"type:text;not null" json:"scopes"`
+	AllowedIPs       authz.StringList `gorm:"column:allowed_ips;type:text;not null" json:"allowed_ips"` // CIDRs; empty allows any address
+	ExpiresAt        time.Time        `gorm:"not null" json:"expires_at"`
+	LastUsedAt       *time.Time       `json:"last_used_at"`
+	LastUsedIP       string           `gorm:"column:last_used_ip" json:"last_used_ip"`
+	CreatedBy        uint             `gorm:"not null" json:"created_by"`
+	RevokedAt        *time.Time       `json:"revoked_at"`
+	RevokedBy        *uint            `json:"revoked_by"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// NewAPIKey describes a key to create
+type NewAPIKey struct {
+	Name       string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  time.Time
+}
+
+// 🤖 CreateServiceAccount — an account for an integration, holding role
+// and department like any user. It starts with no keys.
+func (s *Service) CreateServiceAccount(ctx context.Context, name, role, department string) (*UserSummary, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !serviceAccountNamePattern.MatchString(name) {
+		return nil, errors.New("name must be 3-50 lowercase letters, digits or hyphens")
+	}
+	if !s.roleExists(role) {
+		return nil, errors.New("unknown role")
+	}
+	department = authz.NormalizeDepartment(department)
+	if len(department) > 100 {
+		return nil, errors.New("department must be at most 100 characters")
+	}
+
+	user := User{
+		Name:       name,
+		Email:      name + serviceAccountDomain,
+		Password:   unusablePassword,
+		Role:       role,
+		Department: department,
+		Kind:       KindService,
+		Status:     StatusActive,
+	}
+
+	var existing int64
+	s.DB.Unscoped().Model(&User{}).Where("email = ?", user.Email).Count(&existing)
+	if existing > 0 {
+		return nil, errors.New("a service account with that name already exists")
+	}
+	if err := s.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	s.recordAdminAction(ctx, "CREATE_SERVICE_ACCOUNT", &user, map[string]interface{}{
+		"name":       user.Name,
+		"department": user.Department,
+	})
+
+	summary := summarize(&user)
+	return &summary, nil
+}
+
+// 🔑 CreateAPIKey — issues a key for a service account. The plaintext key
+// is returned once and cannot be recovered later.
+func (s *Service) CreateAPIKey(ctx context.Context, adminID, serviceAccountID uint, req NewAPIKey) (string, *APIKey, error) {
+	account, err := s.findServiceAccount(serviceAccountID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return "", nil, errors.New("name is required and must be at most 100 characters")
+	}
+
+	scopes, err := s.validateScopes(account.Role, req.Scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	allowedIPs, err := normalizeNetworks(req.AllowedIPs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	if !req.ExpiresAt.After(now) {
+		return "", nil, errors.New("expiry must be in the future")
+	}
+	if req.ExpiresAt.After(now.Add(maxAPIKeyLifetime)) {
+		return "", nil, errors.New("API keys may be valid for at most a year")
+	}
+
+	prefix, plaintext, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := APIKey{
+		ServiceAccountID: account.ID,
+		Name:             name,
+		Prefix:           prefix,
+		KeyHash:          crypto.HashString(plaintext),
+		Scopes:           scopes,
+		AllowedIPs:       allowedIPs,
+		ExpiresAt:        req.ExpiresAt,
+		CreatedBy:        adminID,
+	}
+	if err := s.DB.Create(&key).Error; err != nil {
+		return "", nil, err
+	}
+
+	s.recordAdminAction(ctx, "CREATE_API_KEY", account, map[string]interface{}{
+		"api_key_id":  key.ID,
+		"prefix":      key.Prefix,
+		"name":        key.Name,
+		"scopes":      []string(key.Scopes),
+		"allowed_ips": []string(key.AllowedIPs),
+		"expires_at":  key.ExpiresAt,
+	})
+
+	return plaintext, &key, nil
+}
+
+// ListAPIKeys returns a service account's keys, newest first. Revoked and
+// expired keys are kept for the record.
+func (s *Service) ListAPIKeys(serviceAccountID uint) ([]APIKey, error) {
+	if _, err := s.findServiceAccount(serviceAccountID); err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	err := s.DB.Where("service_account_id = ?", serviceAccountID).
+		Order("created_at DESC, id DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// ⛔ RevokeAPIKey — the key stops working on its next use
+func (s *Service) RevokeAPIKey(ctx context.Context, adminID, keyID uint) error {
+	var key APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errAPIKeyNotFound
+		}
+		return err
+	}
+	if key.RevokedAt != nil {
+		return errors.New("API key is already revoked")
+	}
+
+	now := time.Now()
+	if err := s.DB.Model(&APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": adminID,
+	}).Error; err != nil {
+		return err
+	}
+
+	account, err := s.findUser(key.ServiceAccountID)
+	if err != nil {
+		return err
+	}
+	s.recordAdminAction(ctx, "REVOKE_API_KEY", account, map[string]interface{}{
+		"api_key_id": key.ID,
+		"prefix":     key.Prefix,
+	})
+	return nil
+}
+
+// ValidateAPIKey authenticates a request made with an API key and fills
+// in the caller in the request info: the service account, its role and
+// the key's scopes. Every refusal gives the same answer.
+func (s *Service) ValidateAPIKey(ctx context.Context, plaintext string) error {
+	info := requestctx.From(ctx)
+
+	prefix, ok := apiKeyPrefix(plaintext)
+	if !ok {
+		return ErrInvalidAPIKey
+	}
+
+	var key APIKey
+	if err := s.DB.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(crypto.HashString(plaintext))) != 1 {
+		return ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	switch {
+	case key.RevokedAt != nil:
+		log.Printf("⚠️  Revoked API key %s presented from %s", key.Prefix, info.IPAddress)
+		return ErrInvalidAPIKey
+	case !key.ExpiresAt.After(now):
+		return ErrInvalidAPIKey
+	case !ipAllowed(key.AllowedIPs, info.IPAddress):
+		log.Printf("⚠️  API key %s presented from %s outside its allowlist", key.Prefix, info.IPAddress)
+		return ErrInvalidAPIKey
+	}
+
+	account, err := s.findServiceAccount(key.ServiceAccountID)
+	if err != nil {
+		return ErrInvalidAPIKey
+	}
+	if err := checkActive(account); err != nil {
+		return ErrInvalidAPIKey
+	}
+	if err := checkLocked(account); err != nil {
+		return err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedGranularity || key.LastUsedIP != info.IPAddress {
+		s.DB.Model(&APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": info.IPAddress,
+		})
+	}
+
+	info.UserID = account.ID
+	info.Role = account.Role
+	info.APIKeyID = key.ID
+	info.Scopes = key.Scopes
+	if info.Scopes == nil {
+		info.Scopes = []string{}
+	}
+	return nil
+}
+
+// RecordAPIKeyRequest puts a request made with an API key on the audit
+// chain, so every machine action is attributed to its key even where the
+// service it called audits nothing itself
+func (s *Service) RecordAPIKeyRequest(ctx context.Context, method, route string, status int) {
+	if s.auditLog == nil {
+		return
+	}
+
+	err := s.auditLog.LogEntry(ctx, audit.Entry{
+		Action: "API_KEY_REQUEST",
+		Details: map[string]interface{}{
+			"method": method,
+			"route":  route,
+			"status": status,
+		},
+	})
+	if err != nil {
+		log.Printf("⚠️  Audit log failed for API_KEY_REQUEST: %v", err)
+	}
+}
+
+func (s *Service) findServiceAccount(userID uint) (*User, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Kind != KindService {
+		return nil, errNotServiceAccount
+	}
+	return user, nil
+}
+
+// validateScopes requires at least one scope, each a permission the
+// account's role grants now. Later role changes are enforced when the key
+// is used, since checks need both the role and the scope.
+func (s *Service) validateScopes(role string, scopes []string) (authz.StringList, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	var granted map[string]bool
+	if s.authorizer != nil {
+		granted = map[string]bool{}
+		for _, permission := range s.authorizer.RolePermissions(role) {
+			granted[permission] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	var valid authz.StringList
+	for _, scope := range scopes {
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		if !authz.KnownPermission(scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if granted != nil && !granted[scope] {
+			return nil, fmt.Errorf("role %q does not grant %s", role, scope)
+		}
+		valid = append(valid, scope)
+	}
+	sort.Strings(valid)
+	return valid, nil
+}
+
+// normalizeNetworks accepts CIDRs and single addresses
+func normalizeNetworks(entries []string) (authz.StringList, error) {
+	networks := authz.StringList{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", entry)
+		}
+		networks = append(networks, network.String())
+	}
+	return networks, nil
+}
+
+func ipAllowed(networks []string, address string) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range networks {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAPIKey returns a key of the form hv_<prefix>_<secret> and its
+// prefix. The prefix is looked up; the whole key is hashed.
+func generateAPIKey() (string, string, error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix := APIKeyPrefix + hex.EncodeToString(id)
+	return prefix, prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func apiKeyPrefix(plaintext string) (string, bool) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return "", false
+	}
+	prefix, _, ok := strings.Cut(plaintext[len(APIKeyPrefix):], "_")
+	if !ok || len(prefix) != 12 {
+		return "", false
+	}
+	return APIKeyPrefix + prefix, true
+}
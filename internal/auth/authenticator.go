@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/khawsic/health/internal/crypto"
+	"gorm.io/gorm"
+)
+
+// PasswordAuthenticatorName identifies the built-in password store
+const PasswordAuthenticatorName = "local"
+
+var (
+	// ErrUnknownUser means the store has no account for the username, so
+	// the next authenticator is tried
+	ErrUnknownUser = errors.New("unknown user")
+
+	// ErrInvalidCredentials means the account exists but the password is
+	// wrong. The Identity returned with it identifies the account so the
+	// failure counts towards lockout.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrNoRole means the password was right but none of the user's groups
+	// map to a role here
+	ErrNoRole = errors.New("your account is not authorized for this application")
+
+	errInvalidLogin     = errors.New("invalid email or password")
+	errLoginUnavailable = errors.New("sign-in is temporarily unavailable — try again later")
+)
+
+// Identity is what an Authenticator vouches for after checking a password
+type Identity struct {
+	UserID       uint // set only by the local password store, which owns the account
+	Subject      string
+	Email        string
+	Name         string
+	Role         string
+	TrustedEmail bool // the email may link to an existing account
+}
+
+// Authenticator verifies a username and password against one credential
+// store. Stores other than the local one are linked to users through
+// user_identities, keyed by Name and Identity.Subject.
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+type passwordAuthenticator struct {
+	db        *gorm.DB
+	passwords *crypto.Passwords
+}
+
+// NewPasswordAuthenticator checks passwords against the hashes in the users
+// table. A hash in an older format or with outdated parameters is replaced
+// once the password has been verified.
+func NewPasswordAuthenticator(db *gorm.DB, passwords *crypto.Passwords) Authenticator {
+	return &passwordAuthenticator{db: db, passwords: passwords}
+}
+
+func (a *passwordAuthenticator) Name() string {
+	return PasswordAuthenticatorName
+}
+
+func (a *passwordAuthenticator) Authenticate(ctx context.Context, email, password string) (*Identity, error) {
+	var user User
+	if err := a.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, ErrUnknownUser
+	}
+
+	identity := &Identity{UserID: user.ID}
+	ok, rehash, err := a.passwords.Verify(user.Password, password)
+	if err != nil && !errors.Is(err, crypto.ErrUnsupportedHash) {
+		log.Printf("⚠️  Unreadable password hash for user %d: %v", user.ID, err)
+	}
+	if !ok {
+		return identity, ErrInvalidCredentials
+	}
+
+	if rehash {
+		a.rehash(&user, password)
+	}
+	return identity, nil
+}
+
+// rehash upgrades the stored hash. The update only applies if the hash is
+// unchanged, so a concurrent password reset is never overwritten. Failure
+// does not affect the login; the upgrade is retried next time.
+func (a *passwordAuthenticator) rehash(user *User, password string) {
+	hash, err := a.passwords.Hash(password)
+	if err != nil {
+		log.Printf("⚠️  Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+
+	err = a.db.Model(&User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hash).Error
+	if err != nil {
+		log.Printf("⚠️  Failed to store upgraded password hash for user %d: %v", user.ID, err)
+		return
+	}
+	log.Printf("🔁 Upgraded password hash for user %d", user.ID)
+}
+
+// UsePasswordHasher sets how passwords are hashed and which older hash
+// formats are still accepted
+func (s *Service) UsePasswordHasher(passwords *crypto.Passwords) {
+	s.passwords = passwords
+	s.authenticators[0] = NewPasswordAuthenticator(s.DB, passwords)
+}
+
+// AddAuthenticator appends a credential store. Stores are tried in the
+// order they were added, after the local password store.
+func (s *Service) AddAuthenticator(a Authenticator) {
+	s.authenticators = append(s.authenticators, a)
+}
+
+// authenticate checks a password against the stores that may own the
+// account and returns the local user. The first store that knows the
+// account decides: a wrong password there fails the login rather than
+// trying the next store, which could otherwise link the account to another
+// identity by email. Lockout applies whichever store owns the account.
+func (s *Service) authenticate(ctx context.Context, username, password string) (*User, error) {
+	var known *User
+	var user User
+	if err := s.DB.Where("email = ?", username).First(&user).Error; err == nil {
+		known = &user
+
+		// Service accounts authenticate only with API keys
+		if known.Kind == KindService {
+			return nil, errInvalidLogin
+		}
+		if err := checkLocked(known); err != nil {
+			return nil, err
+		}
+	}
+
+	unavailable := false
+	for _, a := range s.authenticatorsFor(known) {
+		identity, err := a.Authenticate(ctx, username, password)
+		switch {
+		case err == nil:
+			verified, err := s.userForIdentity(ctx, a.Name(), identity)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkLocked(verified); err != nil {
+				return nil, err
+			}
+			return verified, nil
+
+		case errors.Is(err, ErrUnknownUser):
+			continue
+
+		case errors.Is(err, ErrInvalidCredentials):
+			if failed := s.linkedUser(a.Name(), identity); failed != nil {
+				s.recordFailedAttempt(failed)
+			}
+			return nil, errInvalidLogin
+
+		case errors.Is(err, ErrNoRole):
+			return nil, err
+
+		default:
+			log.Printf("⚠️  %s authenticator failed: %v", a.Name(), err)
+			unavailable = true
+		}
+	}
+
+	if unavailable {
+		return nil, errLoginUnavailable
+	}
+	return nil, errInvalidLogin
+}
+
+// authenticatorsFor returns the stores to try. A user linked to a directory
+// must sign in there; everyone else tries each store in order.
+func (s *Service) authenticatorsFor(user *User) []Authenticator {
+	if user == nil || len(s.authenticators) < 2 {
+		return s.authenticators
+	}
+
+	var providers []string
+	s.DB.Model(&UserIdentity{}).Where("user_id = ?", user.ID).Pluck("provider", &providers)
+	for _, a := range s.authenticators {
+		for _, provider := range providers {
+			if a.Name() == provider {
+				return []Authenticator{a}
+			}
+		}
+	}
+	return s.authenticators
+}
+
+// userForIdentity resolves a verified identity to a local user,
+// provisioning one for directory accounts signing in for the first time
+func (s *Service) userForIdentity(ctx context.Context, provider string, identity *Identity) (*User, error) {
+	if identity.UserID != 0 {
+		var user User
+		if err := s.DB.First(&user, identity.UserID).Error; err != nil {
+			return nil, errInvalidLogin
+		}
+		return &user, nil
+	}
+	return s.provisionIdentity(ctx, provider, identity)
+}
+
+// linkedUser finds the local user behind an identity, if there is one
+func (s *Service) linkedUser(provider string, identity *Identity) *User {
+	if identity == nil {
+		return nil
+	}
+
+	userID := identity.UserID
+	if userID == 0 {
+		var link UserIdentity
+		if err := s.DB.Where("provider = ? AND subject = ?", provider, identity.Subject).
+			First(&link).Error; err != nil {
+			return nil
+		}
+		userID = link.UserID
+	}
+
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil
+	}
+	return &user
+}
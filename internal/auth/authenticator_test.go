@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// directoryStub accepts any password for its one account
+type directoryStub struct{ email string }
+
+func (d directoryStub) Name() string { return "stub" }
+
+func (d directoryStub) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	if username != d.email {
+		return nil, ErrUnknownUser
+	}
+	return &Identity{Subject: "stub-1", Email: d.email, Role: "admin", TrustedEmail: true}, nil
+}
+
+func TestWrongLocalPasswordDoesNotTryOtherStores(t *testing.T) {
+	s := newTestService(t)
+	user := createUser(t, s, "nurse@example.com", "nurse")
+	hash, err := s.passwords.Hash("Nurse@12345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.DB.Model(user).Update("password", hash)
+	s.AddAuthenticator(directoryStub{email: user.Email})
+
+	if _, err := s.authenticate(context.Background(), user.Email, "anything"); !errors.Is(err, errInvalidLogin) {
+		t.Fatalf("wrong local password: err = %v, want %v", err, errInvalidLogin)
+	}
+
+	var links int64
+	s.DB.Model(&UserIdentity{}).Where("user_id = ?", user.ID).Count(&links)
+	if links != 0 {
+		t.Fatal("a wrong local password linked the account to another store")
+	}
+	s.DB.First(user, user.ID)
+	if user.Role != "nurse" || user.FailedAttempts != 1 {
+		t.Fatalf("role %q with %d failed attempts, want nurse with 1", user.Role, user.FailedAttempts)
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginBackoff spaces out credential guesses against one account. The
+// first FreeAttempts consecutive failures cost nothing; every failure after
+// that locks the account for Base, doubling each time up to Max. Failures
+// are forgotten once none has happened for ResetAfter.
+type LoginBackoff struct {
+	FreeAttempts int
+	Base         time.Duration
+	Max          time.Duration
+	ResetAfter   time.Duration
+}
+
+// DefaultLoginBackoff is used until UseLoginBackoff is called
+func DefaultLoginBackoff() LoginBackoff {
+	return LoginBackoff{
+		FreeAttempts: 4,
+		Base:         time.Minute,
+		Max:          time.Hour,
+		ResetAfter:   24 * time.Hour,
+	}
+}
+
+// Validate rejects settings that would never lock or never unlock
+func (b LoginBackoff) Validate() error {
+	if b.FreeAttempts < 1 {
+		return errors.New("free attempts must be at least 1")
+	}
+	if b.Base <= 0 || b.Max < b.Base {
+		return errors.New("base delay must be positive and no longer than the maximum")
+	}
+	if b.ResetAfter < b.Max {
+		return errors.New("failures must not be forgotten before the longest lockout ends")
+	}
+	return nil
+}
+
+// delay is the lockout after the given number of consecutive failures
+func (b LoginBackoff) delay(failures int) time.Duration {
+	if failures <= b.FreeAttempts {
+		return 0
+	}
+
+	delay := b.Base
+	for i := b.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= b.Max {
+			return b.Max
+		}
+	}
+	return delay
+}
+
+// UseLoginBackoff replaces the default failed login backoff
+func (s *Service) UseLoginBackoff(backoff LoginBackoff) error {
+	if err := backoff.Validate(); err != nil {
+		return err
+	}
+	s.loginBackoff = backoff
+	return nil
+}
+
+// LockedError is returned while an account is in lockout. Until tells
+// clients when to retry.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	remaining := time.Until(e.Until).Round(time.Second)
+	return "account locked — try again in " + remaining.String()
+}
+
+// checkLocked returns a *LockedError while the account is in lockout. Only
+// paths that check guessable secrets — passwords and one-time codes — call
+// it; sessions, passkeys and SSO logins are unaffected.
+func checkLocked(user *User) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return &LockedError{Until: *user.LockedUntil}
+	}
+	return nil
+}
+
+// recordFailedAttempt counts a failed credential and, past the free
+// attempts, locks the account for an exponentially growing delay. The
+// count only resets on a successful login, an unlock, or after ResetAfter.
+// The row is locked while it is counted, so concurrent failures each add
+// one and the delay follows the stored count, not the caller's copy.
+func (s *Service) recordFailedAttempt(user *User) {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var stored User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "failed_attempts", "last_failed_at", "locked_until").
+			First(&stored, user.ID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if stored.LastFailedAt != nil && now.Sub(*stored.LastFailedAt) > s.loginBackoff.ResetAfter {
+			stored.FailedAttempts = 0
+		}
+
+		stored.FailedAttempts++
+		stored.LastFailedAt = &now
+		if delay := s.loginBackoff.delay(stored.FailedAttempts); delay > 0 {
+			lockUntil := now.Add(delay)
+			stored.LockedUntil = &lockUntil
+		}
+
+		if err := tx.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"failed_attempts": stored.FailedAttempts,
+			"locked_until":    stored.LockedUntil,
+			"last_failed_at":  stored.LastFailedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		user.FailedAttempts = stored.FailedAttempts
+		user.LastFailedAt = stored.LastFailedAt
+		user.LockedUntil = stored.LockedUntil
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to record failed login for user %d: %v", user.ID, err)
+	}
+}
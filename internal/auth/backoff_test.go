@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoffDelay(t *testing.T) {
+	backoff := LoginBackoff{FreeAttempts: 3, Base: time.Minute, Max: 5 * time.Minute, ResetAfter: time.Hour}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{3, 0},
+		{4, time.Minute},
+		{5, 2 * time.Minute},
+		{6, 4 * time.Minute},
+		{7, 5 * time.Minute},
+		{20, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFailedAttemptUsesStoredCount(t *testing.T) {
+	s := newTestService(t)
+	user := createUser(t, s, "patient@example.com", "patient")
+
+	// Another request has already counted failures since user was loaded
+	stale := *user
+	now := time.Now()
+	s.DB.Model(user).Updates(map[string]interface{}{"failed_attempts": 4, "last_failed_at": now})
+
+	s.recordFailedAttempt(&stale)
+
+	var stored User
+	s.DB.First(&stored, user.ID)
+	if stored.FailedAttempts != 5 {
+		t.Fatalf("failed attempts = %d, want 5", stored.FailedAttempts)
+	}
+	if stored.LockedUntil == nil || time.Until(*stored.LockedUntil) < 50*time.Second {
+		t.Fatalf("locked until %v, want about a minute from now", stored.LockedUntil)
+	}
+	if stale.FailedAttempts != 5 || stale.LockedUntil == nil {
+		t.Errorf("caller's copy not refreshed: %d attempts, locked until %v", stale.FailedAttempts, stale.LockedUntil)
+	}
+
+	// Failures older than ResetAfter are forgotten
+	old := now.Add(-s.loginBackoff.ResetAfter - time.Minute)
+	if err := s.DB.Model(user).Updates(map[string]interface{}{"last_failed_at": old, "locked_until": nil}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.recordFailedAttempt(&stale)
+
+	var reset User
+	s.DB.First(&reset, user.ID)
+	if reset.FailedAttempts != 1 || reset.LockedUntil != nil {
+		t.Fatalf("after reset: %d attempts, locked until %v, want 1 and unlocked", reset.FailedAttempts, reset.LockedUntil)
+	}
+}
+
+// Anyone who knows an email address can trigger the backoff, so it must
+// not end the user's existing sessions
+func TestLockoutLeavesSessionsAlone(t *testing.T) {
+	s := newTestService(t)
+	user := createUser(t, s, "patient@example.com", "patient")
+
+	for i := 0; i < s.loginBackoff.FreeAttempts+1; i++ {
+		s.recordFailedAttempt(user)
+	}
+	if user.LockedUntil == nil {
+		t.Fatal("failed attempts did not lock the account")
+	}
+
+	if err := s.ValidateAccessToken(user.ID, "session", "token", user.TokenVersion); err != nil {
+		t.Fatalf("locked account's access token refused: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/khawsic/health/internal/keyring"
+	"github.com/khawsic/health/internal/security"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testEncryptionKey = "0123456789abcdef0123456789abcdef"
+
+// newTestService returns a service over a fresh in-memory database with
+// one active signing key
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		t.Cleanup(func() { sqlDB.Close() })
+	}
+
+	if err := db.AutoMigrate(
+		&User{}, &Session{}, &RefreshToken{}, &DeniedToken{}, &MFAPolicy{},
+		&LoginEvent{}, &UserIdentity{}, &WebAuthnCredential{}, &WebAuthnSession{},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := keyring.New(db, testEncryptionKey, AccessTokenExpiry)
+	if err := keys.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	seed, err := security.Encrypt(testEncryptionKey, hex.EncodeToString(private.Seed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&keyring.SigningKey{
+		ID:          "test",
+		PrivateKey:  seed,
+		PublicKey:   public,
+		ActivatesAt: time.Now().Add(-time.Minute),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewService(db, "secret", testEncryptionKey, keys)
+}
+
+// createUser stores an active account
+func createUser(t *testing.T, s *Service, email, role string) *User {
+	t.Helper()
+
+	user := &User{Name: "Test User", Email: email, Role: role, Status: StatusActive}
+	if err := s.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
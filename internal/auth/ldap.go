@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const defaultLDAPTimeout = 10 * time.Second
+
+// LDAPConfig describes one LDAP or Active Directory server. Directories are
+// loaded from the JSON file named by LDAP_DIRECTORIES_FILE.
+type LDAPConfig struct {
+	Name       string `json:"name"` // identities are stored under "ldap:<name>"
+	URL        string `json:"url"`  // ldaps://dc1.example.org or ldap://...
+	StartTLS   bool   `json:"start_tls"`
+	CACertFile string `json:"ca_cert_file"` // PEM roots for ldaps/StartTLS; system roots if empty
+
+	// Service account used to find the user's entry before binding as them.
+	// Leave BindDN empty for directories that allow anonymous search.
+	BindDN          string `json:"bind_dn"`
+	BindPassword    string `json:"bind_password"`
+	BindPasswordEnv string `json:"bind_password_env"` // read the password from this variable instead
+
+	BaseDN string `json:"base_dn"`
+
+	// UserFilter finds the entry; {username} is replaced with the escaped
+	// login name. Defaults to matching sAMAccountName, userPrincipalName or mail.
+	UserFilter string `json:"user_filter"`
+
+	SubjectAttribute string `json:"subject_attribute"` // stable id; defaults to objectGUID, "dn" uses the entry DN
+	EmailAttribute   string `json:"email_attribute"`   // defaults to mail
+	NameAttribute    string `json:"name_attribute"`    // defaults to displayName
+	GroupAttribute   string `json:"group_attribute"`   // defaults to memberOf
+
+	// RoleMapping maps group DNs or common names to local roles. Users in no
+	// mapped group are refused.
+	RoleMapping map[string]string `json:"role_mapping"`
+
+	// LinkByEmail lets a first directory login attach to an existing local
+	// account with the same email address
+	LinkByEmail bool `json:"link_by_email"`
+
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// LDAPAuthenticator checks passwords with a search-then-bind against an
+// LDAP directory and maps group membership to a role
+type LDAPAuthenticator struct {
+	config      LDAPConfig
+	roleMapping map[string]string
+	tls         *tls.Config
+	timeout     time.Duration
+}
+
+// LoadLDAPDirectories reads directory configuration from a JSON array file
+func LoadLDAPDirectories(path string) ([]LDAPConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []LDAPConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid directory file: %w", err)
+	}
+
+	for i := range configs {
+		if configs[i].BindPasswordEnv != "" {
+			configs[i].BindPassword = os.Getenv(configs[i].BindPasswordEnv)
+		}
+	}
+	return configs, nil
+}
+
+// NewLDAPAuthenticator validates the configuration and fills in defaults.
+// It does not connect; an unreachable directory only fails its logins.
+// Mapped roles are checked against the defined roles by
+// Service.ValidateRoleMapping.
+func NewLDAPAuthenticator(cfg LDAPConfig) (*LDAPAuthenticator, error) {
+	if !providerNamePattern.MatchString(cfg.Name) {
+		return nil, fmt.Errorf("directory name %q must be lowercase letters, digits and dashes", cfg.Name)
+	}
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, fmt.Errorf("directory %q needs url and base_dn", cfg.Name)
+	}
+	if len(cfg.RoleMapping) == 0 {
+		return nil, fmt.Errorf("directory %q has no role_mapping", cfg.Name)
+	}
+
+	roleMapping := make(map[string]string, len(cfg.RoleMapping))
+	for group, role := range cfg.RoleMapping {
+		roleMapping[strings.ToLower(group)] = role
+	}
+
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=user)(|(sAMAccountName={username})(userPrincipalName={username})(mail={username})))"
+	}
+	if cfg.SubjectAttribute == "" {
+		cfg.SubjectAttribute = "objectGUID"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "displayName"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+
+	timeout := defaultLDAPTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CACertFile != "" {
+		pemData, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("directory %q: %w", cfg.Name, err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("directory %q: no certificates in %s", cfg.Name, cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if strings.HasPrefix(strings.ToLower(cfg.URL), "ldap://") && !cfg.StartTLS {
+		log.Printf("⚠️  Directory %s uses plain LDAP without StartTLS — passwords cross the network unencrypted", cfg.Name)
+	}
+
+	return &LDAPAuthenticator{
+		config:      cfg,
+		roleMapping: roleMapping,
+		tls:         tlsConfig,
+		timeout:     timeout,
+	}, nil
+}
+
+func (a *LDAPAuthenticator) Name() string {
+	return "ldap:" + a.config.Name
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	// An empty password would be an unauthenticated bind, which succeeds
+	if username == "" || password == "" {
+		return nil, ErrUnknownUser
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.config.BindDN != "" {
+		if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("service account bind: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(a.config.UserFilter, "{username}", ldap.EscapeFilter(username))
+	attributes := []string{a.config.EmailAttribute, a.config.NameAttribute, a.config.GroupAttribute}
+	if a.config.SubjectAttribute != "dn" {
+		attributes = append(attributes, a.config.SubjectAttribute)
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(a.timeout.Seconds()), false, filter, attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrUnknownUser
+		}
+		return nil, fmt.Errorf("user search: %w", err)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrUnknownUser
+	}
+	if len(result.Entries) > 1 {
+		log.Printf("⚠️  Directory %s: %q matches several entries — refusing login", a.config.Name, username)
+		return nil, ErrUnknownUser
+	}
+	entry := result.Entries[0]
+
+	identity := &Identity{
+		Subject:      a.subject(entry),
+		Email:        entry.GetAttributeValue(a.config.EmailAttribute),
+		Name:         entry.GetAttributeValue(a.config.NameAttribute),
+		TrustedEmail: a.config.LinkByEmail,
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("entry %s has no %s", entry.DN, a.config.SubjectAttribute)
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return identity, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind: %w", err)
+	}
+
+	identity.Role = a.mapGroups(entry.GetAttributeValues(a.config.GroupAttribute))
+	if identity.Role == "" {
+		log.Printf("⚠️  Directory login by %s denied — no mapped group", entry.DN)
+		return nil, ErrNoRole
+	}
+
+	return identity, nil
+}
+
+func (a *LDAPAuthenticator) connect(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: a.timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+
+	conn, err := ldap.DialURL(a.config.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(a.tls))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(a.timeout)
+
+	if a.config.StartTLS {
+		if err := conn.StartTLS(a.tls); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// subject returns the stable account id. Binary attributes such as
+// objectGUID are hex encoded.
+func (a *LDAPAuthenticator) subject(entry *ldap.Entry) string {
+	if a.config.SubjectAttribute == "dn" {
+		return strings.ToLower(entry.DN)
+	}
+
+	raw := entry.GetRawAttributeValue(a.config.SubjectAttribute)
+	if len(raw) == 0 {
+		return ""
+	}
+	if strings.EqualFold(a.config.SubjectAttribute, "objectGUID") {
+		return hex.EncodeToString(raw)
+	}
+	return string(raw)
+}
+
+// mapGroups matches each group by full DN or by its leading RDN value (CN)
+func (a *LDAPAuthenticator) mapGroups(groups []string) string {
+	var keys []string
+	for _, group := range groups {
+		keys = append(keys, strings.ToLower(group))
+		if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			keys = append(keys, strings.ToLower(dn.RDNs[0].Attributes[0].Value))
+		}
+	}
+	return mapGroupsToRole(a.roleMapping, keys)
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/khawsic/health/internal/mockldap"
+)
+
+const (
+	testBaseDN       = "DC=healthvault,DC=test"
+	testBindDN       = "CN=svc,OU=Service,DC=healthvault,DC=test"
+	testBindPassword = "bind-secret"
+)
+
+// startDirectory serves a mock directory on a free local port and returns
+// an authenticator pointed at it
+func startDirectory(t *testing.T) *LDAPAuthenticator {
+	t.Helper()
+
+	directory, err := mockldap.New(mockldap.Config{
+		BaseDN:       testBaseDN,
+		BindDN:       testBindDN,
+		BindPassword: testBindPassword,
+		Users: []mockldap.User{
+			{Username: "clinician", Password: "Clinician@123", Email: "clinician@example.com", Name: "Jo Clinician", Groups: []string{"Clinicians"}},
+			{Username: "admin", Password: "Admin@123", Email: "admin@example.com", Name: "Ada Admin", Groups: []string{"Staff", "IT Admins"}},
+			{Username: "reception", Password: "Reception@123", Email: "reception@example.com", Name: "Front Desk", Groups: []string{"Staff"}},
+			{Username: "leaver", Password: "Leaver@123", Email: "leaver@example.com", Name: "Former Clinician", Groups: []string{"Clinicians"}, Disabled: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	go directory.Run(addr)
+	t.Cleanup(func() { directory.Stop() })
+	for deadline := time.Now().Add(5 * time.Second); !directory.Ready(); {
+		if time.Now().After(deadline) {
+			t.Fatal("mock directory did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	authenticator, err := NewLDAPAuthenticator(LDAPConfig{
+		Name:         "corp",
+		URL:          "ldap://" + addr,
+		BindDN:       testBindDN,
+		BindPassword: testBindPassword,
+		BaseDN:       testBaseDN,
+		RoleMapping: map[string]string{
+			"Clinicians": "doctor",
+			"CN=IT Admins,OU=Groups," + testBaseDN: "admin",
+		},
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	authenticator := startDirectory(t)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantRole string
+		wantErr  error
+	}{
+		{name: "bind succeeds", username: "clinician", password: "Clinician@123", wantRole: "doctor"},
+		{name: "login by email", username: "clinician@example.com", password: "Clinician@123", wantRole: "doctor"},
+		{name: "group mapped by full DN", username: "admin", password: "Admin@123", wantRole: "admin"},
+		{name: "wrong password", username: "clinician", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "disabled account", username: "leaver", password: "Leaver@123", wantErr: ErrInvalidCredentials},
+		{name: "no mapped group", username: "reception", password: "Reception@123", wantErr: ErrNoRole},
+		{name: "unknown user", username: "nobody", password: "Nobody@123", wantErr: ErrUnknownUser},
+		{name: "empty password", username: "clinician", password: "", wantErr: ErrUnknownUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := authenticator.Authenticate(context.Background(), tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if identity.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", identity.Role, tt.wantRole)
+			}
+			if identity.Subject == "" || identity.Email == "" {
+				t.Errorf("identity missing subject or email: %+v", identity)
+			}
+		})
+	}
+}
+
+func TestLDAPLoginProvisionsAndHonoursLocalStatus(t *testing.T) {
+	s := newTestService(t)
+	s.AddAuthenticator(startDirectory(t))
+	ctx := context.Background()
+
+	result, err := s.Login(ctx, "clinician", "Clinician@123")
+	if err != nil {
+		t.Fatalf("first directory login failed: %v", err)
+	}
+	if result.AccessToken == "" {
+		t.Fatal("directory login issued no tokens")
+	}
+
+	var user User
+	if err := s.DB.Where("email = ?", "clinician@example.com").First(&user).Error; err != nil {
+		t.Fatalf("directory login provisioned no user: %v", err)
+	}
+	if user.Role != "doctor" {
+		t.Errorf("provisioned role = %q, want doctor", user.Role)
+	}
+
+	if _, err := s.Login(ctx, "clinician", "wrong"); !errors.Is(err, errInvalidLogin) {
+		t.Fatalf("wrong password: err = %v, want %v", err, errInvalidLogin)
+	}
+	s.DB.First(&user, user.ID)
+	if user.FailedAttempts != 1 {
+		t.Errorf("failed attempts = %d, want 1", user.FailedAttempts)
+	}
+
+	// A correct directory password does not revive a locally disabled account
+	s.DB.Model(&user).Update("status", StatusDisabled)
+	if _, err := s.Login(ctx, "clinician", "Clinician@123"); err == nil {
+		t.Fatal("disabled account signed in")
+	}
+}
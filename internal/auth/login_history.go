@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/khawsic/health/internal/geoip"
+	"github.com/khawsic/health/internal/mail"
+	"github.com/khawsic/health/internal/requestctx"
+)
+
+// LoginEvent records one sign-in attempt on a known account, successful or
+// not. Attempts on addresses with no account are not recorded.
+type LoginEvent struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"-"`
+	Success   bool   `gorm:"not null" json:"success"`
+	Reason    string `json:"reason,omitempty"` // why a failed attempt was refused
+	ACR       string `gorm:"column:acr" json:"acr,omitempty"`
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	Device    string `json:"device"`
+	Country   string `json:"country,omitempty"`
+	Region    string `json:"region,omitempty"`
+	City      string `json:"city,omitempty"`
+
+	// NewDevice and NewLocation mark successful logins from a device or
+	// place the account had not signed in from before
+	NewDevice   bool      `gorm:"not null;default:false" json:"new_device"`
+	NewLocation bool      `gorm:"not null;default:false" json:"new_location"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
+
+// UseGeoIP sets the database used to place login addresses
+func (s *Service) UseGeoIP(reader *geoip.Reader) {
+	s.geoip = reader
+}
+
+// 🕓 LoginHistory — returns one page of the user's sign-in attempts,
+// newest first
+func (s *Service) LoginHistory(userID uint, page, pageSize int) ([]LoginEvent, int64, error) {
+	query := s.DB.Model(&LoginEvent{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	events := []LoginEvent{}
+	err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&events).Error
+	return events, total, err
+}
+
+// recordLogin stores a successful login and, when it comes from a device
+// or location the account has not used before, emails the user. The first
+// login ever is not announced.
+func (s *Service) recordLogin(ctx context.Context, user *User, acr string) {
+	event := s.newLoginEvent(ctx, user)
+	event.Success = true
+	event.ACR = acr
+
+	var previous int64
+	s.DB.Model(&LoginEvent{}).Where("user_id = ? AND success = true", user.ID).Count(&previous)
+	if previous > 0 {
+		var seen int64
+		s.DB.Model(&LoginEvent{}).
+			Where("user_id = ? AND success = true AND device = ?", user.ID, event.Device).
+			Count(&seen)
+		event.NewDevice = seen == 0
+
+		if event.Country != "" {
+			s.DB.Model(&LoginEvent{}).
+				Where("user_id = ? AND success = true AND country = ? AND city = ?", user.ID, event.Country, event.City).
+				Count(&seen)
+			event.NewLocation = seen == 0
+		}
+	}
+
+	if err := s.DB.Create(event).Error; err != nil {
+		log.Printf("⚠️  Failed to record login for user %d: %v", user.ID, err)
+	}
+
+	if event.NewDevice || event.NewLocation {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+			defer cancel()
+
+			if err := s.sendNewSignIn(ctx, user, event); err != nil {
+				log.Printf("⚠️  New sign-in notice for user %d failed: %v", user.ID, err)
+			}
+		}()
+	}
+}
+
+// recordLoginFailure stores a refused attempt on a known account and
+// returns err unchanged, so callers can wrap their error returns
+func (s *Service) recordLoginFailure(ctx context.Context, user *User, err error) error {
+	if user == nil || user.Kind == KindService {
+		return err
+	}
+
+	event := s.newLoginEvent(ctx, user)
+	event.Reason = err.Error()
+	if createErr := s.DB.Create(event).Error; createErr != nil {
+		log.Printf("⚠️  Failed to record login failure for user %d: %v", user.ID, createErr)
+	}
+	return err
+}
+
+// userByEmail finds the account a password login named, if there is one
+func (s *Service) userByEmail(email string) *User {
+	var user User
+	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil
+	}
+	return &user
+}
+
+func (s *Service) newLoginEvent(ctx context.Context, user *User) *LoginEvent {
+	info := requestctx.From(ctx)
+	event := &LoginEvent{
+		UserID:    user.ID,
+		IPAddress: info.IPAddress,
+		UserAgent: info.UserAgent,
+		Device:    info.Device,
+		CreatedAt: time.Now(),
+	}
+	if location, ok := s.geoip.Lookup(info.IPAddress); ok {
+		event.Country = location.Country
+		event.Region = location.Region
+		event.City = location.City
+	}
+	return event
+}
+
+func (s *Service) sendNewSignIn(ctx context.Context, user *User, event *LoginEvent) error {
+	if s.mailer == nil {
+		return errors.New("no mailer configured")
+	}
+
+	reason := "a new device"
+	switch {
+	case event.NewDevice && event.NewLocation:
+		reason = "a new device and location"
+	case event.NewLocation:
+		reason = "a new location"
+	}
+
+	device := event.UserAgent
+	if device == "" {
+		device = "Unknown device"
+	}
+	location := geoip.Location{Country: event.Country, Region: event.Region, City: event.City}.String()
+	if location == "" {
+		location = "Unknown"
+	}
+
+	msg, err := mail.Render(mail.TemplateNewSignIn, user.Email, map[string]string{
+		"Name":      user.Name,
+		"Reason":    reason,
+		"Time":      event.CreatedAt.UTC().Format("2 January 2006, 15:04 MST"),
+		"Device":    device,
+		"IPAddress": event.IPAddress,
+		"Location":  location,
+		"ResetURL":  s.appURL + "/forgot-password",
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/khawsic/health/internal/crypto"
+	"github.com/khawsic/health/internal/security"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaTokenExpiry    = 5 * time.Minute
+	recoveryCodeCount = 10
+
+	mfaPurposeVerify = "mfa_verify"
+	mfaPurposeEnroll = "mfa_enroll"
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// 🔐 BeginTOTPEnrollment — creates a new secret for the user to scan.
+// MFA stays off until ConfirmTOTPEnrollment proves the app is set up.
+func (s *Service) BeginTOTPEnrollment(userID uint) (string, string, error) {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return "", "", errors.New("user not found")
+	}
+
+	if user.MFAEnabled {
+		return "", "", errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err := security.Encrypt(s.EncryptionKey, secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    encrypted,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	return secret, totpURI(user.Email, secret), nil
+}
+
+// ✅ ConfirmTOTPEnrollment — verifies the first code, enables MFA and
+// returns fresh recovery codes (shown to the user exactly once)
+func (s *Service) ConfirmTOTPEnrollment(userID uint, code string) ([]string, error) {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.MFAEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("no enrollment in progress")
+	}
+
+	if err := s.checkTOTP(&user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFAEnabled = true
+	return codes, nil
+}
+
+// 🔁 RegenerateRecoveryCodes — invalidates old codes after a fresh TOTP check
+func (s *Service) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !user.MFAEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.checkTOTP(&user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// 🚫 DisableTOTP — turns MFA off unless the user's role requires it
+func (s *Service) DisableTOTP(userID uint, code string) error {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	if !user.MFAEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	if s.mfaRequiredForRole(user.Role) {
+		return fmt.Errorf("two-factor authentication is required for the %s role", user.Role)
+	}
+
+	if err := s.checkTOTP(&user, code); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"mfa_enabled":    false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// 🔑 VerifyMFA — second login step. Accepts a TOTP code or a recovery code.
+func (s *Service) VerifyMFA(ctx context.Context, mfaToken, code, recoveryCode string) (*LoginResult, error) {
+	user, err := s.userFromMFAToken(mfaToken, mfaPurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkLocked(user); err != nil {
+		return nil, s.recordLoginFailure(ctx, user, err)
+	}
+
+	switch {
+	case recoveryCode != "":
+		if err := s.useRecoveryCode(user, recoveryCode); err != nil {
+			return nil, s.recordLoginFailure(ctx, user, err)
+		}
+	case code != "":
+		if err := s.checkTOTP(user, code); err != nil {
+			return nil, s.recordLoginFailure(ctx, user, err)
+		}
+	default:
+		return nil, errors.New("a verification code or recovery code is required")
+	}
+
+	return s.completeLogin(ctx, user, ACRMultiFactor)
+}
+
+// BeginChallengeEnrollment starts TOTP enrollment for a user whose role
+// requires MFA but who has not enrolled yet, using their login challenge
+func (s *Service) BeginChallengeEnrollment(mfaToken string) (string, string, error) {
+	user, err := s.userFromMFAToken(mfaToken, mfaPurposeEnroll)
+	if err != nil {
+		return "", "", err
+	}
+	return s.BeginTOTPEnrollment(user.ID)
+}
+
+// ConfirmChallengeEnrollment finishes enrollment during login and issues
+// the session tokens together with the recovery codes
+func (s *Service) ConfirmChallengeEnrollment(ctx context.Context, mfaToken, code string) (*LoginResult, []string, error) {
+	user, err := s.userFromMFAToken(mfaToken, mfaPurposeEnroll)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkLocked(user); err != nil {
+		return nil, nil, s.recordLoginFailure(ctx, user, err)
+	}
+
+	codes, err := s.ConfirmTOTPEnrollment(user.ID, code)
+	if err != nil {
+		return nil, nil, s.recordLoginFailure(ctx, user, err)
+	}
+
+	result, err := s.completeLogin(ctx, user, ACRMultiFactor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, codes, nil
+}
+
+// 🛡️ SetMFAPolicy — makes MFA mandatory (or optional) for a role
+func (s *Service) SetMFAPolicy(role string, required bool, adminID uint) error {
+	if !s.roleExists(role) {
+		return errors.New("unknown role")
+	}
+
+	policy := MFAPolicy{
+		Role:      role,
+		Required:  required,
+		UpdatedBy: adminID,
+		UpdatedAt: time.Now(),
+	}
+	return s.DB.Save(&policy).Error
+}
+
+// MFAPolicies returns the configured per-role MFA requirements
+func (s *Service) MFAPolicies() ([]MFAPolicy, error) {
+	var policies []MFAPolicy
+	if err := s.DB.Order("role ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (s *Service) mfaRequiredForRole(role string) bool {
+	var policy MFAPolicy
+	if err := s.DB.Where("role = ?", role).Limit(1).Find(&policy).Error; err != nil {
+		// Fail closed — a policy lookup error must not skip the second factor
+		return true
+	}
+	return policy.Required
+}
+
+// checkTOTP validates a code against the user's secret, records the step to
+// block replay, and counts failures towards lockout
+func (s *Service) checkTOTP(user *User, code string) error {
+	secret, err := security.Decrypt(s.EncryptionKey, user.TOTPSecret)
+	if err != nil {
+		return errors.New("failed to read two-factor secret")
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		s.recordFailedAttempt(user)
+		return errors.New("invalid verification code")
+	}
+
+	// Only one request can move past the last step, so two racing with the
+	// same code cannot both succeed
+	result := s.DB.Model(&User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.recordFailedAttempt(user)
+		return errors.New("invalid verification code")
+	}
+
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (s *Service) useRecoveryCode(user *User, code string) error {
+	hash := crypto.HashString(normalizeRecoveryCode(code))
+
+	result := s.DB.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		s.recordFailedAttempt(user)
+		return errors.New("invalid recovery code")
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes deletes existing codes and stores hashes of new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, 7)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(bytes))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+
+		record := RecoveryCode{
+			UserID:   userID,
+			CodeHash: crypto.HashString(normalizeRecoveryCode(codes[i])),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// issueMFAToken signs a short-lived challenge. It uses a key derived from
+// the JWT secret so a challenge can never be accepted as an access token.
+func (s *Service) issueMFAToken(userID uint, purpose string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"purpose": purpose,
+		"exp":     time.Now().Add(mfaTokenExpiry).Unix(),
+	})
+	return token.SignedString(s.mfaSigningKey())
+}
+
+func (s *Service) userFromMFAToken(tokenString, purpose string) (*User, error) {
+	invalid := errors.New("invalid or expired MFA challenge — log in again")
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.mfaSigningKey(), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, invalid
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, invalid
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, invalid
+	}
+
+	var user User
+	if err := s.DB.First(&user, uint(userID)).Error; err != nil {
+		return nil, invalid
+	}
+
+	return &user, nil
+}
+
+func (s *Service) mfaSigningKey() []byte {
+	return []byte(crypto.HashString("mfa-challenge|" + s.JWTSecret))
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/khawsic/health/internal/security"
+)
+
+func TestTOTPCodeAcceptedOnce(t *testing.T) {
+	s := newTestService(t)
+	user := createUser(t, s, "doctor@example.com", "doctor")
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := security.Encrypt(testEncryptionKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.DB.Model(user).Update("totp_secret", encrypted)
+	user.TOTPSecret = encrypted
+
+	key, _ := totpEncoding.DecodeString(secret)
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+
+	// Two requests loaded the user before either recorded the step
+	racing := *user
+	if err := s.checkTOTP(user, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.checkTOTP(&racing, code); err == nil {
+		t.Fatal("the same code was accepted twice")
+	}
+}
@@ -12,9 +12,11 @@ func Migrate(db *gorm.DB) {
 		CREATE TABLE IF NOT EXISTS refresh_tokens (
 			id SERIAL PRIMARY KEY,
 			user_id INT NOT NULL,
-			token TEXT NOT NULL UNIQUE,
+			token_hash TEXT NOT NULL,
+			family_id TEXT NOT NULL,
 			expires_at TIMESTAMP NOT NULL,
 			revoked BOOLEAN DEFAULT FALSE,
+			rotated_at TIMESTAMP,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
 	`).Error
@@ -26,7 +28,7 @@ func Migrate(db *gorm.DB) {
 		CREATE TABLE IF NOT EXISTS password_reset_tokens (
 			id SERIAL PRIMARY KEY,
 			user_id INT NOT NULL,
-			token TEXT NOT NULL UNIQUE,
+			token_hash TEXT NOT NULL,
 			expires_at TIMESTAMP NOT NULL,
 			used BOOLEAN DEFAULT FALSE,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -36,5 +38,342 @@ func Migrate(db *gorm.DB) {
 		log.Fatal("❌ Failed to migrate password_reset_tokens table:", err)
 	}
 
+	// Older databases stored tokens in plaintext — hash them in place so
+	// existing sessions survive and drop the plaintext column
+	err = db.Exec(`
+		ALTER TABLE refresh_tokens
+			ADD COLUMN IF NOT EXISTS token_hash TEXT,
+			ADD COLUMN IF NOT EXISTS family_id TEXT,
+			ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMP;
+		ALTER TABLE password_reset_tokens
+			ADD COLUMN IF NOT EXISTS token_hash TEXT;
+
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+			           WHERE table_name = 'refresh_tokens' AND column_name = 'token') THEN
+				UPDATE refresh_tokens
+				SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex')
+				WHERE token_hash IS NULL;
+				ALTER TABLE refresh_tokens DROP COLUMN token;
+			END IF;
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+			           WHERE table_name = 'password_reset_tokens' AND column_name = 'token') THEN
+				UPDATE password_reset_tokens
+				SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex')
+				WHERE token_hash IS NULL;
+				ALTER TABLE password_reset_tokens DROP COLUMN token;
+			END IF;
+		END $$;
+
+		UPDATE refresh_tokens SET family_id = 'legacy-' || id WHERE family_id IS NULL;
+
+		ALTER TABLE refresh_tokens
+			ALTER COLUMN token_hash SET NOT NULL,
+			ALTER COLUMN family_id SET NOT NULL;
+		ALTER TABLE password_reset_tokens
+			ALTER COLUMN token_hash SET NOT NULL;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to hash stored auth tokens:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN DEFAULT FALSE,
+			ADD COLUMN IF NOT EXISTS totp_secret TEXT,
+			ADD COLUMN IF NOT EXISTS totp_last_step BIGINT DEFAULT 0
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add MFA columns to users:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL,
+			code_hash TEXT NOT NULL UNIQUE,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate mfa_recovery_codes table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS mfa_policies (
+			role VARCHAR(50) PRIMARY KEY,
+			required BOOLEAN NOT NULL DEFAULT FALSE,
+			updated_by INT,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate mfa_policies table:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS webauthn_handle BYTEA UNIQUE,
+			ADD COLUMN IF NOT EXISTS passkey_only BOOLEAN DEFAULT FALSE
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add passkey columns to users:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webauthn_credentials (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL,
+			name TEXT NOT NULL,
+			credential_id BYTEA NOT NULL UNIQUE,
+			public_key BYTEA NOT NULL,
+			attestation_type TEXT,
+			transports TEXT,
+			aaguid BYTEA,
+			flags SMALLINT NOT NULL DEFAULT 0,
+			sign_count BIGINT NOT NULL DEFAULT 0,
+			clone_warning BOOLEAN NOT NULL DEFAULT FALSE,
+			last_used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate webauthn_credentials table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webauthn_sessions (
+			id SERIAL PRIMARY KEY,
+			token_hash TEXT NOT NULL UNIQUE,
+			user_id INT,
+			ceremony VARCHAR(20) NOT NULL,
+			data TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate webauthn_sessions table:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS token_version INT DEFAULT 0
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add token_version to users:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id INT NOT NULL,
+			user_agent TEXT,
+			ip_address VARCHAR(45),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate sessions table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS token_denylist (
+			id TEXT PRIMARY KEY,
+			kind VARCHAR(10) NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_token_denylist_expires_at ON token_denylist(expires_at);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate token_denylist table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL,
+			provider VARCHAR(100) NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT,
+			last_login_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities(provider, subject);
+		CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate user_identities table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS oidc_login_states (
+			id SERIAL PRIMARY KEY,
+			state_hash TEXT NOT NULL UNIQUE,
+			provider VARCHAR(100) NOT NULL,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate oidc_login_states table:", err)
+	}
+
+	// Accounts that existed before registration review are already active
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS status VARCHAR(30) NOT NULL DEFAULT 'active',
+			ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add registration columns to users:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS email_verification_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate email_verification_tokens table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS doctor_applications (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL UNIQUE,
+			license_number TEXT NOT NULL,
+			notes TEXT,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			reviewed_by INT,
+			review_notes TEXT,
+			reviewed_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate doctor_applications table:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS password_history (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL,
+			password_hash TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate password_history table:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS department VARCHAR(100) NOT NULL DEFAULT ''
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add department to users:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS date_of_birth DATE
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add date_of_birth to users:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS last_failed_at TIMESTAMP
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add last_failed_at to users:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'human'
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add kind to users:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id SERIAL PRIMARY KEY,
+			service_account_id INT NOT NULL,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL UNIQUE,
+			key_hash TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			allowed_ips TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			last_used_at TIMESTAMP,
+			last_used_ip TEXT,
+			created_by INT NOT NULL,
+			revoked_at TIMESTAMP,
+			revoked_by INT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_keys_service_account_id ON api_keys(service_account_id);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate api_keys table:", err)
+	}
+
+	err = db.Exec(`
+		ALTER TABLE sessions
+			ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS acr VARCHAR(20)
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to add auth_time to sessions:", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS login_events (
+			id SERIAL PRIMARY KEY,
+			user_id INT NOT NULL,
+			success BOOLEAN NOT NULL,
+			reason TEXT,
+			acr VARCHAR(20),
+			ip_address TEXT,
+			user_agent TEXT,
+			device TEXT,
+			country TEXT,
+			region TEXT,
+			city TEXT,
+			new_device BOOLEAN NOT NULL DEFAULT FALSE,
+			new_location BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_login_events_user_id ON login_events(user_id, created_at);
+	`).Error
+	if err != nil {
+		log.Fatal("❌ Failed to migrate login_events table:", err)
+	}
+
 	log.Println("✅ Auth tables migrated")
 }
\ No newline at end of file
@@ -7,32 +7,211 @@ import (
 )
 
 type User struct {
-	ID             uint           `gorm:"primaryKey"`
-	Name           string         `gorm:"not null"`
-	Email          string         `gorm:"uniqueIndex;not null"`
-	Password       string         `gorm:"not null"`
-	Role           string         `gorm:"not null"` // doctor, patient
-	FailedAttempts int            `gorm:"default:0"`
-	LockedUntil    *time.Time
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	ID              uint           `gorm:"primaryKey"`
+	Name            string         `gorm:"not null"`
+	Email           string         `gorm:"uniqueIndex;not null"`
+	Password        string         `gorm:"not null"`
+	Role            string         `gorm:"not null"` // defined in the roles table
+	Kind            string         `gorm:"column:kind;not null;default:human"` // human or service
+	Department      string         `gorm:"column:department"` // attribute for access policies
+	DateOfBirth     *time.Time     `gorm:"column:date_of_birth;type:date"` // drives proxy age rules
+	FailedAttempts  int            `gorm:"default:0"`
+	LockedUntil     *time.Time
+	LastFailedAt    *time.Time     `gorm:"column:last_failed_at"` // failures age out of the login backoff
+	MFAEnabled      bool           `gorm:"column:mfa_enabled;default:false"`
+	TOTPSecret      string         `gorm:"column:totp_secret"` // AES-256 encrypted
+	TOTPLastStep    int64          `gorm:"column:totp_last_step;default:0"`
+	WebAuthnHandle  []byte         `gorm:"column:webauthn_handle;uniqueIndex"` // random user handle for passkeys
+	PasskeyOnly     bool           `gorm:"column:passkey_only;default:false"`
+	TokenVersion    int            `gorm:"column:token_version;default:0"` // bumped to invalidate access tokens
+	Status          string         `gorm:"column:status;not null;default:active"` // pending_verification, pending_approval, active, rejected, disabled
+	EmailVerifiedAt *time.Time     `gorm:"column:email_verified_at"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
+// RefreshToken stores only the SHA-256 hash of the token handed to the
+// client. Every login starts a family; each refresh rotates the token
+// within it, and presenting a rotated token revokes the whole family.
 type RefreshToken struct {
 	ID        uint      `gorm:"primaryKey"`
 	UserID    uint      `gorm:"not null"`
-	Token     string    `gorm:"not null;uniqueIndex"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	FamilyID  string    `gorm:"not null;index"`
 	ExpiresAt time.Time `gorm:"not null"`
 	Revoked   bool      `gorm:"default:false"`
+	RotatedAt *time.Time
 	CreatedAt time.Time
 }
 
+// PasswordResetToken stores only the SHA-256 hash of the emailed token
 type PasswordResetToken struct {
 	ID        uint      `gorm:"primaryKey"`
 	UserID    uint      `gorm:"not null"`
-	Token     string    `gorm:"not null;uniqueIndex"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
 	ExpiresAt time.Time `gorm:"not null"`
 	Used      bool      `gorm:"default:false"`
 	CreatedAt time.Time
+}
+
+// RecoveryCode is a single-use MFA fallback; only its SHA-256 hash is stored
+type RecoveryCode struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	CodeHash  string    `gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (RecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+// MFAPolicy makes a second factor mandatory for every user with Role
+type MFAPolicy struct {
+	Role      string    `gorm:"primaryKey"`
+	Required  bool      `gorm:"not null;default:false"`
+	UpdatedBy uint
+	UpdatedAt time.Time
+}
+
+func (MFAPolicy) TableName() string {
+	return "mfa_policies"
+}
+
+// WebAuthnCredential is a registered passkey. A user may hold several.
+type WebAuthnCredential struct {
+	ID              uint       `gorm:"primaryKey"`
+	UserID          uint       `gorm:"not null;index"`
+	Name            string     `gorm:"not null"`
+	CredentialID    []byte     `gorm:"column:credential_id;not null;uniqueIndex"`
+	PublicKey       []byte     `gorm:"not null"`
+	AttestationType string
+	Transports      string     // comma-separated
+	AAGUID          []byte     `gorm:"column:aaguid"`
+	Flags           uint8      `gorm:"not null;default:0"`
+	SignCount       uint32     `gorm:"not null;default:0"`
+	CloneWarning    bool       `gorm:"not null;default:false"`
+	LastUsedAt      *time.Time
+	CreatedAt       time.Time
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// WebAuthnSession holds the challenge of an unfinished ceremony. The client
+// only receives an opaque token; its SHA-256 hash is the lookup key.
+type WebAuthnSession struct {
+	ID        uint      `gorm:"primaryKey"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	UserID    *uint
+	Ceremony  string    `gorm:"not null"` // registration, login
+	Data      string    `gorm:"type:text;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (WebAuthnSession) TableName() string {
+	return "webauthn_sessions"
+}
+
+// Session is one login on one device. It shares its ID with the refresh
+// token family and is carried in access tokens as the sid claim.
+type Session struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+
+	// AuthTime is when the user last proved who they are in this session,
+	// at login or by stepping up, and ACR how strongly
+	AuthTime *time.Time `gorm:"column:auth_time" json:"auth_time"`
+	ACR      string     `gorm:"column:acr" json:"acr"`
+}
+
+// DeniedToken blocks every access token of a session (sid) until
+// ExpiresAt, after which the tokens could not be used anyway
+type DeniedToken struct {
+	ID        string    `gorm:"primaryKey"`
+	Kind      string    `gorm:"not null"` // sid
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+func (DeniedToken) TableName() string {
+	return "token_denylist"
+}
+
+// UserIdentity links a user to an external identity provider account
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;index"`
+	Provider    string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+}
+
+// OIDCLoginState holds the nonce and PKCE verifier of an unfinished
+// authorization-code login. The state parameter is the lookup key and only
+// its SHA-256 hash is stored.
+type OIDCLoginState struct {
+	ID           uint      `gorm:"primaryKey"`
+	StateHash    string    `gorm:"not null;uniqueIndex"`
+	Provider     string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+// EmailVerificationToken proves ownership of a newly registered address.
+// Only the SHA-256 hash of the emailed token is stored.
+type EmailVerificationToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// DoctorApplication is a self-registered doctor waiting in the admin
+// review queue. The account stays inactive until it is approved.
+type DoctorApplication struct {
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   `gorm:"not null;uniqueIndex"`
+	User          User   `gorm:"foreignKey:UserID"`
+	LicenseNumber string `gorm:"not null"`
+	Notes         string // from the applicant, e.g. hospital and department
+	Status        string `gorm:"not null;default:pending"` // pending, approved, rejected
+	ReviewedBy    *uint
+	ReviewNotes   string
+	ReviewedAt    *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// PasswordHistory remembers a user's recent password hashes so the
+// password policy can refuse reuse
+type PasswordHistory struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"not null;index"`
+	PasswordHash string `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_history"
 }
\ No newline at end of file
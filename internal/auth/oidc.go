@@ -0,0 +1,439 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/khawsic/health/internal/crypto"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// OIDCLoginTimeout is how long a user has to finish signing in at the
+// identity provider
+const OIDCLoginTimeout = 10 * time.Minute
+
+var (
+	errUnknownProvider = errors.New("unknown identity provider")
+	errOIDCLogin       = errors.New("single sign-on failed — start again")
+
+	providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// OIDCProviderConfig describes one external identity provider. Providers are
+// loaded from the JSON file named by OIDC_PROVIDERS_FILE.
+type OIDCProviderConfig struct {
+	Name            string   `json:"name"` // URL-safe key, e.g. "hospital"
+	DisplayName     string   `json:"display_name"`
+	Issuer          string   `json:"issuer"`
+	ClientID        string   `json:"client_id"`
+	ClientSecret    string   `json:"client_secret"`
+	ClientSecretEnv string   `json:"client_secret_env"` // read the secret from this variable instead
+	RedirectURL     string   `json:"redirect_url"`
+	Scopes          []string `json:"scopes"`
+	GroupsClaim     string   `json:"groups_claim"` // defaults to "groups"
+
+	// RoleMapping maps IdP group names to local roles. Users in no mapped
+	// group are refused.
+	RoleMapping map[string]string `json:"role_mapping"`
+
+	// LinkByEmail lets a first SSO login attach to an existing local account
+	// with the same verified email address
+	LinkByEmail bool `json:"link_by_email"`
+
+	// MFAAMRValues lists "amr" values that prove the IdP already performed
+	// multi-factor authentication. Without one of them our own second factor
+	// is required as usual.
+	MFAAMRValues []string `json:"mfa_amr_values"`
+}
+
+type oidcProvider struct {
+	config   OIDCProviderConfig
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// OIDCProvider is the public description of a configured provider
+type OIDCProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// LoadOIDCProviders reads provider configuration from a JSON array file
+func LoadOIDCProviders(path string) ([]OIDCProviderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []OIDCProviderConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid provider file: %w", err)
+	}
+
+	for i := range configs {
+		if configs[i].ClientSecretEnv != "" {
+			configs[i].ClientSecret = os.Getenv(configs[i].ClientSecretEnv)
+		}
+	}
+	return configs, nil
+}
+
+// EnableOIDC runs discovery against every provider. It fails on the first
+// provider that is unreachable or misconfigured.
+func (s *Service) EnableOIDC(ctx context.Context, configs []OIDCProviderConfig) error {
+	providers := make(map[string]*oidcProvider, len(configs))
+
+	for _, cfg := range configs {
+		if !providerNamePattern.MatchString(cfg.Name) {
+			return fmt.Errorf("provider name %q must be lowercase letters, digits and dashes", cfg.Name)
+		}
+		if providers[cfg.Name] != nil {
+			return fmt.Errorf("provider %q is configured twice", cfg.Name)
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			return fmt.Errorf("provider %q needs issuer, client_id and redirect_url", cfg.Name)
+		}
+		if len(cfg.RoleMapping) == 0 {
+			return fmt.Errorf("provider %q has no role_mapping", cfg.Name)
+		}
+		if err := s.ValidateRoleMapping(cfg.RoleMapping); err != nil {
+			return fmt.Errorf("provider %q: %w", cfg.Name, err)
+		}
+
+		if cfg.DisplayName == "" {
+			cfg.DisplayName = cfg.Name
+		}
+		if cfg.GroupsClaim == "" {
+			cfg.GroupsClaim = "groups"
+		}
+		scopes := cfg.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{"profile", "email", "groups"}
+		}
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+
+		provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return fmt.Errorf("provider %q discovery failed: %w", cfg.Name, err)
+		}
+
+		providers[cfg.Name] = &oidcProvider{
+			config:   cfg,
+			verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+			oauth2: oauth2.Config{
+				ClientID:     cfg.ClientID,
+				ClientSecret: cfg.ClientSecret,
+				Endpoint:     provider.Endpoint(),
+				RedirectURL:  cfg.RedirectURL,
+				Scopes:       scopes,
+			},
+		}
+	}
+
+	s.oidc = providers
+	return nil
+}
+
+// OIDCProviders lists the configured providers, sorted by name
+func (s *Service) OIDCProviders() []OIDCProvider {
+	list := make([]OIDCProvider, 0, len(s.oidc))
+	for _, p := range s.oidc {
+		list = append(list, OIDCProvider{Name: p.config.Name, DisplayName: p.config.DisplayName})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// 🌐 BeginOIDCLogin — returns the provider's authorization URL and the
+// state it carries. The nonce and PKCE verifier are kept server-side until
+// the callback; the caller must bind the state to the browser so a callback
+// started elsewhere is refused.
+func (s *Service) BeginOIDCLogin(providerName string) (string, string, error) {
+	p := s.oidc[providerName]
+	if p == nil {
+		return "", "", errUnknownProvider
+	}
+
+	state, err := generateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := generateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	// Opportunistically clear abandoned logins
+	s.DB.Where("expires_at < ?", time.Now()).Delete(&OIDCLoginState{})
+
+	record := OIDCLoginState{
+		StateHash:    crypto.HashString(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginTimeout),
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return "", "", err
+	}
+
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// 🌐 FinishOIDCLogin — exchanges the authorization code, validates the ID
+// token, provisions or links the user and issues our own tokens
+func (s *Service) FinishOIDCLogin(ctx context.Context, providerName, state, code string) (*LoginResult, error) {
+	p := s.oidc[providerName]
+	if p == nil {
+		return nil, errUnknownProvider
+	}
+
+	loginState, err := s.takeOIDCLoginState(providerName, state)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(loginState.CodeVerifier))
+	if err != nil {
+		log.Printf("⚠️  OIDC code exchange with %s failed: %v", providerName, err)
+		return nil, errOIDCLogin
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		log.Printf("⚠️  OIDC provider %s returned no id_token", providerName)
+		return nil, errOIDCLogin
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		log.Printf("⚠️  OIDC ID token from %s rejected: %v", providerName, err)
+		return nil, errOIDCLogin
+	}
+	if idToken.Nonce != loginState.Nonce {
+		log.Printf("🚨 OIDC nonce mismatch from %s — possible token replay", providerName)
+		return nil, errOIDCLogin
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errOIDCLogin
+	}
+
+	role := p.mapRole(claimStrings(claims[p.config.GroupsClaim]))
+	if role == "" {
+		log.Printf("⚠️  OIDC login by %s/%s denied — no mapped group", providerName, idToken.Subject)
+		return nil, ErrNoRole
+	}
+
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	name, _ := claims["name"].(string)
+
+	user, err := s.provisionIdentity(ctx, providerName, &Identity{
+		Subject:      idToken.Subject,
+		Email:        email,
+		Name:         name,
+		Role:         role,
+		TrustedEmail: p.config.LinkByEmail && emailVerified,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PasskeyOnly {
+		return nil, s.recordLoginFailure(ctx, user, errors.New("this account requires passkey sign-in"))
+	}
+
+	if p.satisfiesMFA(claimStrings(claims["amr"])) {
+		return s.completeLogin(ctx, user, ACRMultiFactor)
+	}
+	return s.secondFactorOrComplete(ctx, user, ACRFederated)
+}
+
+// provisionIdentity finds the user linked to an external identity, links an
+// existing account by trusted email, or creates a new one. The external
+// store is authoritative for the role; a change revokes existing tokens.
+func (s *Service) provisionIdentity(ctx context.Context, provider string, identity *Identity) (*User, error) {
+	now := time.Now()
+
+	var user User
+	var link UserIdentity
+	err := s.DB.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&link).Error
+	switch {
+	case err == nil:
+		if err := s.DB.First(&user, link.UserID).Error; err != nil {
+			return nil, errAccountGone
+		}
+		s.DB.Model(&link).Updates(map[string]interface{}{"email": identity.Email, "last_login_at": now})
+
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if identity.Email == "" {
+			return nil, errors.New("identity provider did not share an email address")
+		}
+
+		err := s.DB.Where("email = ?", identity.Email).First(&user).Error
+		switch {
+		case err == nil:
+			if !identity.TrustedEmail {
+				log.Printf("⚠️  Login %s/%s matches existing account %s but linking is not allowed", provider, identity.Subject, identity.Email)
+				return nil, errors.New("an account with this email already exists")
+			}
+			log.Printf("🔗 Linked %s identity %s to user %d", provider, identity.Subject, user.ID)
+
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			name := identity.Name
+			if name == "" {
+				name = identity.Email
+			}
+			user, err = s.createSSOUser(name, identity.Email, identity.Role)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("👤 Provisioned user %d from %s identity %s", user.ID, provider, identity.Subject)
+
+		default:
+			return nil, err
+		}
+
+		link = UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			LastLoginAt: &now,
+		}
+		if err := s.DB.Create(&link).Error; err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, err
+	}
+
+	if user.Role != identity.Role {
+		log.Printf("🔁 Role of user %d changed by %s: %s → %s", user.ID, provider, user.Role, identity.Role)
+		if err := s.DB.Model(&user).Update("role", identity.Role).Error; err != nil {
+			return nil, err
+		}
+		if err := s.InvalidateUserTokens(user.ID); err != nil {
+			return nil, err
+		}
+		if s.authorizer != nil {
+			s.authorizer.ForgetUser(user.ID)
+		}
+
+		// No admin made this change, so it is recorded as the system's
+		s.recordSystemAction(ctx, "CHANGE_USER_ROLE", &user, map[string]interface{}{
+			"previous_role":     user.Role,
+			"new_role":          identity.Role,
+			"identity_provider": provider,
+		})
+		if err := s.DB.First(&user, user.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// createSSOUser creates an account that can only sign in through its IdP;
+// the password is a random value nobody knows
+func (s *Service) createSSOUser(name, email, role string) (User, error) {
+	secret, err := generateRandomToken()
+	if err != nil {
+		return User{}, err
+	}
+
+	hashedPassword, err := s.passwords.Hash(secret)
+	if err != nil {
+		return User{}, err
+	}
+
+	// The identity provider vouches for the account, so it is active at once
+	now := time.Now()
+	user := User{
+		Name:            name,
+		Email:           email,
+		Password:        hashedPassword,
+		Role:            role,
+		Status:          StatusActive,
+		EmailVerifiedAt: &now,
+	}
+	return user, s.DB.Create(&user).Error
+}
+
+// takeOIDCLoginState consumes the stored state so a callback cannot be replayed
+func (s *Service) takeOIDCLoginState(providerName, state string) (*OIDCLoginState, error) {
+	var record OIDCLoginState
+	if err := s.DB.Where("state_hash = ? AND provider = ?", crypto.HashString(state), providerName).
+		First(&record).Error; err != nil {
+		return nil, errOIDCLogin
+	}
+
+	result := s.DB.Delete(&record)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, errOIDCLogin
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return nil, errOIDCLogin
+	}
+
+	return &record, nil
+}
+
+func (p *oidcProvider) mapRole(groups []string) string {
+	return mapGroupsToRole(p.config.RoleMapping, groups)
+}
+
+// mapGroupsToRole returns the most privileged role any group maps to
+func mapGroupsToRole(mapping map[string]string, groups []string) string {
+	role := ""
+	for _, group := range groups {
+		mapped := mapping[group]
+		if mapped != "" && (role == "" || roleRank(mapped) > roleRank(role)) {
+			role = mapped
+		}
+	}
+	return role
+}
+
+func (p *oidcProvider) satisfiesMFA(amr []string) bool {
+	for _, value := range amr {
+		for _, accepted := range p.config.MFAAMRValues {
+			if strings.EqualFold(value, accepted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimStrings accepts a claim given either as a string or a string array
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
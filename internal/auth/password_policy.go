@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Password policy violation codes. Clients can key help text off these;
+// the messages are already suitable for display.
+const (
+	PasswordTooShort      = "password_too_short"
+	PasswordTooLong       = "password_too_long"
+	PasswordMissingLower  = "password_missing_lowercase"
+	PasswordMissingUpper  = "password_missing_uppercase"
+	PasswordMissingDigit  = "password_missing_digit"
+	PasswordMissingSymbol = "password_missing_symbol"
+	PasswordBannedWord    = "password_contains_banned_word"
+	PasswordPersonalInfo  = "password_contains_personal_info"
+	PasswordBreached      = "password_breached"
+	PasswordRecentlyUsed  = "password_recently_used"
+)
+
+// minPersonalInfoFragment is the shortest name or email fragment that a
+// password may not contain; shorter ones match too many passwords by chance
+const minPersonalInfoFragment = 4
+
+// PasswordPolicy describes what a new password must satisfy. It is loaded
+// from the JSON file named by PASSWORD_POLICY_FILE over the defaults.
+type PasswordPolicy struct {
+	MinLength     int  `json:"min_length"`
+	MaxLength     int  `json:"max_length"`
+	RequireLower  bool `json:"require_lowercase"`
+	RequireUpper  bool `json:"require_uppercase"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+
+	// BannedWords may not appear anywhere in the password, ignoring case
+	BannedWords []string `json:"banned_words"`
+
+	// RejectPersonalInfo refuses passwords containing the user's name or
+	// the local part of their email address
+	RejectPersonalInfo bool `json:"reject_personal_info"`
+
+	// HistorySize is how many of the user's most recent passwords, the
+	// current one included, cannot be chosen again. Zero disables the check.
+	HistorySize int `json:"history_size"`
+}
+
+// DefaultPasswordPolicy is used when no policy file is configured
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:          10,
+		MaxLength:          128,
+		RequireLower:       true,
+		RequireUpper:       true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		BannedWords:        []string{"healthvault", "password", "qwerty", "letmein"},
+		RejectPersonalInfo: true,
+		HistorySize:        5,
+	}
+}
+
+// LoadPasswordPolicy reads a policy file; fields it leaves out keep their
+// default values
+func LoadPasswordPolicy(path string) (PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("invalid password policy file: %w", err)
+	}
+
+	if policy.MinLength < 8 {
+		return policy, errors.New("min_length must be at least 8")
+	}
+	if policy.MaxLength < policy.MinLength || policy.MaxLength > 1024 {
+		return policy, errors.New("max_length must be between min_length and 1024")
+	}
+	if policy.HistorySize < 0 {
+		return policy, errors.New("history_size must not be negative")
+	}
+	return policy, nil
+}
+
+// PasswordViolation is one reason a password was refused
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a candidate password broke
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// check applies the rules that need nothing but the password and the
+// account's name and email
+func (p PasswordPolicy) check(password, name, email string) []PasswordViolation {
+	var violations []PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(PasswordTooShort, fmt.Sprintf("Password must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		add(PasswordTooLong, fmt.Sprintf("Password must be at most %d bytes", p.MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireLower && !lower {
+		add(PasswordMissingLower, "Password must contain a lowercase letter")
+	}
+	if p.RequireUpper && !upper {
+		add(PasswordMissingUpper, "Password must contain an uppercase letter")
+	}
+	if p.RequireDigit && !digit {
+		add(PasswordMissingDigit, "Password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		add(PasswordMissingSymbol, "Password must contain a symbol")
+	}
+
+	folded := strings.ToLower(password)
+	for _, word := range p.BannedWords {
+		if word != "" && strings.Contains(folded, strings.ToLower(word)) {
+			add(PasswordBannedWord, "Password contains a commonly used word")
+			break
+		}
+	}
+
+	if p.RejectPersonalInfo {
+		for _, fragment := range personalInfo(name, email) {
+			if strings.Contains(folded, fragment) {
+				add(PasswordPersonalInfo, "Password must not contain your name or email address")
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// personalInfo splits the name and email local part into the lowercase
+// fragments long enough to be worth refusing
+func personalInfo(name, email string) []string {
+	local, _, _ := strings.Cut(email, "@")
+	fields := strings.FieldsFunc(strings.ToLower(name+" "+local), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var fragments []string
+	for _, field := range fields {
+		if utf8.RuneCountInString(field) >= minPersonalInfoFragment {
+			fragments = append(fragments, field)
+		}
+	}
+	return fragments
+}
+
+// BreachedPasswords looks candidates up in a local copy of a breached
+// password corpus stored in the k-anonymity range format: one file per
+// 5-character uppercase SHA-1 prefix (e.g. 21BD1 or 21BD1.txt), each line
+// holding the remaining 35 hex characters and a count as "SUFFIX:COUNT".
+type BreachedPasswords struct {
+	dir string
+}
+
+// NewBreachedPasswords checks that the range directory exists. Prefixes
+// with no file are treated as having no breached hashes.
+func NewBreachedPasswords(dir string) (*BreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &BreachedPasswords{dir: dir}, nil
+}
+
+// Contains reports whether the password's SHA-1 hash is in the corpus
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(b.dir, prefix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// UsePasswordPolicy replaces the default password policy
+func (s *Service) UsePasswordPolicy(policy PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
+// UseBreachedPasswords turns on the breached password check
+func (s *Service) UseBreachedPasswords(breached *BreachedPasswords) {
+	s.breachedPasswords = breached
+}
+
+// PasswordPolicy returns the active policy so clients can describe it
+func (s *Service) PasswordPolicy() PasswordPolicy {
+	return s.passwordPolicy
+}
+
+// validatePassword checks a new password for user. The history check only
+// runs for existing accounts and only once the cheaper rules pass, since it
+// costs a hash comparison per remembered password.
+func (s *Service) validatePassword(user *User, password string) error {
+	violations := s.passwordPolicy.check(password, user.Name, user.Email)
+
+	if s.breachedPasswords != nil {
+		breached, err := s.breachedPasswords.Contains(password)
+		if err != nil {
+			return fmt.Errorf("unable to check password: %w", err)
+		}
+		if breached {
+			violations = append(violations, PasswordViolation{
+				Code:    PasswordBreached,
+				Message: "This password has appeared in a data breach — choose a different one",
+			})
+		}
+	}
+
+	if len(violations) == 0 && user.ID != 0 && s.passwordRecentlyUsed(user, password) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordRecentlyUsed,
+			Message: fmt.Sprintf("Password must differ from your last %d passwords", s.passwordPolicy.HistorySize),
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Service) passwordRecentlyUsed(user *User, password string) bool {
+	if s.passwordPolicy.HistorySize <= 0 {
+		return false
+	}
+
+	// The current password counts even for accounts created before
+	// history was kept
+	hashes := []string{user.Password}
+
+	var history []PasswordHistory
+	s.DB.Where("user_id = ?", user.ID).
+		Order("created_at DESC, id DESC").
+		Limit(s.passwordPolicy.HistorySize).
+		Find(&history)
+	for _, entry := range history {
+		hashes = append(hashes, entry.PasswordHash)
+	}
+
+	for _, hash := range hashes {
+		if ok, _, _ := s.passwords.Verify(hash, password); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPassword remembers a newly set password hash and forgets those
+// beyond the history size
+func (s *Service) recordPassword(tx *gorm.DB, userID uint, hash string) error {
+	if s.passwordPolicy.HistorySize <= 0 {
+		return nil
+	}
+
+	if err := tx.Create(&PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+		return err
+	}
+
+	var keep []uint
+	if err := tx.Model(&PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(s.passwordPolicy.HistorySize).
+		Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id NOT IN ?", userID, keep).Delete(&PasswordHistory{}).Error
+}
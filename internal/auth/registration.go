@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/khawsic/health/internal/crypto"
+	"github.com/khawsic/health/internal/mail"
+	"gorm.io/gorm"
+)
+
+// Account status. Self-registered accounts start unverified; doctors then
+// wait for an administrator before they can sign in.
+const (
+	StatusPendingVerification = "pending_verification"
+	StatusPendingApproval     = "pending_approval"
+	StatusActive              = "active"
+	StatusRejected            = "rejected"
+)
+
+// Doctor application status
+const (
+	ApplicationPending  = "pending"
+	ApplicationApproved = "approved"
+	ApplicationRejected = "rejected"
+)
+
+const verificationTokenExpiry = 24 * time.Hour
+
+var errInvalidVerification = errors.New("invalid or expired verification link")
+
+// checkActive refuses sign-in for accounts that are not yet, or no longer,
+// allowed in. It runs after the credential check so the account's state is
+// only revealed to someone who knows the password.
+func checkActive(user *User) error {
+	switch user.Status {
+	case StatusActive:
+		return nil
+	case StatusPendingVerification:
+		return errors.New("verify your email address before signing in")
+	case StatusPendingApproval:
+		return errors.New("your account is awaiting administrator approval")
+	case StatusRejected:
+		return errors.New("your registration was not approved")
+	case StatusDisabled:
+		return errors.New("this account has been disabled — contact your administrator")
+	default:
+		return errAccountGone
+	}
+}
+
+// 📧 VerifyEmail — consumes a verification link. Patients become active;
+// doctors move on to the approval queue. Returns the new account status.
+func (s *Service) VerifyEmail(token string) (string, error) {
+	var record EmailVerificationToken
+	err := s.DB.Where("token_hash = ? AND used_at IS NULL", crypto.HashString(token)).First(&record).Error
+	if err != nil || time.Now().After(record.ExpiresAt) {
+		return "", errInvalidVerification
+	}
+
+	var status string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Conditional update so a link cannot be used twice concurrently
+		result := tx.Model(&EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidVerification
+		}
+
+		var user User
+		if err := tx.First(&user, record.UserID).Error; err != nil {
+			return errInvalidVerification
+		}
+		if user.Status != StatusPendingVerification {
+			status = user.Status
+			return nil
+		}
+
+		status = StatusActive
+		if user.Role == "doctor" {
+			status = StatusPendingApproval
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"status":            status,
+			"email_verified_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.forgetUser(record.UserID)
+	log.Printf("📧 Email verified for user %d — status %s", record.UserID, status)
+	return status, nil
+}
+
+// 📧 ResendVerification — sends a fresh link to an unverified account. Like
+// RequestPasswordReset it works in the background and never reveals
+// whether the address is registered.
+func (s *Service) ResendVerification(email string) {
+	go func() {
+		var user User
+		if err := s.DB.Where("email = ? AND status = ?", email, StatusPendingVerification).First(&user).Error; err != nil {
+			return
+		}
+		s.sendVerificationEmail(user)
+	}()
+}
+
+// sendVerificationEmail delivers a new link, logging rather than returning
+// failures; callers run it in the background
+func (s *Service) sendVerificationEmail(user User) {
+	ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+	defer cancel()
+
+	if err := s.sendVerification(ctx, &user); err != nil {
+		log.Printf("⚠️  Verification email for user %d failed: %v", user.ID, err)
+	}
+}
+
+func (s *Service) sendVerification(ctx context.Context, user *User) error {
+	if s.mailer == nil {
+		return errors.New("no mailer configured")
+	}
+
+	// Only the newest link works
+	s.DB.Model(&EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", time.Now())
+
+	verifyToken, err := generateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	record := EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: crypto.HashString(verifyToken),
+		ExpiresAt: time.Now().Add(verificationTokenExpiry),
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return err
+	}
+
+	msg, err := mail.Render(mail.TemplateEmailVerification, user.Email, map[string]string{
+		"Name":      user.Name,
+		"VerifyURL": s.appURL + "/verify-email?token=" + url.QueryEscape(verifyToken),
+		"ExpiresIn": fmt.Sprintf("%d hours", int(verificationTokenExpiry.Hours())),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// ListDoctorApplications returns the review queue, oldest first. An empty
+// status returns applications in every state.
+func (s *Service) ListDoctorApplications(status string) ([]DoctorApplication, error) {
+	query := s.DB.Preload("User").Order("created_at ASC")
+	if status != "" {
+		if status != ApplicationPending && status != ApplicationApproved && status != ApplicationRejected {
+			return nil, errors.New("invalid status filter")
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var applications []DoctorApplication
+	if err := query.Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	return applications, nil
+}
+
+// 🩺 ReviewDoctorApplication — approves or rejects a pending doctor. Only
+// applicants who verified their email can be approved; either decision is
+// emailed to the applicant.
+func (s *Service) ReviewDoctorApplication(applicationID, adminID uint, approve bool, notes string) error {
+	var application DoctorApplication
+	var user User
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&application, applicationID).Error; err != nil {
+			return errors.New("application not found")
+		}
+		if application.Status != ApplicationPending {
+			return errors.New("application has already been reviewed")
+		}
+		if err := tx.First(&user, application.UserID).Error; err != nil {
+			return errors.New("applicant account no longer exists")
+		}
+		if approve && user.Status != StatusPendingApproval {
+			return errors.New("applicant has not verified their email address")
+		}
+
+		decision, status := ApplicationRejected, StatusRejected
+		if approve {
+			decision, status = ApplicationApproved, StatusActive
+		}
+
+		now := time.Now()
+		result := tx.Model(&DoctorApplication{}).
+			Where("id = ? AND status = ?", application.ID, ApplicationPending).
+			Updates(map[string]interface{}{
+				"status":       decision,
+				"reviewed_by":  adminID,
+				"review_notes": notes,
+				"reviewed_at":  now,
+				"updated_at":   now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("application has already been reviewed")
+		}
+
+		application.Status = decision
+		application.ReviewNotes = notes
+		return tx.Model(&user).Update("status", status).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.forgetUser(user.ID)
+	log.Printf("🩺 Doctor application %d for user %d %s by admin %d", application.ID, user.ID, application.Status, adminID)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.sendApplicationDecision(ctx, &user, &application); err != nil {
+			log.Printf("⚠️  Decision email for application %d failed: %v", application.ID, err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) sendApplicationDecision(ctx context.Context, user *User, application *DoctorApplication) error {
+	if s.mailer == nil {
+		return errors.New("no mailer configured")
+	}
+
+	msg, err := mail.Render(mail.TemplateDoctorApplicationDecision, user.Email, map[string]interface{}{
+		"Name":     user.Name,
+		"Approved": application.Status == ApplicationApproved,
+		"Notes":    application.ReviewNotes,
+		"LoginURL": s.appURL + "/",
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
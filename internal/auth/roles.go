@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/khawsic/health/internal/authz"
+)
+
+// knownRoles are the built-in roles, used when no authorizer is configured
+// (for example by the seed command)
+var knownRoles = map[string]bool{"admin": true, "doctor": true, "patient": true}
+
+// builtInRank orders roles so a user in several mapped identity provider
+// groups gets the most privileged one. Roles defined by administrators
+// rank above patients and below doctors.
+var builtInRank = map[string]int{
+	"patient":    1,
+	"researcher": 2,
+	"pharmacist": 3,
+	"nurse":      3,
+	"auditor":    3,
+	"doctor":     4,
+	"admin":      5,
+}
+
+func roleRank(role string) int {
+	if rank, ok := builtInRank[role]; ok {
+		return rank
+	}
+	return 2
+}
+
+// UseAuthorizer makes roles defined in the database valid for role
+// changes, MFA policies and identity provider mappings
+func (s *Service) UseAuthorizer(authorizer *authz.Service) {
+	s.authorizer = authorizer
+}
+
+func (s *Service) roleExists(role string) bool {
+	if s.authorizer == nil {
+		return knownRoles[role]
+	}
+	return s.authorizer.RoleExists(role)
+}
+
+// canManageRoles reports whether role grants role:manage
+func (s *Service) canManageRoles(role string) bool {
+	if s.authorizer == nil {
+		return role == "admin"
+	}
+	for _, permission := range s.authorizer.RolePermissions(role) {
+		if permission == authz.PermRoleManage {
+			return true
+		}
+	}
+	return false
+}
+
+// otherRoleManagers reports whether an active account other than userID
+// can still manage roles, so nobody removes the last one able to
+func (s *Service) otherRoleManagers(userID uint) (bool, error) {
+	var roles []string
+	if err := s.DB.Model(&User{}).
+		Where("id <> ? AND status = ? AND kind <> ?", userID, StatusActive, KindService).
+		Distinct().Pluck("role", &roles).Error; err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if s.canManageRoles(role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ValidateRoleMapping checks that every group in an identity provider
+// mapping maps to a defined role
+func (s *Service) ValidateRoleMapping(mapping map[string]string) error {
+	for group, role := range mapping {
+		if !s.roleExists(role) {
+			return fmt.Errorf("group %q maps to unknown role %q", group, role)
+		}
+	}
+	return nil
+}
@@ -1,45 +1,101 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/khawsic/health/internal/audit"
+	"github.com/khawsic/health/internal/authz"
+	"github.com/khawsic/health/internal/crypto"
+	"github.com/khawsic/health/internal/geoip"
+	"github.com/khawsic/health/internal/keyring"
+	"github.com/khawsic/health/internal/mail"
 	"gorm.io/gorm"
 )
 
 const (
-	maxFailedAttempts  = 5
-	lockoutDuration    = 15 * time.Minute
-	accessTokenExpiry  = 15 * time.Minute
+	AccessTokenExpiry  = 15 * time.Minute
 	refreshTokenExpiry = 7 * 24 * time.Hour
 	resetTokenExpiry   = 30 * time.Minute
+	mailTimeout        = time.Minute
 )
 
+// Service handles authentication. Access tokens are signed with the
+// asymmetric Keys; JWTSecret only protects internal MFA challenge tokens.
 type Service struct {
-	DB        *gorm.DB
-	JWTSecret string
+	DB             *gorm.DB
+	JWTSecret      string
+	EncryptionKey  string
+	Keys           *keyring.Keyring
+	webAuthn       *webauthn.WebAuthn
+	oidc           map[string]*oidcProvider
+	authenticators []Authenticator
+	mailer         mail.Mailer
+	appURL         string
+	geoip          *geoip.Reader
+	sessions       *sessionCache
+	auditLog       *audit.Service
+	authorizer     *authz.Service
+
+	passwords         *crypto.Passwords
+	passwordPolicy    PasswordPolicy
+	breachedPasswords *BreachedPasswords
+
+	loginBackoff LoginBackoff
 }
 
-func NewService(db *gorm.DB, secret string) *Service {
+func NewService(db *gorm.DB, secret, encryptionKey string, keys *keyring.Keyring) *Service {
+	passwords := crypto.DefaultPasswords()
 	return &Service{
-		DB:        db,
-		JWTSecret: secret,
+		DB:             db,
+		JWTSecret:      secret,
+		EncryptionKey:  encryptionKey,
+		Keys:           keys,
+		authenticators: []Authenticator{NewPasswordAuthenticator(db, passwords)},
+		sessions:       newSessionCache(),
+		passwords:      passwords,
+		passwordPolicy: DefaultPasswordPolicy(),
+		loginBackoff:   DefaultLoginBackoff(),
 	}
 }
 
-// 🔐 Register User
-func (s *Service) Register(name, email, password, role string) error {
+// LoginResult is returned by Login. When a second factor is still needed
+// only MFAToken is set and the tokens are issued by VerifyMFA instead.
+type LoginResult struct {
+	AccessToken           string
+	RefreshToken          string
+	MFAToken              string
+	MFAEnrollmentRequired bool
+}
+
+// 🔐 Register User — new accounts must verify their email address, and
+// doctors are then held for administrator approval
+func (s *Service) Register(name, email, password, role, licenseNumber, notes string) error {
 
 	validRoles := map[string]bool{"doctor": true, "patient": true}
 	if !validRoles[role] {
 		return errors.New("invalid role: must be 'doctor' or 'patient'")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	licenseNumber = strings.TrimSpace(licenseNumber)
+	if role == "doctor" && licenseNumber == "" {
+		return errors.New("license number is required to register as a doctor")
+	}
+
+	if err := s.validatePassword(&User{Name: name, Email: email}, password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(password)
 	if err != nil {
 		return err
 	}
@@ -47,135 +103,330 @@ func (s *Service) Register(name, email, password, role string) error {
 	user := User{
 		Name:     name,
 		Email:    email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 		Role:     role,
+		Status:   StatusPendingVerification,
 	}
 
-	return s.DB.Create(&user).Error
-}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := s.recordPassword(tx, user.ID, user.Password); err != nil {
+			return err
+		}
+		if role != "doctor" {
+			return nil
+		}
+		return tx.Create(&DoctorApplication{
+			UserID:        user.ID,
+			LicenseNumber: licenseNumber,
+			Notes:         strings.TrimSpace(notes),
+			Status:        ApplicationPending,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
 
-// 🔑 Login User — returns access token + refresh token
-func (s *Service) Login(email, password string) (string, string, error) {
-	var user User
+	go s.sendVerificationEmail(user)
+	return nil
+}
 
-	err := s.DB.Where("email = ?", email).First(&user).Error
+// 🔑 Login User — returns access token + refresh token, or an MFA challenge
+func (s *Service) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	user, err := s.authenticate(ctx, email, password)
 	if err != nil {
-		return "", "", errors.New("invalid email or password")
+		return nil, s.recordLoginFailure(ctx, s.userByEmail(email), err)
 	}
 
-	// Check if account is locked
-	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
-		remaining := time.Until(*user.LockedUntil).Round(time.Second)
-		return "", "", errors.New("account locked — try again in " + remaining.String())
+	// Staff who opted into passkey-only login cannot use a password at all
+	if user.PasskeyOnly {
+		return nil, s.recordLoginFailure(ctx, user, errors.New("this account requires passkey sign-in"))
 	}
 
-	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		user.FailedAttempts++
-		if user.FailedAttempts >= maxFailedAttempts {
-			lockUntil := time.Now().Add(lockoutDuration)
-			user.LockedUntil = &lockUntil
-			user.FailedAttempts = 0
+	return s.secondFactorOrComplete(ctx, user, ACRPassword)
+}
+
+// secondFactorOrComplete finishes a first-factor login. Enrolled users must
+// verify, and roles that require MFA must enroll before any tokens are issued.
+// acr describes the first factor, for logins that need no second one.
+func (s *Service) secondFactorOrComplete(ctx context.Context, user *User, acr string) (*LoginResult, error) {
+	if err := checkActive(user); err != nil {
+		return nil, s.recordLoginFailure(ctx, user, err)
+	}
+
+	if user.MFAEnabled || s.mfaRequiredForRole(user.Role) {
+		purpose := mfaPurposeVerify
+		if !user.MFAEnabled {
+			purpose = mfaPurposeEnroll
 		}
-		s.DB.Save(&user)
-		return "", "", errors.New("invalid email or password")
+
+		mfaToken, err := s.issueMFAToken(user.ID, purpose)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LoginResult{
+			MFAToken:              mfaToken,
+			MFAEnrollmentRequired: !user.MFAEnabled,
+		}, nil
+	}
+
+	return s.completeLogin(ctx, user, acr)
+}
+
+// completeLogin resets lockout state, issues the session tokens and adds
+// the login to the user's history. acr records how strongly the user
+// authenticated.
+func (s *Service) completeLogin(ctx context.Context, user *User, acr string) (*LoginResult, error) {
+	if err := checkActive(user); err != nil {
+		return nil, s.recordLoginFailure(ctx, user, err)
 	}
 
 	// Reset failed attempts on successful login
 	user.FailedAttempts = 0
 	user.LockedUntil = nil
-	s.DB.Save(&user)
-
-	// Generate access token
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(accessTokenExpiry).Unix(),
+	user.LastFailedAt = nil
+	s.DB.Model(user).Updates(map[string]interface{}{
+		"failed_attempts": 0,
+		"locked_until":    nil,
+		"last_failed_at":  nil,
 	})
 
-	accessTokenString, err := accessToken.SignedString([]byte(s.JWTSecret))
+	// Lockout state feeds access token validation
+	s.forgetUser(user.ID)
+
+	accessToken, refreshToken, err := s.issueTokens(ctx, user, acr)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
+	s.recordLogin(ctx, user, acr)
 
-	// Generate refresh token
-	refreshTokenString, err := generateRandomToken()
+	return &LoginResult{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// issueTokens starts a session for this login: a new refresh token family
+// sharing the session ID, and an access token bound to it
+func (s *Service) issueTokens(ctx context.Context, user *User, acr string) (string, string, error) {
+	sessionID, err := generateRandomToken()
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken := RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshTokenString,
+	session := Session{
+		ID:        sessionID,
 		ExpiresAt: time.Now().Add(refreshTokenExpiry),
-		Revoked:   false,
+		ACR:       acr,
+	}
+	if err := s.startSession(ctx, user, &session); err != nil {
+		return "", "", err
+	}
+
+	refreshTokenString, err := storeRefreshToken(s.DB, user.ID, sessionID, session.ExpiresAt)
+	if err != nil {
+		return "", "", err
 	}
 
-	if err := s.DB.Create(&refreshToken).Error; err != nil {
+	accessTokenString, err := s.signAccessToken(user, &session)
+	if err != nil {
 		return "", "", err
 	}
 
 	return accessTokenString, refreshTokenString, nil
 }
 
-// 🔄 Refresh — exchange refresh token for new access token
-func (s *Service) Refresh(refreshTokenString string) (string, error) {
+// signAccessToken signs with the keyring's active Ed25519 key and names it
+// in the kid header so verifiers can pick the key from the JWKS. The sid
+// and ver claims let AuthMiddleware revoke the token server-side and jti
+// makes every token unique; auth_time and acr let sensitive routes demand
+// a recent authentication.
+func (s *Service) signAccessToken(user *User, session *Session) (string, error) {
+	kid, privateKey, err := s.Keys.Signer()
+	if err != nil {
+		return "", err
+	}
+
+	tokenID, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"role":    user.Role,
+		"sid":     session.ID,
+		"jti":     tokenID,
+		"ver":     user.TokenVersion,
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(AccessTokenExpiry).Unix(),
+	}
+	if session.AuthTime != nil {
+		claims["auth_time"] = session.AuthTime.Unix()
+		claims["acr"] = session.ACR
+	}
+	accessToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	accessToken.Header["kid"] = kid
+
+	return accessToken.SignedString(privateKey)
+}
+
+// storeRefreshToken generates a refresh token and persists only its hash
+func storeRefreshToken(db *gorm.DB, userID uint, familyID string, expiresAt time.Time) (string, error) {
+	refreshTokenString, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := RefreshToken{
+		UserID:    userID,
+		TokenHash: crypto.HashString(refreshTokenString),
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+		Revoked:   false,
+	}
+
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+
+	return refreshTokenString, nil
+}
+
+// 🔄 Refresh — exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is retired; presenting it again is
+// treated as theft and revokes every token in its family.
+func (s *Service) Refresh(ctx context.Context, refreshTokenString string) (string, string, error) {
 	var token RefreshToken
 
-	err := s.DB.Where("token = ? AND revoked = false", refreshTokenString).First(&token).Error
+	err := s.DB.Where("token_hash = ?", crypto.HashString(refreshTokenString)).First(&token).Error
 	if err != nil {
-		return "", errors.New("invalid or expired refresh token")
+		return "", "", errors.New("invalid or expired refresh token")
+	}
+
+	if token.RotatedAt != nil {
+		s.revokeFamily(&token)
+		return "", "", errReuseDetected
+	}
+
+	if token.Revoked {
+		return "", "", errors.New("invalid or expired refresh token")
 	}
 
 	if time.Now().After(token.ExpiresAt) {
-		return "", errors.New("refresh token expired")
+		return "", "", errors.New("refresh token expired")
 	}
 
 	var user User
 	if err := s.DB.First(&user, token.UserID).Error; err != nil {
-		return "", errors.New("user not found")
+		return "", "", errors.New("user not found")
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(accessTokenExpiry).Unix(),
+	if err := checkActive(&user); err != nil {
+		return "", "", err
+	}
+
+	var newRefreshToken string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Conditional update so two concurrent refreshes cannot both win
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND revoked = false AND rotated_at IS NULL", token.ID).
+			Updates(map[string]interface{}{
+				"revoked":    true,
+				"rotated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReuseDetected
+		}
+
+		// Rotation keeps the family's original expiry so refreshing never
+		// extends a login beyond refreshTokenExpiry
+		var err error
+		newRefreshToken, err = storeRefreshToken(tx, user.ID, token.FamilyID, token.ExpiresAt)
+		return err
 	})
+	if errors.Is(err, errReuseDetected) {
+		s.revokeFamily(&token)
+		return "", "", err
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	session := s.touchSession(ctx, &token)
 
-	accessTokenString, err := accessToken.SignedString([]byte(s.JWTSecret))
+	accessTokenString, err := s.signAccessToken(&user, session)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return accessTokenString, nil
+	return accessTokenString, newRefreshToken, nil
 }
 
-// 🚪 Logout — revoke refresh token
-func (s *Service) Logout(refreshTokenString string) error {
-	result := s.DB.Model(&RefreshToken{}).
-		Where("token = ?", refreshTokenString).
-		Update("revoked", true)
+var errReuseDetected = errors.New("refresh token reuse detected — all sessions from this login have been revoked")
+
+// revokeFamily ends the session a reused refresh token belongs to,
+// including the access tokens already issued from it
+func (s *Service) revokeFamily(token *RefreshToken) {
+	log.Printf("🚨 Refresh token reuse detected for user %d — revoking token family %s", token.UserID, token.FamilyID)
 
-	if result.Error != nil {
-		return result.Error
+	if err := s.revokeSession(token.FamilyID); err != nil {
+		log.Printf("⚠️  Failed to revoke token family %s: %v", token.FamilyID, err)
 	}
+}
+
+// 🚪 Logout — ends the session the refresh token belongs to
+func (s *Service) Logout(refreshTokenString string) error {
+	var token RefreshToken
 
-	if result.RowsAffected == 0 {
+	err := s.DB.Where("token_hash = ?", crypto.HashString(refreshTokenString)).First(&token).Error
+	if err != nil {
 		return errors.New("token not found")
 	}
 
-	return nil
+	return s.revokeSession(token.FamilyID)
 }
 
-// 🔁 RequestPasswordReset — generates a reset token for the user
-func (s *Service) RequestPasswordReset(email string) (string, error) {
+// 🔁 RequestPasswordReset — emails a reset link if the address belongs to a
+// local account. The work happens in the background so the caller's
+// response, and its timing, do not reveal whether the account exists.
+func (s *Service) RequestPasswordReset(email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("⚠️  Password reset for %s failed: %v", email, err)
+		}
+	}()
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
 	var user User
+	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil
+	}
+	if user.Kind == KindService {
+		return nil
+	}
 
-	err := s.DB.Where("email = ?", email).First(&user).Error
-	if err != nil {
-		// Return success even if email not found — prevents user enumeration
-		return "", nil
+	// Accounts signed in through SSO or a directory have no local password
+	// to reset; changing it there must happen at the identity provider
+	var linked int64
+	s.DB.Model(&UserIdentity{}).Where("user_id = ?", user.ID).Count(&linked)
+	if linked > 0 {
+		log.Printf("⚠️  Password reset requested for externally managed user %d — ignored", user.ID)
+		return nil
+	}
+
+	if s.mailer == nil {
+		return errors.New("no mailer configured")
 	}
 
 	// Revoke any existing reset tokens for this user
@@ -186,29 +437,44 @@ func (s *Service) RequestPasswordReset(email string) (string, error) {
 	// Generate reset token
 	resetToken, err := generateRandomToken()
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	token := PasswordResetToken{
 		UserID:    user.ID,
-		Token:     resetToken,
+		TokenHash: crypto.HashString(resetToken),
 		ExpiresAt: time.Now().Add(resetTokenExpiry),
 		Used:      false,
 	}
 
 	if err := s.DB.Create(&token).Error; err != nil {
-		return "", err
+		return err
+	}
+
+	msg, err := mail.Render(mail.TemplatePasswordReset, user.Email, map[string]string{
+		"Name":      user.Name,
+		"ResetURL":  s.appURL + "/forgot-password?token=" + url.QueryEscape(resetToken),
+		"ExpiresIn": fmt.Sprintf("%d minutes", int(resetTokenExpiry.Minutes())),
+	})
+	if err != nil {
+		return err
 	}
 
-	// In production this would be emailed — for now return token directly
-	return resetToken, nil
+	return s.mailer.Send(ctx, msg)
+}
+
+// UseMailer sets how outgoing email is delivered. appURL is the frontend
+// origin that links in emails point to.
+func (s *Service) UseMailer(mailer mail.Mailer, appURL string) {
+	s.mailer = mailer
+	s.appURL = strings.TrimSuffix(appURL, "/")
 }
 
 // 🔁 ResetPassword — validates token and sets new password
 func (s *Service) ResetPassword(token, newPassword string) error {
 	var resetToken PasswordResetToken
 
-	err := s.DB.Where("token = ? AND used = false", token).First(&resetToken).Error
+	err := s.DB.Where("token_hash = ? AND used = false", crypto.HashString(token)).First(&resetToken).Error
 	if err != nil {
 		return errors.New("invalid or expired reset token")
 	}
@@ -218,28 +484,39 @@ func (s *Service) ResetPassword(token, newPassword string) error {
 		return errors.New("reset token has expired")
 	}
 
-	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
-	if err != nil {
+	var user User
+	if err := s.DB.First(&user, resetToken.UserID).Error; err != nil {
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err := s.validatePassword(&user, newPassword); err != nil {
 		return err
 	}
 
-	// Update password
-	if err := s.DB.Model(&User{}).
-		Where("id = ?", resetToken.UserID).
-		Update("password", string(hashedPassword)).Error; err != nil {
+	// Hash new password
+	hashedPassword, err := s.passwords.Hash(newPassword)
+	if err != nil {
 		return err
 	}
 
-	// Mark token as used
-	s.DB.Model(&resetToken).Update("used", true)
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Update password
+		if err := tx.Model(&user).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		if err := s.recordPassword(tx, user.ID, hashedPassword); err != nil {
+			return err
+		}
 
-	// Revoke all refresh tokens for security
-	s.DB.Model(&RefreshToken{}).
-		Where("user_id = ?", resetToken.UserID).
-		Update("revoked", true)
+		// Mark token as used
+		return tx.Model(&resetToken).Update("used", true).Error
+	})
+	if err != nil {
+		return err
+	}
 
-	return nil
+	// End every session and invalidate outstanding access tokens
+	return s.InvalidateUserTokens(resetToken.UserID)
 }
 
 // Helper — generate cryptographically secure random token
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/khawsic/health/internal/requestctx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// userStateTTL bounds how long a role change, lockout or deletion can
+	// go unnoticed by another instance
+	userStateTTL = 10 * time.Second
+
+	// denylistSyncInterval bounds how long a revocation made on another
+	// instance can go unnoticed
+	denylistSyncInterval = 10 * time.Second
+
+	denyKindSession = "sid"
+)
+
+var (
+	errSessionRevoked = errors.New("session has been revoked — log in again")
+	errTokenOutdated  = errors.New("session is no longer valid — log in again")
+	errAccountGone    = errors.New("account is no longer active")
+)
+
+// sessionCache keeps per-user token state and the denylist in memory so
+// AuthMiddleware does not hit the database on every request
+type sessionCache struct {
+	mu       sync.Mutex
+	users    map[uint]cachedUser
+	denied   map[string]time.Time
+	syncedAt time.Time
+}
+
+type cachedUser struct {
+	version   int
+	status    string
+	fetchedAt time.Time
+}
+
+func newSessionCache() *sessionCache {
+	return &sessionCache{
+		users:  make(map[uint]cachedUser),
+		denied: make(map[string]time.Time),
+	}
+}
+
+// ValidateAccessToken is called by AuthMiddleware for every request after
+// the signature check. It rejects tokens without a jti, tokens whose
+// session was revoked, whose user was deleted or is no longer active, and
+// tokens minted before the user's token version was bumped. Revocation is
+// per session: a single access token is never denylisted. The failed login
+// backoff is deliberately not checked here — anyone who knows the email
+// address can trigger it, and it must not end the user's sessions. To
+// block a signed-in user, disable the account.
+func (s *Service) ValidateAccessToken(userID uint, sessionID, tokenID string, version int) error {
+	if sessionID == "" || tokenID == "" {
+		return errTokenOutdated
+	}
+
+	if s.isDenied(sessionID) {
+		return errSessionRevoked
+	}
+
+	state, err := s.userState(userID)
+	if err != nil {
+		return err
+	}
+
+	if state.version != version {
+		return errTokenOutdated
+	}
+
+	if state.status != StatusActive {
+		return errAccountGone
+	}
+
+	return nil
+}
+
+// ListSessions returns the user's active sessions, newest first
+func (s *Service) ListSessions(userID uint) ([]Session, error) {
+	var sessions []Session
+	err := s.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// 🚪 RevokeSession — signs one of the user's devices out
+func (s *Service) RevokeSession(userID uint, sessionID string) error {
+	var session Session
+	if err := s.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		First(&session).Error; err != nil {
+		return errors.New("session not found")
+	}
+
+	return s.revokeSession(session.ID)
+}
+
+// RevokeOtherSessions signs out every device except the current one
+func (s *Service) RevokeOtherSessions(userID uint, currentSessionID string) (int, error) {
+	var sessions []Session
+	if err := s.DB.Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?",
+		userID, currentSessionID, time.Now()).Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if err := s.revokeSession(session.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// InvalidateUserTokens bumps the user's token version and revokes every
+// session, so all outstanding access and refresh tokens stop working.
+// Call it on role change, password reset and any admin security action.
+func (s *Service) InvalidateUserTokens(userID uint) error {
+	now := time.Now()
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&RefreshToken{}).Where("user_id = ?", userID).
+			Update("revoked", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.forgetUser(userID)
+	return nil
+}
+
+// startSession records a new login for the device described by ctx. The
+// login itself is the session's first authentication.
+func (s *Service) startSession(ctx context.Context, user *User, session *Session) error {
+	info := requestctx.From(ctx)
+	now := time.Now()
+
+	session.UserID = user.ID
+	session.UserAgent = info.UserAgent
+	session.IPAddress = info.IPAddress
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	session.AuthTime = &now
+	return s.DB.Create(session).Error
+}
+
+// touchSession updates last-seen details on refresh and returns the
+// session, whose authentication time carries over to the new access token.
+// Refresh families that predate session tracking get their session row
+// created here, with no authentication time.
+func (s *Service) touchSession(ctx context.Context, token *RefreshToken) *Session {
+	info := requestctx.From(ctx)
+	session := Session{ID: token.FamilyID}
+
+	result := s.DB.Model(&Session{}).
+		Where("id = ?", token.FamilyID).
+		Updates(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip_address":   info.IPAddress,
+			"user_agent":   info.UserAgent,
+		})
+	if result.Error == nil && result.RowsAffected == 0 {
+		result = s.DB.Create(&Session{
+			ID:         token.FamilyID,
+			UserID:     token.UserID,
+			UserAgent:  info.UserAgent,
+			IPAddress:  info.IPAddress,
+			LastSeenAt: time.Now(),
+			ExpiresAt:  token.ExpiresAt,
+		})
+	}
+	if result.Error != nil {
+		log.Printf("⚠️  Failed to update session %s: %v", token.FamilyID, result.Error)
+	}
+
+	s.DB.Select("id", "auth_time", "acr").Where("id = ?", token.FamilyID).Limit(1).Find(&session)
+	return &session
+}
+
+// revokeSession ends a session: its refresh tokens stop rotating and its
+// access tokens are denylisted until they would have expired
+func (s *Service) revokeSession(sessionID string) error {
+	now := time.Now()
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Session{}).Where("id = ? AND revoked_at IS NULL", sessionID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&RefreshToken{}).Where("family_id = ?", sessionID).
+			Update("revoked", true).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.deny(sessionID, denyKindSession, now.Add(AccessTokenExpiry))
+}
+
+func (s *Service) deny(id, kind string, expiresAt time.Time) error {
+	entry := DeniedToken{ID: id, Kind: kind, ExpiresAt: expiresAt}
+	if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error; err != nil {
+		return err
+	}
+
+	s.sessions.mu.Lock()
+	s.sessions.denied[id] = expiresAt
+	s.sessions.mu.Unlock()
+	return nil
+}
+
+func (s *Service) isDenied(id string) bool {
+	s.syncDenylist()
+
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	expiresAt, ok := s.sessions.denied[id]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// syncDenylist reloads unexpired entries so revocations made by other
+// instances take effect within denylistSyncInterval
+func (s *Service) syncDenylist() {
+	s.sessions.mu.Lock()
+	stale := time.Since(s.sessions.syncedAt) > denylistSyncInterval
+	if stale {
+		// Claim the sync so concurrent requests keep using the cached set
+		s.sessions.syncedAt = time.Now()
+	}
+	s.sessions.mu.Unlock()
+	if !stale {
+		return
+	}
+
+	var entries []DeniedToken
+	if err := s.DB.Where("expires_at > ?", time.Now()).Find(&entries).Error; err != nil {
+		log.Printf("⚠️  Failed to sync token denylist, using cached set: %v", err)
+		return
+	}
+
+	denied := make(map[string]time.Time, len(entries))
+	for _, entry := range entries {
+		denied[entry.ID] = entry.ExpiresAt
+	}
+
+	s.sessions.mu.Lock()
+	s.sessions.denied = denied
+	s.sessions.mu.Unlock()
+
+	s.DB.Where("expires_at < ?", time.Now().Add(-time.Hour)).Delete(&DeniedToken{})
+}
+
+func (s *Service) userState(userID uint) (cachedUser, error) {
+	s.sessions.mu.Lock()
+	state, ok := s.sessions.users[userID]
+	s.sessions.mu.Unlock()
+	if ok && time.Since(state.fetchedAt) < userStateTTL {
+		return state, nil
+	}
+
+	// Soft-deleted users are excluded by GORM, so deletion ends access here
+	var user User
+	if err := s.DB.Select("id", "token_version", "status").First(&user, userID).Error; err != nil {
+		return cachedUser{}, errAccountGone
+	}
+
+	state = cachedUser{
+		version:   user.TokenVersion,
+		status:    user.Status,
+		fetchedAt: time.Now(),
+	}
+
+	s.sessions.mu.Lock()
+	s.sessions.users[userID] = state
+	s.sessions.mu.Unlock()
+	return state, nil
+}
+
+func (s *Service) forgetUser(userID uint) {
+	s.sessions.mu.Lock()
+	delete(s.sessions.users, userID)
+	s.sessions.mu.Unlock()
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/khawsic/health/internal/audit"
+)
+
+// Authentication context classes, carried in the acr claim
+const (
+	ACRPassword    = "pwd" // password only
+	ACRFederated   = "sso" // identity provider that did not report MFA
+	ACRMultiFactor = "mfa" // second factor, passkey, or identity provider MFA
+)
+
+// Ways to satisfy a step-up challenge. Login means signing in again.
+const (
+	StepUpPassword     = "password"
+	StepUpTOTP         = "totp"
+	StepUpRecoveryCode = "recovery_code"
+	StepUpLogin        = "login"
+)
+
+// ⬆️ StepUp — confirms the user's identity again within a session and
+// returns a new access token whose auth_time is now. Users enrolled in MFA
+// must confirm with their second factor; others with their password.
+// Failures count towards lockout like failed logins.
+func (s *Service) StepUp(ctx context.Context, userID uint, sessionID, password, code, recoveryCode string) (string, error) {
+	if sessionID == "" {
+		return "", errors.New("step-up requires a signed-in session")
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return "", err
+	}
+	if err := checkLocked(user); err != nil {
+		return "", err
+	}
+
+	var method, acr string
+	switch {
+	case user.MFAEnabled && recoveryCode != "":
+		method, acr = StepUpRecoveryCode, ACRMultiFactor
+		err = s.useRecoveryCode(user, recoveryCode)
+	case user.MFAEnabled && code != "":
+		method, acr = StepUpTOTP, ACRMultiFactor
+		err = s.checkTOTP(user, code)
+	case user.MFAEnabled:
+		return "", errors.New("confirm with a verification code or recovery code")
+	case password != "" && !user.PasskeyOnly && s.hasPassword(user):
+		method, acr = StepUpPassword, ACRPassword
+		err = s.confirmPassword(ctx, user, password)
+	default:
+		return "", errors.New("sign in again to confirm your identity")
+	}
+	if err != nil {
+		s.recordStepUp(ctx, "STEP_UP_FAILED", method, "")
+		return "", err
+	}
+
+	now := time.Now()
+	result := s.DB.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, user.ID).
+		Updates(map[string]interface{}{
+			"auth_time": now,
+			"acr":       acr,
+		})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", errSessionRevoked
+	}
+
+	s.recordStepUp(ctx, "STEP_UP", method, acr)
+	return s.signAccessToken(user, &Session{ID: sessionID, AuthTime: &now, ACR: acr})
+}
+
+// StepUpMethods lists how the user can answer a step-up challenge, so
+// clients can prompt for the right thing
+func (s *Service) StepUpMethods(ctx context.Context, userID uint) []string {
+	user, err := s.findUser(userID)
+	if err != nil || user.Kind == KindService {
+		return nil
+	}
+
+	switch {
+	case user.MFAEnabled:
+		return []string{StepUpTOTP, StepUpRecoveryCode, StepUpLogin}
+	case user.PasskeyOnly || !s.hasPassword(user):
+		return []string{StepUpLogin}
+	default:
+		return []string{StepUpPassword, StepUpLogin}
+	}
+}
+
+// confirmPassword checks the password against whichever store owns the
+// account, as at login
+func (s *Service) confirmPassword(ctx context.Context, user *User, password string) error {
+	verified, err := s.authenticate(ctx, user.Email, password)
+	if err != nil {
+		if errors.Is(err, errInvalidLogin) {
+			return errors.New("incorrect password")
+		}
+		return err
+	}
+	if verified.ID != user.ID {
+		return errors.New("incorrect password")
+	}
+	return nil
+}
+
+// hasPassword reports whether the user has a password to confirm: a local
+// account, or one linked to a directory rather than only to SSO providers
+func (s *Service) hasPassword(user *User) bool {
+	var providers []string
+	s.DB.Model(&UserIdentity{}).Where("user_id = ?", user.ID).Pluck("provider", &providers)
+	if len(providers) == 0 {
+		return true
+	}
+	for _, a := range s.authenticators {
+		for _, provider := range providers {
+			if a.Name() == provider {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Service) recordStepUp(ctx context.Context, action, method, acr string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	details := map[string]interface{}{"method": method}
+	if acr != "" {
+		details["acr"] = acr
+	}
+	if err := s.auditLog.LogEntry(ctx, audit.Entry{Action: action, Details: details}); err != nil {
+		log.Printf("⚠️  Audit log failed for %s: %v", action, err)
+	}
+}
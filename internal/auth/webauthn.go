@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/khawsic/health/internal/crypto"
+	"gorm.io/gorm"
+)
+
+const (
+	webAuthnCeremonyTimeout = 5 * time.Minute
+	webAuthnHandleSize      = 32
+
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+var errPasskeysDisabled = errors.New("passkey sign-in is not configured")
+
+// EnableWebAuthn configures the relying party used for passkey ceremonies.
+// rpID is the bare domain (e.g. health.example.com) and origins lists every
+// origin the browser may report, scheme included.
+func (s *Service) EnableWebAuthn(rpID, displayName string, origins []string) error {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: displayName,
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: webAuthnCeremonyTimeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: webAuthnCeremonyTimeout},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.webAuthn = w
+	return nil
+}
+
+// 🔑 BeginPasskeyRegistration — returns the creation options for
+// navigator.credentials.create() and the session token for the finish call
+func (s *Service) BeginPasskeyRegistration(userID uint) (*protocol.CredentialCreation, string, error) {
+	if s.webAuthn == nil {
+		return nil, "", errPasskeysDisabled
+	}
+
+	user, err := s.loadPasskeyUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(user.WebAuthnHandle) == 0 {
+		handle := make([]byte, webAuthnHandleSize)
+		if _, err := rand.Read(handle); err != nil {
+			return nil, "", err
+		}
+		if err := s.DB.Model(&user.User).Update("webauthn_handle", handle).Error; err != nil {
+			return nil, "", err
+		}
+		user.WebAuthnHandle = handle
+	}
+
+	// Excluding existing credentials stops the same authenticator being
+	// registered twice
+	creation, session, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.saveWebAuthnSession(&user.ID, ceremonyRegistration, session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return creation, token, nil
+}
+
+// ✅ FinishPasskeyRegistration — verifies the attestation response (the raw
+// PublicKeyCredential JSON from the browser) and stores the new passkey
+func (s *Service) FinishPasskeyRegistration(userID uint, sessionToken, name string, response []byte) (*WebAuthnCredential, error) {
+	if s.webAuthn == nil {
+		return nil, errPasskeysDisabled
+	}
+
+	session, err := s.takeWebAuthnSession(sessionToken, ceremonyRegistration, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, errors.New("malformed passkey response")
+	}
+
+	credential, err := s.webAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		log.Printf("⚠️  Passkey registration failed for user %d: %v", userID, err)
+		return nil, errors.New("passkey verification failed")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	record := WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		Flags:           uint8(credential.Flags.ProtocolValue()),
+		SignCount:       credential.Authenticator.SignCount,
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return nil, errors.New("passkey is already registered")
+	}
+
+	return &record, nil
+}
+
+// 🔑 BeginPasskeyLogin — starts a discoverable (usernameless) assertion.
+// The authenticator picks the account, so no email is needed up front.
+func (s *Service) BeginPasskeyLogin() (*protocol.CredentialAssertion, string, error) {
+	if s.webAuthn == nil {
+		return nil, "", errPasskeysDisabled
+	}
+
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.saveWebAuthnSession(nil, ceremonyLogin, session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return assertion, token, nil
+}
+
+// ✅ FinishPasskeyLogin — verifies the assertion and issues session tokens.
+// A user-verified passkey already combines possession with a PIN or
+// biometric, so it satisfies the per-role MFA policy on its own.
+func (s *Service) FinishPasskeyLogin(ctx context.Context, sessionToken string, response []byte) (*LoginResult, error) {
+	if s.webAuthn == nil {
+		return nil, errPasskeysDisabled
+	}
+
+	session, err := s.takeWebAuthnSession(sessionToken, ceremonyLogin, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, errors.New("malformed passkey response")
+	}
+
+	found, credential, err := s.webAuthn.ValidatePasskeyLogin(s.findPasskeyUser, *session, parsed)
+	if err != nil {
+		log.Printf("⚠️  Passkey login failed: %v", err)
+		return nil, errors.New("passkey verification failed")
+	}
+
+	// The password backoff does not apply: a passkey cannot be guessed,
+	// and honouring it would let anyone lock the user out of both
+	user := found.(*passkeyUser)
+
+	var stored WebAuthnCredential
+	if err := s.DB.Where("user_id = ? AND credential_id = ?", user.ID, credential.ID).
+		First(&stored).Error; err != nil {
+		return nil, errors.New("passkey verification failed")
+	}
+
+	// A counter that failed to advance means two copies of the private key
+	// may exist. The credential is blocked until the user removes it.
+	if stored.CloneWarning || credential.Authenticator.CloneWarning {
+		if !stored.CloneWarning {
+			s.DB.Model(&stored).Update("clone_warning", true)
+			log.Printf("🚨 Possible cloned passkey %d for user %d (stored count %d, presented %d)",
+				stored.ID, user.ID, stored.SignCount, parsed.Response.AuthenticatorData.Counter)
+		}
+		s.recordFailedAttempt(&user.User)
+		return nil, s.recordLoginFailure(ctx, &user.User,
+			errors.New("passkey blocked: possible cloned authenticator — remove it and register a new one"))
+	}
+
+	now := time.Now()
+	if err := s.DB.Model(&stored).Updates(map[string]interface{}{
+		"sign_count":   credential.Authenticator.SignCount,
+		"flags":        uint8(credential.Flags.ProtocolValue()),
+		"last_used_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, &user.User, ACRMultiFactor)
+}
+
+// ListPasskeys returns the user's registered credentials
+func (s *Service) ListPasskeys(userID uint) ([]WebAuthnCredential, error) {
+	var credentials []WebAuthnCredential
+	if err := s.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// 🗑️ DeletePasskey — removes one credential. The last passkey of a
+// passkey-only account cannot be removed, or the user would be locked out.
+func (s *Service) DeletePasskey(userID, credentialID uint) error {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&WebAuthnCredential{}).
+			Where("user_id = ? AND clone_warning = ?", userID, false).
+			Count(&count).Error; err != nil {
+			return err
+		}
+
+		var credential WebAuthnCredential
+		if err := tx.Where("id = ? AND user_id = ?", credentialID, userID).First(&credential).Error; err != nil {
+			return errors.New("passkey not found")
+		}
+
+		if user.PasskeyOnly && !credential.CloneWarning && count <= 1 {
+			return errors.New("cannot remove the last passkey while passkey-only login is enabled")
+		}
+
+		return tx.Delete(&credential).Error
+	})
+}
+
+// 🛡️ SetPasskeyOnly — lets staff turn password login off for their account.
+// Requires at least one usable passkey so the account stays reachable.
+func (s *Service) SetPasskeyOnly(userID uint, enabled bool) error {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	if enabled {
+		// Any staff role may switch password login off; patients keep it
+		if user.Role == "patient" {
+			return errors.New("passkey-only login is available to staff accounts only")
+		}
+
+		var count int64
+		if err := s.DB.Model(&WebAuthnCredential{}).
+			Where("user_id = ? AND clone_warning = ?", userID, false).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("register a passkey before enabling passkey-only login")
+		}
+	}
+
+	return s.DB.Model(&user).Update("passkey_only", enabled).Error
+}
+
+// passkeyUser adapts User to the webauthn.User interface
+type passkeyUser struct {
+	User
+	credentials []WebAuthnCredential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte          { return u.WebAuthnHandle }
+func (u *passkeyUser) WebAuthnName() string        { return u.Email }
+func (u *passkeyUser) WebAuthnDisplayName() string { return u.Name }
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		credentials[i] = c.toWebAuthn()
+	}
+	return credentials
+}
+
+func (c WebAuthnCredential) toWebAuthn() webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	if c.Transports != "" {
+		for _, t := range strings.Split(c.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              c.CredentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(c.Flags)),
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       c.AAGUID,
+			SignCount:    c.SignCount,
+			CloneWarning: c.CloneWarning,
+		},
+	}
+}
+
+func (s *Service) loadPasskeyUser(userID uint) (*passkeyUser, error) {
+	var user User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var credentials []WebAuthnCredential
+	if err := s.DB.Where("user_id = ?", userID).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	return &passkeyUser{User: user, credentials: credentials}, nil
+}
+
+// findPasskeyUser resolves the account from the user handle returned by the
+// authenticator during a discoverable login
+func (s *Service) findPasskeyUser(rawID, userHandle []byte) (webauthn.User, error) {
+	var user User
+	if err := s.DB.Where("webauthn_handle = ?", userHandle).First(&user).Error; err != nil {
+		return nil, errors.New("unknown passkey")
+	}
+
+	passkey, err := s.loadPasskeyUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range passkey.credentials {
+		if bytes.Equal(c.CredentialID, rawID) {
+			return passkey, nil
+		}
+	}
+	return nil, errors.New("unknown passkey")
+}
+
+// saveWebAuthnSession stores the ceremony state server-side and returns the
+// opaque token the client echoes back on the finish call
+func (s *Service) saveWebAuthnSession(userID *uint, ceremony string, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	// Opportunistically clear abandoned ceremonies
+	s.DB.Where("expires_at < ?", time.Now()).Delete(&WebAuthnSession{})
+
+	record := WebAuthnSession{
+		TokenHash: crypto.HashString(token),
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(webAuthnCeremonyTimeout),
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// takeWebAuthnSession loads and deletes a ceremony so each challenge can be
+// answered only once
+func (s *Service) takeWebAuthnSession(token, ceremony string, userID *uint) (*webauthn.SessionData, error) {
+	invalid := errors.New("invalid or expired passkey session — start again")
+
+	var record WebAuthnSession
+	if err := s.DB.Where("token_hash = ? AND ceremony = ?", crypto.HashString(token), ceremony).
+		First(&record).Error; err != nil {
+		return nil, invalid
+	}
+
+	result := s.DB.Delete(&record)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, invalid
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return nil, invalid
+	}
+
+	if userID != nil && (record.UserID == nil || *record.UserID != *userID) {
+		return nil, invalid
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(record.Data), &session); err != nil {
+		return nil, invalid
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8080"
+)
+
+// virtualAuthenticator is a software passkey: one P-256 key with a
+// signature counter the test controls
+type virtualAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+}
+
+func newVirtualAuthenticator(t *testing.T) *virtualAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &virtualAuthenticator{key: key, credentialID: id}
+}
+
+// register answers a creation challenge with a "none" attestation
+func (a *virtualAuthenticator) register(t *testing.T, challenge string) []byte {
+	t.Helper()
+
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.PublicKey.X.FillBytes(x)
+	a.key.PublicKey.Y.FillBytes(y)
+	publicKey, err := cbor.Marshal(map[int]interface{}{1: 2, 3: -7, -1: 1, -2: x, -3: y})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authData := a.authData(0x45, 0) // user present, user verified, attested credential
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    encode(clientData(t, "webauthn.create", challenge)),
+		"attestationObject": encode(attestation),
+	})
+}
+
+// assert answers a login challenge, reporting signCount
+func (a *virtualAuthenticator) assert(t *testing.T, challenge string, userHandle []byte, signCount uint32) []byte {
+	t.Helper()
+
+	data := clientData(t, "webauthn.get", challenge)
+	authData := a.authData(0x05, signCount) // user present, user verified
+	hash := sha256.Sum256(data)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), hash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    encode(data),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(userHandle),
+	})
+}
+
+func (a *virtualAuthenticator) authData(flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func (a *virtualAuthenticator) response(t *testing.T, fields map[string]string) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": fields,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func clientData(t *testing.T, ceremony, challenge string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestPasskeyRegisterLoginAndCloneDetection(t *testing.T) {
+	s := newTestService(t)
+	if err := s.EnableWebAuthn(testRPID, "HealthVault", []string{testOrigin}); err != nil {
+		t.Fatal(err)
+	}
+	user := createUser(t, s, "patient@example.com", "patient")
+	authenticator := newVirtualAuthenticator(t)
+
+	creation, token, err := s.BeginPasskeyRegistration(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := authenticator.register(t, creation.Response.Challenge.String())
+	if _, err := s.FinishPasskeyRegistration(user.ID, token, "Laptop", response); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+
+	if err := s.DB.First(user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(signCount uint32) (*LoginResult, error) {
+		assertion, token, err := s.BeginPasskeyLogin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := authenticator.assert(t, assertion.Response.Challenge.String(), user.WebAuthnHandle, signCount)
+		return s.FinishPasskeyLogin(context.Background(), token, response)
+	}
+
+	result, err := login(5)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if result.AccessToken == "" || result.RefreshToken == "" {
+		t.Fatal("login issued no tokens")
+	}
+
+	var stored WebAuthnCredential
+	s.DB.Where("user_id = ?", user.ID).First(&stored)
+	if stored.SignCount != 5 {
+		t.Fatalf("stored sign count = %d, want 5", stored.SignCount)
+	}
+
+	// A counter that goes backwards means the key may have been copied
+	if _, err := login(3); err == nil || !strings.Contains(err.Error(), "passkey blocked") {
+		t.Fatalf("rolled-back counter: err = %v, want passkey blocked", err)
+	}
+	s.DB.First(&stored, stored.ID)
+	if !stored.CloneWarning {
+		t.Fatal("rolled-back counter did not flag the credential")
+	}
+
+	// The flag sticks even when the counter moves forward again
+	if _, err := login(9); err == nil || !strings.Contains(err.Error(), "passkey blocked") {
+		t.Fatalf("flagged credential: err = %v, want passkey blocked", err)
+	}
+}
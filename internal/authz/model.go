@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Role is a named set of permissions. Users hold exactly one role.
+type Role struct {
+	Name        string    `gorm:"primaryKey;size:50" json:"name"`
+	Description string    `json:"description"`
+	BuiltIn     bool      `gorm:"not null;default:false" json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RolePermission grants Permission to everyone holding Role
+type RolePermission struct {
+	Role       string `gorm:"primaryKey;size:50"`
+	Permission string `gorm:"primaryKey;size:100"`
+}
+
+// SeededGrant remembers that a built-in role received a default
+// permission, so it is not granted again after an administrator removes it
+type SeededGrant struct {
+	Role       string `gorm:"primaryKey;size:50"`
+	Permission string `gorm:"primaryKey;size:100"`
+	CreatedAt  time.Time
+}
+
+func (SeededGrant) TableName() string {
+	return "role_permission_seeds"
+}
+
+// Policy effects. Deny policies override every grant.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Policy adds attribute conditions on top of role grants. It applies to a
+// request when the caller's role is in Roles (or Roles is empty) and the
+// permission matches one of Permissions, which may end in "*" (record:*).
+// An applicable policy matches when every condition holds: a matching deny
+// policy refuses the request, a matching allow policy grants a permission
+// the role lacks.
+type Policy struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"uniqueIndex;not null" json:"name"`
+	Description string     `json:"description"`
+	Effect      string     `gorm:"size:10;not null" json:"effect"`
+	Roles       StringList `gorm:"type:text;not null" json:"roles"`
+	Permissions StringList `gorm:"type:text;not null" json:"permissions"`
+	Conditions  Conditions `gorm:"type:text;not null" json:"conditions"`
+	Enabled     bool       `gorm:"not null;default:true" json:"enabled"`
+	UpdatedBy   uint       `json:"updated_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (Policy) TableName() string {
+	return "access_policies"
+}
+
+// Conditions are the attribute checks of a policy. Empty fields are not
+// checked.
+type Conditions struct {
+	// Departments the caller must belong to
+	Departments []string `json:"departments,omitempty"`
+
+	// SameDepartment compares the patient's department with the caller's:
+	// true requires them to match, false requires them to differ. Only
+	// patient-scoped permissions can use it.
+	SameDepartment *bool `json:"same_department,omitempty"`
+
+	// Networks the request must come from, and networks it must not come
+	// from, as CIDR blocks
+	Networks        []string `json:"networks,omitempty"`
+	ExcludeNetworks []string `json:"exclude_networks,omitempty"`
+
+	// Days (mon … sun) and a From–To window ("HH:MM", 24-hour) the request
+	// must fall in. A window whose end is before its start spans midnight.
+	Days     []string `json:"days,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to,omitempty"`
+	Timezone string   `json:"timezone,omitempty"` // IANA name, default UTC
+}
+
+// StringList is stored as a JSON array in a text column
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		l = StringList{}
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+func (c Conditions) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *Conditions) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
+
+func scanJSON(value interface{}, target interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), target)
+	case []byte:
+		return json.Unmarshal(v, target)
+	default:
+		return errors.New("unsupported JSON column value")
+	}
+}
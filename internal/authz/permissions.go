@@ -0,0 +1,110 @@
+package authz
+
+// Permissions name an action on a kind of resource as "resource:action".
+// Roles are granted permissions; routes and services check them.
+const (
+	PermRecordRead      = "record:read"
+	PermRecordReadOwn   = "record:read_own"
+	PermRecordReadAll   = "record:read_all"
+	PermRecordHistory   = "record:history"
+	PermRecordWrite     = "record:write"
+	PermRecordDelete    = "record:delete"
+	PermRecordEmergency = "record:emergency"
+
+	PermCareTeamRead    = "care_team:read"
+	PermCareTeamRequest = "care_team:request"
+	PermCareTeamManage  = "care_team:manage"
+
+	PermConsentManage = "consent:manage"
+
+	PermProxyRequest = "proxy:request"
+	PermProxyVerify  = "proxy:verify"
+
+	PermAuditRead   = "audit:read"
+	PermAuditExport = "audit:export"
+
+	PermUserRead                = "user:read"
+	PermUserManage              = "user:manage"
+	PermDoctorApplicationReview = "doctor_application:review"
+	PermMFAPolicyManage         = "mfa_policy:manage"
+	PermKeyManage               = "key:manage"
+	PermRoleManage              = "role:manage"
+	PermPolicyManage            = "policy:manage"
+	PermServiceAccountManage    = "service_account:manage"
+)
+
+// PermissionInfo describes a permission for the admin API
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// PatientScoped permissions are checked against a patient, so policies
+	// on them may compare the patient's department with the caller's
+	PatientScoped bool `json:"patient_scoped"`
+}
+
+// Catalogue lists every permission the application checks
+var Catalogue = []PermissionInfo{
+	{PermRecordRead, "Read a patient's medical records", true},
+	{PermRecordReadOwn, "Read one's own medical records", true},
+	{PermRecordReadAll, "List every medical record in the system", true},
+	{PermRecordHistory, "Read the version history of a record", true},
+	{PermRecordWrite, "Create and update medical records", true},
+	{PermRecordDelete, "Delete medical records", true},
+	{PermRecordEmergency, "Break-glass access to a single record", true},
+	{PermCareTeamRead, "See one's own care assignments", false},
+	{PermCareTeamRequest, "Ask to join a patient's care team and leave it", false},
+	{PermCareTeamManage, "Assign clinicians to patients and review assignment requests", false},
+	{PermConsentManage, "Grant and revoke consent to one's own records and see how it is used", false},
+	{PermProxyRequest, "Ask to act for a dependent patient and act for them once verified", false},
+	{PermProxyVerify, "Verify, decline and revoke proxy relationships", false},
+	{PermAuditRead, "Read, search and verify the audit log", false},
+	{PermAuditExport, "Export the audit log", false},
+	{PermUserRead, "List and view user accounts", false},
+	{PermUserManage, "Unlock, disable, enable, offboard and delete accounts, and change their role or department", false},
+	{PermDoctorApplicationReview, "Approve or reject doctor registrations", false},
+	{PermMFAPolicyManage, "Set per-role MFA requirements", false},
+	{PermKeyManage, "List and rotate access token signing keys", false},
+	{PermRoleManage, "Define roles and the permissions they grant", false},
+	{PermPolicyManage, "Define attribute policies and explain other users' access", false},
+	{PermServiceAccountManage, "Create service accounts for integrations and issue and revoke their API keys", false},
+}
+
+func lookupPermission(name string) (PermissionInfo, bool) {
+	for _, info := range Catalogue {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return PermissionInfo{}, false
+}
+
+// builtInRoles are created on first start. Each default permission is
+// granted once, so permissions added in later versions reach existing
+// roles while grants an administrator removed stay removed.
+var builtInRoles = []struct {
+	name        string
+	description string
+	permissions []string
+}{
+	{"admin", "System administrator", []string{
+		PermRecordReadAll, PermAuditRead, PermAuditExport, PermUserRead, PermUserManage,
+		PermDoctorApplicationReview, PermMFAPolicyManage, PermKeyManage, PermRoleManage, PermPolicyManage,
+		PermCareTeamRead, PermCareTeamManage, PermProxyVerify, PermServiceAccountManage,
+	}},
+	{"doctor", "Treating physician", []string{
+		PermRecordRead, PermRecordHistory, PermRecordWrite, PermRecordDelete, PermRecordEmergency,
+		PermCareTeamRead, PermCareTeamRequest,
+	}},
+	{"patient", "Patient with access to their own records", []string{PermRecordReadOwn, PermConsentManage, PermProxyRequest}},
+	{"nurse", "Nursing staff", []string{PermRecordRead, PermRecordHistory, PermCareTeamRead, PermCareTeamRequest}},
+	{"pharmacist", "Dispensing pharmacist", []string{PermRecordRead, PermCareTeamRead, PermCareTeamRequest}},
+	{"auditor", "Read-only compliance auditor", []string{PermAuditRead, PermAuditExport, PermUserRead}},
+	{"researcher", "Researcher — no patient data until permissions or policies grant it", nil},
+}
+
+// KnownPermission reports whether name is in the catalogue
+func KnownPermission(name string) bool {
+	_, ok := lookupPermission(name)
+	return ok
+}
@@ -0,0 +1,274 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Step results in a decision trace
+const (
+	ResultGranted    = "granted"
+	ResultNotGranted = "not_granted"
+	ResultMatched    = "matched"
+	ResultNotMatched = "not_matched"
+	ResultDeferred   = "deferred"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Subject is the caller of a request
+type Subject struct {
+	UserID     uint   `json:"user_id"`
+	Role       string `json:"role"`
+	Department string `json:"department"`
+}
+
+// Resource is the patient a request concerns
+type Resource struct {
+	PatientID  uint   `json:"patient_id"`
+	Department string `json:"department"`
+}
+
+// Request is everything a decision depends on. A nil Resource means the
+// patient is not known yet, as at the route; conditions on it are deferred
+// to the check the service makes once it has loaded the patient.
+type Request struct {
+	Subject    Subject   `json:"subject"`
+	Permission string    `json:"permission"`
+	Resource   *Resource `json:"resource,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	Time       time.Time `json:"time"`
+}
+
+// compiledPolicy is a Policy with its conditions parsed once at load time
+type compiledPolicy struct {
+	Policy
+	networks []*net.IPNet
+	excluded []*net.IPNet
+	days     map[time.Weekday]bool
+	from, to int // minutes after midnight; from < 0 means no window
+	location *time.Location
+}
+
+func compilePolicy(p Policy) (*compiledPolicy, error) {
+	if strings.TrimSpace(p.Name) == "" {
+		return nil, errors.New("policy name is required")
+	}
+	if p.Effect != EffectAllow && p.Effect != EffectDeny {
+		return nil, errors.New("effect must be allow or deny")
+	}
+	if len(p.Permissions) == 0 {
+		return nil, errors.New("a policy needs at least one permission")
+	}
+
+	patientScoped := true
+	for _, pattern := range p.Permissions {
+		matchedAny := false
+		for _, info := range Catalogue {
+			if permissionMatches(pattern, info.Name) {
+				matchedAny = true
+				patientScoped = patientScoped && info.PatientScoped
+			}
+		}
+		if !matchedAny {
+			return nil, fmt.Errorf("unknown permission %q", pattern)
+		}
+	}
+
+	c := p.Conditions
+	if c.SameDepartment != nil && !patientScoped {
+		return nil, errors.New("same_department only applies to patient-scoped permissions")
+	}
+
+	compiled := &compiledPolicy{Policy: p, from: -1, location: time.UTC}
+
+	var err error
+	if compiled.networks, err = parseNetworks(c.Networks); err != nil {
+		return nil, err
+	}
+	if compiled.excluded, err = parseNetworks(c.ExcludeNetworks); err != nil {
+		return nil, err
+	}
+
+	if len(c.Days) > 0 {
+		compiled.days = make(map[time.Weekday]bool, len(c.Days))
+		for _, day := range c.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("unknown day %q — use mon, tue, wed, thu, fri, sat or sun", day)
+			}
+			compiled.days[weekday] = true
+		}
+	}
+
+	if c.From != "" || c.To != "" {
+		if compiled.from, err = parseClock(c.From); err != nil {
+			return nil, err
+		}
+		if compiled.to, err = parseClock(c.To); err != nil {
+			return nil, err
+		}
+		if compiled.from == compiled.to {
+			return nil, errors.New("from and to must differ")
+		}
+	}
+
+	if c.Timezone != "" {
+		if compiled.location, err = time.LoadLocation(c.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", c.Timezone)
+		}
+	}
+
+	return compiled, nil
+}
+
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", cidr)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q — use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// permissionMatches reports whether pattern ("record:read", "record:*" or
+// "*") covers permission
+func permissionMatches(pattern, permission string) bool {
+	if pattern == "*" || pattern == permission {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	return ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(permission, prefix)
+}
+
+func (p *compiledPolicy) appliesTo(role, permission string) bool {
+	if len(p.Roles) > 0 && !contains(p.Roles, role) {
+		return false
+	}
+	for _, pattern := range p.Permissions {
+		if permissionMatches(pattern, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate checks every condition against req. It returns the result and
+// a description of the first condition that failed, or of all that held.
+func (p *compiledPolicy) evaluate(req *Request) (string, string) {
+	var held []string
+	c := p.Conditions
+
+	if len(c.Departments) > 0 {
+		if !containsFold(c.Departments, req.Subject.Department) {
+			return ResultNotMatched, fmt.Sprintf("department %q is not one of %s", req.Subject.Department, strings.Join(c.Departments, ", "))
+		}
+		held = append(held, fmt.Sprintf("department %q is one of %s", req.Subject.Department, strings.Join(c.Departments, ", ")))
+	}
+
+	if len(p.networks) > 0 || len(p.excluded) > 0 {
+		ip := net.ParseIP(req.IPAddress)
+		if ip == nil {
+			return ResultNotMatched, "source address is unknown"
+		}
+		if len(p.networks) > 0 {
+			if !inNetworks(ip, p.networks) {
+				return ResultNotMatched, fmt.Sprintf("source %s is outside %s", ip, strings.Join(c.Networks, ", "))
+			}
+			held = append(held, fmt.Sprintf("source %s is inside %s", ip, strings.Join(c.Networks, ", ")))
+		}
+		if len(p.excluded) > 0 {
+			if inNetworks(ip, p.excluded) {
+				return ResultNotMatched, fmt.Sprintf("source %s is inside excluded %s", ip, strings.Join(c.ExcludeNetworks, ", "))
+			}
+			held = append(held, fmt.Sprintf("source %s is outside %s", ip, strings.Join(c.ExcludeNetworks, ", ")))
+		}
+	}
+
+	now := req.Time.In(p.location)
+	if p.days != nil {
+		if !p.days[now.Weekday()] {
+			return ResultNotMatched, fmt.Sprintf("%s is not one of %s", now.Weekday(), strings.Join(c.Days, ", "))
+		}
+		held = append(held, fmt.Sprintf("%s is one of %s", now.Weekday(), strings.Join(c.Days, ", ")))
+	}
+	if p.from >= 0 {
+		minute := now.Hour()*60 + now.Minute()
+		inside := minute >= p.from && minute < p.to
+		if p.to < p.from {
+			inside = minute >= p.from || minute < p.to
+		}
+		clock := now.Format("15:04 MST")
+		if !inside {
+			return ResultNotMatched, fmt.Sprintf("%s is outside %s–%s", clock, c.From, c.To)
+		}
+		held = append(held, fmt.Sprintf("%s is within %s–%s", clock, c.From, c.To))
+	}
+
+	if c.SameDepartment != nil {
+		if req.Resource == nil {
+			return ResultDeferred, "needs the patient's department — checked again once the patient is known"
+		}
+		same := req.Subject.Department != "" && strings.EqualFold(req.Subject.Department, req.Resource.Department)
+		if same != *c.SameDepartment {
+			if same {
+				return ResultNotMatched, fmt.Sprintf("patient is in the caller's department %q", req.Subject.Department)
+			}
+			return ResultNotMatched, fmt.Sprintf("patient's department %q differs from the caller's %q", req.Resource.Department, req.Subject.Department)
+		}
+		if same {
+			held = append(held, fmt.Sprintf("patient is in the caller's department %q", req.Subject.Department))
+		} else {
+			held = append(held, fmt.Sprintf("patient's department %q differs from the caller's %q", req.Resource.Department, req.Subject.Department))
+		}
+	}
+
+	if len(held) == 0 {
+		return ResultMatched, "no conditions"
+	}
+	return ResultMatched, strings.Join(held, "; ")
+}
+
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if value != "" && strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,742 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khawsic/health/internal/audit"
+	"github.com/khawsic/health/internal/requestctx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// stateTTL bounds how long a role, policy or department change made on
+// another instance can go unnoticed
+const stateTTL = 10 * time.Second
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+
+	errPolicyNotFound = errors.New("policy not found")
+	errUserNotFound   = errors.New("user not found")
+
+	roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+)
+
+// Decision is the outcome of a permission check together with the steps
+// that led to it, so a refusal can be explained
+type Decision struct {
+	Allowed    bool    `json:"allowed"`
+	Permission string  `json:"permission"`
+	Reason     string  `json:"reason"`
+	Request    Request `json:"request"`
+	Steps      []Step  `json:"steps"`
+}
+
+// Step is one role grant or policy considered for a decision
+type Step struct {
+	Source string `json:"source"` // role or policy
+	Name   string `json:"name"`
+	Effect string `json:"effect,omitempty"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DeniedError is returned by services when a permission check fails
+type DeniedError struct {
+	Decision Decision
+}
+
+func (e *DeniedError) Error() string {
+	return "access denied: " + e.Decision.Reason
+}
+
+// RoleSummary is a role with its permissions and how many users hold it
+type RoleSummary struct {
+	Role
+	Permissions []string `json:"permissions"`
+	Users       int64    `json:"users"`
+}
+
+type cachedSubject struct {
+	role       string
+	department string
+	fetchedAt  time.Time
+}
+
+// Service decides whether a caller may use a permission. Roles and
+// policies live in the database and are cached in memory.
+type Service struct {
+	db       *gorm.DB
+	auditLog *audit.Service
+
+	mu       sync.RWMutex
+	roles    map[string]map[string]bool
+	policies []*compiledPolicy
+	loadedAt time.Time
+
+	subjectsMu sync.Mutex
+	subjects   map[uint]cachedSubject
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		db:       db,
+		roles:    make(map[string]map[string]bool),
+		subjects: make(map[uint]cachedSubject),
+	}
+}
+
+// Migrate creates the role and policy tables and the built-in roles, and
+// grants built-in roles the default permissions they have not had yet
+func (s *Service) Migrate() error {
+	if err := s.db.AutoMigrate(&Role{}, &RolePermission{}, &SeededGrant{}, &Policy{}); err != nil {
+		return err
+	}
+
+	for _, builtIn := range builtInRoles {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&Role{Name: builtIn.name, Description: builtIn.description, BuiltIn: true})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				log.Printf("👥 Created built-in role %s", builtIn.name)
+			}
+
+			for _, permission := range builtIn.permissions {
+				seeded := tx.Clauses(clause.OnConflict{DoNothing: true}).
+					Create(&SeededGrant{Role: builtIn.name, Permission: permission})
+				if seeded.Error != nil {
+					return seeded.Error
+				}
+				if seeded.RowsAffected == 0 {
+					continue
+				}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+					Create(&RolePermission{Role: builtIn.name, Permission: permission}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseAuditLog records refusals and role and policy changes on the audit
+// chain
+func (s *Service) UseAuditLog(auditService *audit.Service) {
+	s.auditLog = auditService
+}
+
+// Load reads roles and policies into memory. A policy that no longer
+// compiles is skipped with a warning rather than blocking every check.
+func (s *Service) Load() error {
+	var grants []RolePermission
+	if err := s.db.Find(&grants).Error; err != nil {
+		return err
+	}
+	var names []string
+	if err := s.db.Model(&Role{}).Pluck("name", &names).Error; err != nil {
+		return err
+	}
+	var stored []Policy
+	if err := s.db.Where("enabled = ?", true).Order("id ASC").Find(&stored).Error; err != nil {
+		return err
+	}
+
+	roles := make(map[string]map[string]bool, len(names))
+	for _, name := range names {
+		roles[name] = make(map[string]bool)
+	}
+	for _, grant := range grants {
+		if roles[grant.Role] != nil {
+			roles[grant.Role][grant.Permission] = true
+		}
+	}
+
+	policies := make([]*compiledPolicy, 0, len(stored))
+	for _, policy := range stored {
+		compiled, err := compilePolicy(policy)
+		if err != nil {
+			log.Printf("⚠️  Skipping access policy %q: %v", policy.Name, err)
+			continue
+		}
+		policies = append(policies, compiled)
+	}
+
+	s.mu.Lock()
+	s.roles = roles
+	s.policies = policies
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Service) reloadIfStale() {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > stateTTL
+	s.mu.RUnlock()
+	if !stale {
+		return
+	}
+	if err := s.Load(); err != nil {
+		log.Printf("⚠️  Failed to reload roles and policies, using cached set: %v", err)
+	}
+}
+
+// RoleExists reports whether name is a defined role
+func (s *Service) RoleExists(name string) bool {
+	s.reloadIfStale()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.roles[name] != nil
+}
+
+// RolePermissions lists what role grants, sorted
+func (s *Service) RolePermissions(role string) []string {
+	s.reloadIfStale()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	permissions := make([]string, 0, len(s.roles[role]))
+	for permission := range s.roles[role] {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// Evaluate decides req against the cached roles and policies. Deny
+// policies win over every grant; otherwise the role's grants apply, then
+// allow policies.
+func (s *Service) Evaluate(req Request) Decision {
+	s.reloadIfStale()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	decision := Decision{Permission: req.Permission, Request: req}
+	role := req.Subject.Role
+
+	grants, roleKnown := s.roles[role]
+	granted := grants[req.Permission]
+	roleStep := Step{Source: "role", Name: role, Result: ResultNotGranted}
+	if granted {
+		roleStep.Result = ResultGranted
+	}
+	if !roleKnown {
+		roleStep.Detail = "role is not defined"
+	}
+	decision.Steps = append(decision.Steps, roleStep)
+
+	deniedBy, allowedBy := -1, -1
+	for _, policy := range s.policies {
+		if !policy.appliesTo(role, req.Permission) {
+			continue
+		}
+		result, detail := policy.evaluate(&req)
+		decision.Steps = append(decision.Steps, Step{
+			Source: "policy",
+			Name:   policy.Name,
+			Effect: policy.Effect,
+			Result: result,
+			Detail: detail,
+		})
+
+		switch {
+		case policy.Effect == EffectDeny && result == ResultMatched && deniedBy < 0:
+			deniedBy = len(decision.Steps) - 1
+		case policy.Effect == EffectAllow && result != ResultNotMatched && allowedBy < 0:
+			allowedBy = len(decision.Steps) - 1
+		}
+	}
+
+	switch {
+	case deniedBy >= 0:
+		step := decision.Steps[deniedBy]
+		decision.Reason = fmt.Sprintf("policy %q denies %s: %s", step.Name, req.Permission, step.Detail)
+	case granted:
+		decision.Allowed = true
+		decision.Reason = fmt.Sprintf("role %q grants %s", role, req.Permission)
+	case allowedBy >= 0 && decision.Steps[allowedBy].Result == ResultDeferred:
+		decision.Allowed = true
+		decision.Reason = fmt.Sprintf("policy %q may allow %s once the patient is known", decision.Steps[allowedBy].Name, req.Permission)
+	case allowedBy >= 0:
+		step := decision.Steps[allowedBy]
+		decision.Allowed = true
+		decision.Reason = fmt.Sprintf("policy %q allows %s: %s", step.Name, req.Permission, step.Detail)
+	case !roleKnown:
+		decision.Reason = fmt.Sprintf("role %q is not defined", role)
+	default:
+		decision.Reason = fmt.Sprintf("role %q does not grant %s", role, req.Permission)
+	}
+	return decision
+}
+
+// Check decides whether the caller in ctx may use permission. A nil
+// resource defers patient conditions, as the route check does; services
+// pass the patient once they have loaded it. Refusals are audited.
+func (s *Service) Check(ctx context.Context, permission string, resource *Resource) Decision {
+	decision := s.decide(ctx, permission, resource)
+	if !decision.Allowed {
+		s.recordDenial(ctx, decision)
+	}
+	return decision
+}
+
+// Require is Check for services: it always has the resource and returns a
+// *DeniedError when the caller is refused
+func (s *Service) Require(ctx context.Context, permission string, resource Resource) error {
+	decision := s.Check(ctx, permission, &resource)
+	if !decision.Allowed {
+		return &DeniedError{Decision: decision}
+	}
+	return nil
+}
+
+// ExplainSelf shows how a check for the caller in ctx would be decided
+// right now, without auditing it as a refusal
+func (s *Service) ExplainSelf(ctx context.Context, permission string, resource *Resource) Decision {
+	return s.decide(ctx, permission, resource)
+}
+
+// Explain shows how a check for another user would be decided, from the
+// given source address and time
+func (s *Service) Explain(userID uint, permission string, resource *Resource, ipAddress string, at time.Time) (Decision, error) {
+	subject, err := s.subject(userID, "")
+	if err != nil {
+		return Decision{}, err
+	}
+	if err := s.resolve(resource); err != nil {
+		return Decision{}, err
+	}
+	return s.Evaluate(Request{
+		Subject:    subject,
+		Permission: permission,
+		Resource:   resource,
+		IPAddress:  ipAddress,
+		Time:       at,
+	}), nil
+}
+
+func (s *Service) decide(ctx context.Context, permission string, resource *Resource) Decision {
+	info := requestctx.From(ctx)
+	req := Request{
+		Subject:    Subject{UserID: info.UserID, Role: info.Role},
+		Permission: permission,
+		Resource:   resource,
+		IPAddress:  info.IPAddress,
+		Time:       time.Now(),
+	}
+
+	// The role comes from the token; the department is looked up so an
+	// administrator's change applies without signing the user out
+	if info.UserID != 0 {
+		subject, err := s.subject(info.UserID, info.Role)
+		if err != nil {
+			return Decision{Permission: permission, Request: req, Reason: "caller could not be identified"}
+		}
+		req.Subject = subject
+	}
+	if err := s.resolve(resource); err != nil {
+		return Decision{Permission: permission, Request: req, Reason: "patient could not be identified"}
+	}
+
+	// An API key narrows its service account's role to the key's scopes
+	if info.APIKeyID != 0 && !hasScope(info.Scopes, permission) {
+		return Decision{
+			Permission: permission,
+			Request:    req,
+			Reason:     fmt.Sprintf("API key scopes do not include %s", permission),
+			Steps: []Step{{
+				Source: "api_key",
+				Name:   fmt.Sprint(info.APIKeyID),
+				Result: ResultNotGranted,
+			}},
+		}
+	}
+
+	return s.Evaluate(req)
+}
+
+func hasScope(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve fills in the patient's department
+func (s *Service) resolve(resource *Resource) error {
+	if resource == nil || resource.PatientID == 0 || resource.Department != "" {
+		return nil
+	}
+	patient, err := s.subject(resource.PatientID, "")
+	if err != nil {
+		return err
+	}
+	resource.Department = patient.Department
+	return nil
+}
+
+// subject loads a user's role and department. A non-empty role overrides
+// the stored one.
+func (s *Service) subject(userID uint, role string) (Subject, error) {
+	s.subjectsMu.Lock()
+	cached, ok := s.subjects[userID]
+	s.subjectsMu.Unlock()
+
+	if !ok || time.Since(cached.fetchedAt) > stateTTL {
+		var row struct {
+			Role       string
+			Department string
+		}
+		result := s.db.Table("users").
+			Select("role", "department").
+			Where("id = ? AND deleted_at IS NULL", userID).
+			Scan(&row)
+		if result.Error != nil {
+			return Subject{}, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return Subject{}, errUserNotFound
+		}
+
+		cached = cachedSubject{role: row.Role, department: row.Department, fetchedAt: time.Now()}
+		s.subjectsMu.Lock()
+		s.subjects[userID] = cached
+		s.subjectsMu.Unlock()
+	}
+
+	if role == "" {
+		role = cached.role
+	}
+	return Subject{UserID: userID, Role: role, Department: cached.department}, nil
+}
+
+// ForgetUser drops cached attributes after a user's role or department
+// changes
+func (s *Service) ForgetUser(userID uint) {
+	s.subjectsMu.Lock()
+	delete(s.subjects, userID)
+	s.subjectsMu.Unlock()
+}
+
+func (s *Service) recordDenial(ctx context.Context, decision Decision) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Action: "ACCESS_DENIED",
+		Details: map[string]interface{}{
+			"permission": decision.Permission,
+			"reason":     decision.Reason,
+		},
+	}
+	if resource := decision.Request.Resource; resource != nil && resource.PatientID != 0 {
+		entry.PatientID = &resource.PatientID
+	}
+	if err := s.auditLog.LogEntry(ctx, entry); err != nil {
+		log.Printf("⚠️  Audit log failed for ACCESS_DENIED: %v", err)
+	}
+}
+
+func (s *Service) recordChange(ctx context.Context, action string, details map[string]interface{}) {
+	if s.auditLog == nil {
+		return
+	}
+	if err := s.auditLog.LogEntry(ctx, audit.Entry{Action: action, Details: details}); err != nil {
+		log.Printf("⚠️  Audit log failed for %s: %v", action, err)
+	}
+}
+
+// =========================
+// ROLES
+// =========================
+
+// ListRoles returns every role with its permissions and holder count
+func (s *Service) ListRoles() ([]RoleSummary, error) {
+	var roles []Role
+	if err := s.db.Order("name ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	var grants []RolePermission
+	if err := s.db.Order("permission ASC").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	byRole := make(map[string][]string)
+	for _, grant := range grants {
+		byRole[grant.Role] = append(byRole[grant.Role], grant.Permission)
+	}
+
+	var counts []struct {
+		Role  string
+		Count int64
+	}
+	if err := s.db.Table("users").
+		Select("role, COUNT(*) AS count").
+		Where("deleted_at IS NULL").
+		Group("role").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	holders := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		holders[count.Role] = count.Count
+	}
+
+	summaries := make([]RoleSummary, len(roles))
+	for i, role := range roles {
+		permissions := byRole[role.Name]
+		if permissions == nil {
+			permissions = []string{}
+		}
+		summaries[i] = RoleSummary{Role: role, Permissions: permissions, Users: holders[role.Name]}
+	}
+	return summaries, nil
+}
+
+// ➕ CreateRole — defines a new role
+func (s *Service) CreateRole(ctx context.Context, name, description string, permissions []string) error {
+	if !roleNamePattern.MatchString(name) {
+		return errors.New("role name must be 2–50 lowercase letters, digits, dashes or underscores")
+	}
+	if err := validatePermissions(permissions); err != nil {
+		return err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&Role{}).Where("name = ?", name).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errors.New("a role with this name already exists")
+		}
+		if err := tx.Create(&Role{Name: name, Description: description}).Error; err != nil {
+			return err
+		}
+		return grant(tx, name, permissions)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordChange(ctx, "CREATE_ROLE", map[string]interface{}{
+		"role":        name,
+		"permissions": permissions,
+	})
+	return s.Load()
+}
+
+// ✏️ UpdateRole — replaces a role's description and permissions. Callers
+// cannot take role:manage away from their own role.
+func (s *Service) UpdateRole(ctx context.Context, name, description string, permissions []string) error {
+	if err := validatePermissions(permissions); err != nil {
+		return err
+	}
+	if requestctx.From(ctx).Role == name && !contains(permissions, PermRoleManage) {
+		return errors.New("you cannot remove role:manage from your own role")
+	}
+
+	previous := s.RolePermissions(name)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Role{}).Where("name = ?", name).Update("description", description)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUnknownRole
+		}
+		if err := tx.Where("role = ?", name).Delete(&RolePermission{}).Error; err != nil {
+			return err
+		}
+		return grant(tx, name, permissions)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordChange(ctx, "UPDATE_ROLE", map[string]interface{}{
+		"role":                 name,
+		"previous_permissions": previous,
+		"permissions":          permissions,
+	})
+	return s.Load()
+}
+
+// 🗑️ DeleteRole — removes a custom role nobody holds
+func (s *Service) DeleteRole(ctx context.Context, name string) error {
+	var role Role
+	if err := s.db.Where("name = ?", name).First(&role).Error; err != nil {
+		return ErrUnknownRole
+	}
+	if role.BuiltIn {
+		return errors.New("built-in roles cannot be deleted")
+	}
+
+	var holders int64
+	if err := s.db.Table("users").Where("role = ? AND deleted_at IS NULL", name).Count(&holders).Error; err != nil {
+		return err
+	}
+	if holders > 0 {
+		return fmt.Errorf("%d user(s) still hold this role", holders)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", name).Delete(&RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordChange(ctx, "DELETE_ROLE", map[string]interface{}{"role": name})
+	return s.Load()
+}
+
+func validatePermissions(permissions []string) error {
+	seen := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		if _, ok := lookupPermission(permission); !ok {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+		if seen[permission] {
+			return fmt.Errorf("permission %q is listed twice", permission)
+		}
+		seen[permission] = true
+	}
+	return nil
+}
+
+func grant(tx *gorm.DB, role string, permissions []string) error {
+	for _, permission := range permissions {
+		if err := tx.Create(&RolePermission{Role: role, Permission: permission}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =========================
+// POLICIES
+// =========================
+
+// ListPolicies returns every policy, disabled ones included
+func (s *Service) ListPolicies() ([]Policy, error) {
+	var policies []Policy
+	if err := s.db.Order("id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// ➕ CreatePolicy — validates and stores a new policy
+func (s *Service) CreatePolicy(ctx context.Context, adminID uint, policy Policy) (*Policy, error) {
+	policy.ID = 0
+	policy.UpdatedBy = adminID
+	if err := s.validatePolicy(&policy); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&policy).Error; err != nil {
+		return nil, err
+	}
+
+	s.recordChange(ctx, "CREATE_POLICY", map[string]interface{}{
+		"policy_id": policy.ID,
+		"policy":    policy,
+	})
+	return &policy, s.Load()
+}
+
+// ✏️ UpdatePolicy — replaces a policy's definition
+func (s *Service) UpdatePolicy(ctx context.Context, adminID, policyID uint, policy Policy) (*Policy, error) {
+	var existing Policy
+	if err := s.db.First(&existing, policyID).Error; err != nil {
+		return nil, errPolicyNotFound
+	}
+
+	policy.ID = existing.ID
+	policy.CreatedAt = existing.CreatedAt
+	policy.UpdatedBy = adminID
+	if err := s.validatePolicy(&policy); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, err
+	}
+
+	s.recordChange(ctx, "UPDATE_POLICY", map[string]interface{}{
+		"policy_id": policy.ID,
+		"previous":  existing,
+		"policy":    policy,
+	})
+	return &policy, s.Load()
+}
+
+// 🗑️ DeletePolicy — removes a policy
+func (s *Service) DeletePolicy(ctx context.Context, policyID uint) error {
+	var existing Policy
+	if err := s.db.First(&existing, policyID).Error; err != nil {
+		return errPolicyNotFound
+	}
+	if err := s.db.Delete(&existing).Error; err != nil {
+		return err
+	}
+
+	s.recordChange(ctx, "DELETE_POLICY", map[string]interface{}{
+		"policy_id": existing.ID,
+		"policy":    existing,
+	})
+	return s.Load()
+}
+
+func (s *Service) validatePolicy(policy *Policy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	for i, department := range policy.Conditions.Departments {
+		policy.Conditions.Departments[i] = NormalizeDepartment(department)
+	}
+	if _, err := compilePolicy(*policy); err != nil {
+		return err
+	}
+
+	var taken int64
+	if err := s.db.Model(&Policy{}).Where("name = ? AND id <> ?", policy.Name, policy.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errors.New("a policy with this name already exists")
+	}
+	for _, role := range policy.Roles {
+		if !s.RoleExists(role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+	return nil
+}
+
+// NormalizeDepartment gives departments one spelling so comparisons do
+// not depend on case or stray spaces
+func NormalizeDepartment(department string) string {
+	return strings.ToLower(strings.TrimSpace(department))
+}
@@ -8,12 +8,52 @@ import (
 )
 
 type Config struct {
-	DBUrl            string
-	JWTSecret        string
-	Port             string
-	EncryptionKey    string
-	ED25519PrivateKey string
-	ED25519PublicKey  string
+	DBUrl                 string
+	AuditDBUrl            string
+	JWTSecret             string
+	Port                  string
+	EncryptionKey         string
+	ED25519PrivateKey     string
+	ED25519PublicKey      string
+	AuditVerifyInterval   string
+	AlertWebhookURL       string
+	TSAURL                string
+	TSACACert             string
+	TSALocalDir           string
+	TSACheckpointInterval string
+	WebAuthnRPID          string
+	WebAuthnRPName        string
+	WebAuthnOrigins       string
+	JWTKeyRotation        string
+	OIDCProvidersFile     string
+	LDAPDirectoriesFile   string
+	AppBaseURL            string
+	MailDriver            string
+	MailFrom              string
+	MailFileDir           string
+	SMTPHost              string
+	SMTPPort              string
+	SMTPUsername          string
+	SMTPPassword          string
+	PasswordPolicyFile    string
+	BreachedPasswordsDir  string
+	PasswordHasher        string
+	Argon2Memory          string
+	Argon2Iterations      string
+	Argon2Parallelism     string
+	BcryptCost            string
+	ProxyAdultAge         string
+	AdolescentAge         string
+	AdolescentCategories  string
+	ThrottleStore         string
+	ThrottleRulesFile     string
+	LoginFreeAttempts     string
+	LoginBackoffBase      string
+	LoginBackoffMax       string
+	LoginBackoffReset     string
+	StepUpMaxAge          string
+	GeoIPDatabase         string
+	TrustedProxies        string
 }
 
 func Load() *Config {
@@ -23,12 +63,52 @@ func Load() *Config {
 	}
 
 	return &Config{
-		DBUrl:            getEnv("DB_URL", ""),
-		JWTSecret:        getEnv("JWT_SECRET", ""),
-		Port:             getEnv("PORT", "8080"),
-		EncryptionKey:    getEnv("ENCRYPTION_KEY", ""),
-		ED25519PrivateKey: getEnv("ED25519_PRIVATE_KEY", ""),
-		ED25519PublicKey:  getEnv("ED25519_PUBLIC_KEY", ""),
+		DBUrl:                 getEnv("DB_URL", ""),
+		AuditDBUrl:            getEnv("AUDIT_DB_URL", ""),
+		JWTSecret:             getEnv("JWT_SECRET", ""),
+		Port:                  getEnv("PORT", "8080"),
+		EncryptionKey:         getEnv("ENCRYPTION_KEY", ""),
+		ED25519PrivateKey:     getEnv("ED25519_PRIVATE_KEY", ""),
+		ED25519PublicKey:      getEnv("ED25519_PUBLIC_KEY", ""),
+		AuditVerifyInterval:   getEnv("AUDIT_VERIFY_INTERVAL", "5m"),
+		AlertWebhookURL:       getEnv("ALERT_WEBHOOK_URL", ""),
+		TSAURL:                getEnv("TSA_URL", ""),
+		TSACACert:             getEnv("TSA_CA_CERT", ""),
+		TSALocalDir:           getEnv("TSA_LOCAL_DIR", ".tsa"),
+		TSACheckpointInterval: getEnv("TSA_CHECKPOINT_INTERVAL", "1h"),
+		WebAuthnRPID:          getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName:        getEnv("WEBAUTHN_RP_NAME", "HealthVault"),
+		WebAuthnOrigins:       getEnv("WEBAUTHN_ORIGINS", "http://localhost:8080"),
+		JWTKeyRotation:        getEnv("JWT_KEY_ROTATION_INTERVAL", "720h"),
+		OIDCProvidersFile:     getEnv("OIDC_PROVIDERS_FILE", ""),
+		LDAPDirectoriesFile:   getEnv("LDAP_DIRECTORIES_FILE", ""),
+		AppBaseURL:            getEnv("APP_BASE_URL", "http://localhost:5173"),
+		MailDriver:            getEnv("MAIL_DRIVER", ""),
+		MailFrom:              getEnv("MAIL_FROM", "HealthVault <no-reply@healthvault.local>"),
+		MailFileDir:           getEnv("MAIL_FILE_DIR", ".mail"),
+		SMTPHost:              getEnv("SMTP_HOST", ""),
+		SMTPPort:              getEnv("SMTP_PORT", "587"),
+		SMTPUsername:          getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:          getEnv("SMTP_PASSWORD", ""),
+		PasswordPolicyFile:    getEnv("PASSWORD_POLICY_FILE", ""),
+		BreachedPasswordsDir:  getEnv("BREACHED_PASSWORDS_DIR", ""),
+		PasswordHasher:        getEnv("PASSWORD_HASHER", "argon2id"),
+		Argon2Memory:          getEnv("ARGON2_MEMORY_KIB", "65536"),
+		Argon2Iterations:      getEnv("ARGON2_ITERATIONS", "3"),
+		Argon2Parallelism:     getEnv("ARGON2_PARALLELISM", "2"),
+		BcryptCost:            getEnv("BCRYPT_COST", "12"),
+		ProxyAdultAge:         getEnv("PROXY_ADULT_AGE", "18"),
+		AdolescentAge:         getEnv("ADOLESCENT_AGE", "12"),
+		AdolescentCategories:  getEnv("ADOLESCENT_CONFIDENTIAL_CATEGORIES", ""),
+		ThrottleStore:         getEnv("THROTTLE_STORE", "postgres"),
+		ThrottleRulesFile:     getEnv("THROTTLE_RULES_FILE", ""),
+		LoginFreeAttempts:     getEnv("LOGIN_FREE_ATTEMPTS", "4"),
+		LoginBackoffBase:      getEnv("LOGIN_BACKOFF_BASE", "1m"),
+		LoginBackoffMax:       getEnv("LOGIN_BACKOFF_MAX", "1h"),
+		LoginBackoffReset:     getEnv("LOGIN_BACKOFF_RESET", "24h"),
+		StepUpMaxAge:          getEnv("STEP_UP_MAX_AGE", "5m"),
+		GeoIPDatabase:         getEnv("GEOIP_DATABASE", ""),
+		TrustedProxies:        getEnv("TRUSTED_PROXIES", ""),
 	}
 }
 
@@ -38,4 +118,4 @@ func getEnv(key string, fallback string) string {
 		return fallback
 	}
 	return value
-}
\ No newline at end of file
+}
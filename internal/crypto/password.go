@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnsupportedHash means no configured hasher understands a stored hash
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+// PasswordHasher produces and checks one password hash format
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+
+	// Recognizes reports whether encoded is in this hasher's format
+	Recognizes(encoded string) bool
+
+	// Outdated reports whether encoded was made with weaker parameters
+	// than the hasher now uses
+	Outdated(encoded string) bool
+}
+
+// Argon2Params tunes Argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the RFC 9106 recommendation for
+// memory-constrained environments (64 MiB, 3 passes)
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2idHasher stores hashes in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+func NewArgon2idHasher(params Argon2Params) (*Argon2idHasher, error) {
+	if params.Memory < 8*uint32(params.Parallelism) || params.Iterations < 1 || params.Parallelism < 1 {
+		return nil, errors.New("argon2id needs at least 1 iteration, 1 lane and 8 KiB of memory per lane")
+	}
+	if params.SaltLength < 16 || params.KeyLength < 16 {
+		return nil, errors.New("argon2id salt and key must be at least 16 bytes")
+	}
+	return &Argon2idHasher{params: params}, nil
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func (h *Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (h *Argon2idHasher) Outdated(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.params.Memory ||
+		params.Iterations < h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) < h.params.SaltLength ||
+		uint32(len(key)) < h.params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return params, nil, nil, errors.New("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2 hash")
+	}
+	return params, salt, key, nil
+}
+
+// BcryptHasher produces $2a$ modular crypt hashes. bcrypt refuses
+// passwords longer than 72 bytes.
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < 10 || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between 10 and %d", bcrypt.MaxCost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(hash), err
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h *BcryptHasher) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.cost
+}
+
+// Passwords hashes new passwords with the preferred hasher and verifies
+// stored hashes with whichever configured hasher recognizes them, so
+// existing hashes keep working while the user base migrates
+type Passwords struct {
+	preferred PasswordHasher
+	legacy    []PasswordHasher
+}
+
+func NewPasswords(preferred PasswordHasher, legacy ...PasswordHasher) *Passwords {
+	return &Passwords{preferred: preferred, legacy: legacy}
+}
+
+// DefaultPasswords hashes with Argon2id and still accepts bcrypt cost 12
+// hashes from before the switch
+func DefaultPasswords() *Passwords {
+	preferred, _ := NewArgon2idHasher(DefaultArgon2Params())
+	legacy, _ := NewBcryptHasher(12)
+	return NewPasswords(preferred, legacy)
+}
+
+func (p *Passwords) Hash(password string) (string, error) {
+	return p.preferred.Hash(password)
+}
+
+// Verify checks a password against a stored hash. rehash is true when the
+// password matched but the hash should be replaced with a fresh one from
+// Hash, because it uses another format or outdated parameters.
+func (p *Passwords) Verify(encoded, password string) (ok, rehash bool, err error) {
+	if p.preferred.Recognizes(encoded) {
+		ok, err = p.preferred.Verify(encoded, password)
+		return ok, ok && p.preferred.Outdated(encoded), err
+	}
+
+	for _, hasher := range p.legacy {
+		if hasher.Recognizes(encoded) {
+			ok, err = hasher.Verify(encoded, password)
+			return ok, ok, err
+		}
+	}
+	return false, false, ErrUnsupportedHash
+}
+
+// MaxPasswordBytes is the longest password every configured hasher
+// accepts, or 0 when there is no limit
+func (p *Passwords) MaxPasswordBytes() int {
+	if _, ok := p.preferred.(*BcryptHasher); ok {
+		return 72
+	}
+	return 0
+}
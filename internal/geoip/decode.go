@@ -0,0 +1,228 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Data section field types
+const (
+	typePointer = 1
+	typeString  = 2
+	typeDouble  = 3
+	typeBytes   = 4
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeInt32   = 8
+	typeUint64  = 9
+	typeUint128 = 10
+	typeArray   = 11
+	typeBool    = 14
+	typeFloat   = 15
+)
+
+// maxDepth bounds nesting so a corrupt file cannot recurse without end,
+// and maxValues bounds the values one decode may produce, since pointers
+// can share a subtree many times over
+const (
+	maxDepth  = 32
+	maxValues = 1 << 16
+)
+
+var errCorrupt = errors.New("corrupt data section")
+
+// decoder reads values from a data section. Maps decode to
+// map[string]interface{}, arrays to []interface{}, and unsigned integers
+// up to 64 bits to uint64; 128-bit integers are returned as raw bytes.
+type decoder struct {
+	data   []byte
+	depth  int
+	values int
+}
+
+// decode reads the value at offset and returns it with the offset just
+// past it
+func (d *decoder) decode(offset uint) (interface{}, uint, error) {
+	d.depth++
+	d.values++
+	defer func() { d.depth-- }()
+	if d.depth > maxDepth || d.values > maxValues {
+		return nil, 0, errCorrupt
+	}
+
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if kind == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target)
+		return value, next, err
+	}
+
+	// Every entry takes at least a byte, so a count larger than the rest
+	// of the section is corrupt rather than a reason to allocate
+	if (kind == typeMap || kind == typeArray) && size > uint(len(d.data))-offset {
+		return nil, 0, errCorrupt
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			m[name], offset, err = d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+
+	case typeArray:
+		a := make([]interface{}, size)
+		for i := range a {
+			a[i], offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return a, offset, nil
+
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	b, err := d.bytes(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	next := offset + size
+
+	switch kind {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errCorrupt
+		}
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int32(n), next, nil
+	default:
+		return nil, 0, errCorrupt
+	}
+}
+
+// control reads a field's control byte and returns its type, size and the
+// offset of its payload. Pointers return the raw size bits.
+func (d *decoder) control(offset uint) (int, uint, uint, error) {
+	b, err := d.bytes(offset, 1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	offset++
+
+	kind := int(b[0] >> 5)
+	if kind == typePointer {
+		return kind, uint(b[0] & 0x1f), offset, nil
+	}
+	if kind == 0 {
+		ext, err := d.bytes(offset, 1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		kind = 7 + int(ext[0])
+		offset++
+	}
+
+	size := uint(b[0] & 0x1f)
+	if size < 29 {
+		return kind, size, offset, nil
+	}
+
+	extra := size - 28
+	ext, err := d.bytes(offset, extra)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var n uint
+	for _, c := range ext {
+		n = n<<8 | uint(c)
+	}
+	switch extra {
+	case 1:
+		size = 29 + n
+	case 2:
+		size = 285 + n
+	default:
+		size = 65821 + n
+	}
+	return kind, size, offset + extra, nil
+}
+
+// pointer resolves a pointer field to an offset in the data section
+func (d *decoder) pointer(bits, offset uint) (uint, uint, error) {
+	length := bits>>3 + 1
+	b, err := d.bytes(offset, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var n uint
+	if length < 4 {
+		n = bits & 0x7
+	}
+	for _, c := range b {
+		n = n<<8 | uint(c)
+	}
+	switch length {
+	case 2:
+		n += 2048
+	case 3:
+		n += 526336
+	}
+	return n, offset + length, nil
+}
+
+func (d *decoder) bytes(offset, n uint) ([]byte, error) {
+	if offset > uint(len(d.data)) || n > uint(len(d.data))-offset {
+		return nil, errCorrupt
+	}
+	return d.data[offset : offset+n], nil
+}
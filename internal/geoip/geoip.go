@@ -0,0 +1,212 @@
+package geoip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+var errCorruptTree = errors.New("corrupt search tree")
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// Location is where an address was placed. Fields the database does not
+// carry are empty.
+type Location struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// String formats the location for people, most specific part first
+func (l Location) String() string {
+	var parts []string
+	for _, part := range []string{l.City, l.Region, l.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Reader looks addresses up in a MaxMind DB (.mmdb) file, such as
+// GeoLite2-City or GeoLite2-Country, loaded into memory. A nil Reader finds
+// nothing, so callers need not check whether GeoIP is configured.
+type Reader struct {
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	treeSize   uint
+	ipv4Start  uint
+}
+
+// Open loads and checks the database file
+func Open(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newReader(data)
+}
+
+func newReader(data []byte) (*Reader, error) {
+	at := bytes.LastIndex(data, metadataMarker)
+	if at < 0 {
+		return nil, errors.New("geoip: not a MaxMind DB file")
+	}
+	metaStart := uint(at + len(metadataMarker))
+	meta, _, err := (&decoder{data: data[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: invalid metadata: %w", err)
+	}
+	metadata, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("geoip: invalid metadata")
+	}
+
+	r := &Reader{
+		data:       data,
+		nodeCount:  uint(asUint(metadata["node_count"])),
+		recordSize: uint(asUint(metadata["record_size"])),
+		ipVersion:  uint(asUint(metadata["ip_version"])),
+	}
+	if major := asUint(metadata["binary_format_major_version"]); major != 2 {
+		return nil, fmt.Errorf("geoip: unsupported format version %d", major)
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("geoip: unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("geoip: unsupported IP version %d", r.ipVersion)
+	}
+
+	// Checked before multiplying so a huge node count cannot wrap around
+	if r.nodeCount == 0 || r.nodeCount > uint(at)/(r.recordSize/4) {
+		return nil, errors.New("geoip: search tree exceeds file")
+	}
+	r.treeSize = r.nodeCount * r.recordSize / 4
+	if r.treeSize+16 > uint(at) {
+		return nil, errors.New("geoip: search tree exceeds file")
+	}
+
+	// IPv4 addresses live under ::/96 in IPv6 databases
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			if r.ipv4Start, err = r.record(r.ipv4Start, 0); err != nil {
+				return nil, fmt.Errorf("geoip: %w", err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Lookup returns the location of the address, and false when it is
+// invalid, private or not in the database
+func (r *Reader) Lookup(address string) (Location, bool) {
+	if r == nil {
+		return Location{}, false
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
+		return Location{}, false
+	}
+
+	offset, ok, err := r.find(ip)
+	if err != nil || !ok {
+		return Location{}, false
+	}
+
+	value, _, err := (&decoder{data: r.data[r.treeSize+16:]}).decode(offset)
+	if err != nil {
+		return Location{}, false
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return Location{}, false
+	}
+
+	location := Location{
+		Country: asString(path(record, "country", "iso_code")),
+		City:    asString(path(record, "city", "names", "en")),
+	}
+	if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+		location.Region = asString(path(subdivisions[0], "names", "en"))
+	}
+	return location, location != Location{}
+}
+
+// find walks the search tree and returns the record's data section offset.
+// It reports false when the address is not in the database.
+func (r *Reader) find(ip net.IP) (uint, bool, error) {
+	node := uint(0)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		node = r.ipv4Start
+	} else if r.ipVersion == 4 {
+		return 0, false, nil
+	}
+
+	var err error
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		if node, err = r.record(node, uint(bit)); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if node < r.nodeCount+16 {
+		return 0, false, nil
+	}
+	return node - r.nodeCount - 16, true, nil
+}
+
+// record reads the left (0) or right (1) record of a search tree node
+func (r *Reader) record(node, side uint) (uint, error) {
+	nodeBytes := r.recordSize / 4
+	if node >= r.nodeCount || (node+1)*nodeBytes > r.treeSize || side > 1 {
+		return 0, errCorruptTree
+	}
+	b := r.data[node*nodeBytes : (node+1)*nodeBytes]
+
+	switch r.recordSize {
+	case 24:
+		b = b[side*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		if side == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		b = b[side*4:]
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3]), nil
+	}
+}
+
+// path follows map keys into a decoded record
+func path(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func asString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func asUint(value interface{}) uint64 {
+	n, _ := value.(uint64)
+	return n
+}
@@ -0,0 +1,311 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// encodeValue writes one data section field. It covers the types the
+// fixtures need: strings, unsigned integers, maps and arrays.
+func encodeValue(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		writeControl(buf, typeString, uint(len(v)))
+		buf.WriteString(v)
+	case uint64:
+		var b []byte
+		for n := v; n > 0; n >>= 8 {
+			b = append([]byte{byte(n)}, b...)
+		}
+		writeControl(buf, typeUint32, uint(len(b)))
+		buf.Write(b)
+	case map[string]interface{}:
+		writeControl(buf, typeMap, uint(len(v)))
+		for key, item := range v {
+			encodeValue(buf, key)
+			encodeValue(buf, item)
+		}
+	case []interface{}:
+		writeControl(buf, typeArray, uint(len(v)))
+		for _, item := range v {
+			encodeValue(buf, item)
+		}
+	default:
+		panic(fmt.Sprintf("cannot encode %T", value))
+	}
+}
+
+func writeControl(buf *bytes.Buffer, kind int, size uint) {
+	if size >= 29 {
+		panic("fixture values must be shorter than 29")
+	}
+	if kind > 7 {
+		buf.WriteByte(byte(size))
+		buf.WriteByte(byte(kind - 7))
+		return
+	}
+	buf.WriteByte(byte(kind<<5) | byte(size))
+}
+
+// network is a fixture entry: a prefix and the record it maps to
+type network struct {
+	cidr   string
+	record map[string]interface{}
+}
+
+// buildDatabase writes a MaxMind DB with one search tree path per network
+func buildDatabase(ipVersion, recordSize uint, networks []network) []byte {
+	type node [2]int // child node, or -1 for empty, or -2-k for data offset k
+	nodes := []node{{-1, -1}}
+
+	var data bytes.Buffer
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			panic(err)
+		}
+		ip := ipNet.IP
+		ones, _ := ipNet.Mask.Size()
+		// IPv4 networks go under ::/96, not the ::ffff:0:0/96 To16 uses
+		if ipVersion == 6 && len(ip) == net.IPv4len {
+			ip = append(make(net.IP, 12), ip...)
+			ones += 96
+		}
+
+		offset := data.Len()
+		encodeValue(&data, n.record)
+
+		current := 0
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+			if i == ones-1 {
+				nodes[current][bit] = -2 - offset
+				break
+			}
+			if nodes[current][bit] < 0 {
+				nodes = append(nodes, node{-1, -1})
+				nodes[current][bit] = len(nodes) - 1
+			}
+			current = nodes[current][bit]
+		}
+	}
+
+	count := uint(len(nodes))
+	var file bytes.Buffer
+	for _, n := range nodes {
+		var values [2]uint
+		for side, child := range n {
+			switch {
+			case child == -1:
+				values[side] = count
+			case child < -1:
+				values[side] = count + 16 + uint(-2-child)
+			default:
+				values[side] = uint(child)
+			}
+		}
+		file.Write(encodeNode(recordSize, values[0], values[1]))
+	}
+	file.Write(make([]byte, 16))
+	file.Write(data.Bytes())
+	file.Write(metadataMarker)
+	encodeValue(&file, map[string]interface{}{
+		"node_count":                  uint64(count),
+		"record_size":                 uint64(recordSize),
+		"ip_version":                  uint64(ipVersion),
+		"binary_format_major_version": uint64(2),
+	})
+	return file.Bytes()
+}
+
+func encodeNode(recordSize, left, right uint) []byte {
+	switch recordSize {
+	case 24:
+		return []byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)}
+	case 28:
+		return []byte{
+			byte(left >> 16), byte(left >> 8), byte(left),
+			byte(left>>24)<<4 | byte(right>>24)&0x0f,
+			byte(right >> 16), byte(right >> 8), byte(right),
+		}
+	default:
+		b := binary.BigEndian.AppendUint32(nil, uint32(left))
+		return binary.BigEndian.AppendUint32(b, uint32(right))
+	}
+}
+
+func cityRecord(country, region, city string) map[string]interface{} {
+	return map[string]interface{}{
+		"country":      map[string]interface{}{"iso_code": country},
+		"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": region}}},
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": city}},
+	}
+}
+
+var fixtureNetworks = []network{
+	{"81.2.69.0/24", cityRecord("GB", "England", "London")},
+	{"175.16.199.0/24", cityRecord("CN", "Jilin Sheng", "Changchun")},
+	{"2001:480::/32", cityRecord("US", "California", "San Diego")},
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		address string
+		want    Location
+		found   bool
+	}{
+		{"81.2.69.142", Location{Country: "GB", Region: "England", City: "London"}, true},
+		{"175.16.199.1", Location{Country: "CN", Region: "Jilin Sheng", City: "Changchun"}, true},
+		{"2001:480::1", Location{Country: "US", Region: "California", City: "San Diego"}, true},
+		{"81.2.70.1", Location{}, false},
+		{"2001:481::1", Location{}, false},
+		{"10.0.0.1", Location{}, false},
+		{"127.0.0.1", Location{}, false},
+		{"not an address", Location{}, false},
+	}
+
+	for _, recordSize := range []uint{24, 28, 32} {
+		r, err := newReader(buildDatabase(6, recordSize, fixtureNetworks))
+		if err != nil {
+			t.Fatalf("record size %d: %v", recordSize, err)
+		}
+		for _, tt := range tests {
+			got, found := r.Lookup(tt.address)
+			if got != tt.want || found != tt.found {
+				t.Errorf("record size %d: Lookup(%q) = %+v, %v; want %+v, %v",
+					recordSize, tt.address, got, found, tt.want, tt.found)
+			}
+		}
+	}
+}
+
+func TestLookupIPv4Database(t *testing.T) {
+	r, err := newReader(buildDatabase(4, 24, fixtureNetworks[:2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := r.Lookup("81.2.69.142"); !ok || got.City != "London" {
+		t.Errorf("Lookup(81.2.69.142) = %+v, %v", got, ok)
+	}
+	if _, ok := r.Lookup("2001:480::1"); ok {
+		t.Error("IPv4 database found an IPv6 address")
+	}
+}
+
+func TestNilReader(t *testing.T) {
+	var r *Reader
+	if _, ok := r.Lookup("81.2.69.142"); ok {
+		t.Error("nil reader found an address")
+	}
+}
+
+func TestCorruptDatabase(t *testing.T) {
+	valid := buildDatabase(6, 24, fixtureNetworks)
+	at := bytes.LastIndex(valid, metadataMarker)
+
+	withMetadata := func(metadata map[string]interface{}) []byte {
+		var file bytes.Buffer
+		file.Write(valid[:at])
+		file.Write(metadataMarker)
+		encodeValue(&file, metadata)
+		return file.Bytes()
+	}
+	metadata := func(nodeCount uint64) map[string]interface{} {
+		return map[string]interface{}{
+			"node_count":                  nodeCount,
+			"record_size":                 uint64(24),
+			"ip_version":                  uint64(6),
+			"binary_format_major_version": uint64(2),
+		}
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no metadata", valid[:at]},
+		{"truncated metadata", valid[:at+len(metadataMarker)+3]},
+		{"tree larger than file", withMetadata(metadata(1 << 20))},
+		{"node count that overflows", withMetadata(metadata(1<<64 - 1))},
+		{"no nodes", withMetadata(metadata(0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newReader(tt.data); err == nil {
+				t.Error("corrupt database opened")
+			}
+		})
+	}
+}
+
+// TestCorruptRecords points search tree records past the tree and the data
+// section; lookups must fail rather than panic
+func TestCorruptRecords(t *testing.T) {
+	valid := buildDatabase(6, 32, fixtureNetworks)
+	at := bytes.LastIndex(valid, metadataMarker)
+
+	r, err := newReader(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for node := uint(0); node < r.nodeCount; node++ {
+		for _, value := range []uint32{0xffffffff, uint32(r.nodeCount) + 16 + uint32(at)} {
+			data := append([]byte(nil), valid...)
+			binary.BigEndian.PutUint32(data[node*8:], value)
+			binary.BigEndian.PutUint32(data[node*8+4:], value)
+
+			corrupt, err := newReader(data)
+			if err != nil {
+				continue
+			}
+			for _, address := range []string{"81.2.69.142", "175.16.199.1", "2001:480::1"} {
+				corrupt.Lookup(address)
+			}
+		}
+	}
+}
+
+func TestDecodeRejectsOversizedContainers(t *testing.T) {
+	// An array claiming more entries than there are bytes left
+	data := []byte{0x1d, byte(typeArray - 7), 0xff}
+	if _, _, err := (&decoder{data: data}).decode(0); err == nil {
+		t.Error("oversized array decoded")
+	}
+
+	// A pointer that points at itself
+	if _, _, err := (&decoder{data: []byte{0x20, 0x00}}).decode(0); err == nil {
+		t.Error("self-referencing pointer decoded")
+	}
+
+	// Thirty arrays each holding two pointers to the next: a few bytes
+	// that would expand to a billion values
+	var shared []byte
+	for level := 0; level < 30; level++ {
+		next := byte(len(shared) + 6)
+		shared = append(shared, 0x02, byte(typeArray-7), 0x20, next, 0x20, next)
+	}
+	shared = append(shared, 0x40|1, 'x')
+	if _, _, err := (&decoder{data: shared}).decode(0); err == nil {
+		t.Error("exponentially shared data decoded")
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add(buildDatabase(6, 24, fixtureNetworks), "81.2.69.142")
+	f.Add(buildDatabase(6, 28, fixtureNetworks), "2001:480::1")
+	f.Add(buildDatabase(4, 32, fixtureNetworks[:2]), "175.16.199.1")
+
+	f.Fuzz(func(t *testing.T, data []byte, address string) {
+		r, err := newReader(data)
+		if err != nil {
+			return
+		}
+		r.Lookup(address)
+		r.Lookup("81.2.69.142")
+		r.Lookup("2001:480::1")
+	})
+}
@@ -0,0 +1,352 @@
+package keyring
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/khawsic/health/internal/security"
+	"gorm.io/gorm"
+)
+
+const (
+	// PublishLead is how long a new key is advertised in the JWKS before it
+	// signs anything, so verifiers that cache the JWKS already know it
+	PublishLead = 5 * time.Minute
+
+	// reloadInterval bounds how stale the in-memory key set may get when
+	// another instance rotates
+	reloadInterval = time.Minute
+
+	// rotationLockKey serializes rotation across instances
+	rotationLockKey = 7_302_034
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// SigningKey is an Ed25519 key used to sign access tokens. The private key
+// is AES-256 encrypted at rest. A key signs from ActivatesAt until a newer
+// key activates, and verifies tokens until RetiresAt.
+type SigningKey struct {
+	ID          string     `gorm:"primaryKey" json:"kid"`
+	PrivateKey  string     `gorm:"type:text;not null" json:"-"`
+	PublicKey   []byte     `gorm:"not null" json:"-"`
+	ActivatesAt time.Time  `gorm:"not null" json:"activates_at"`
+	RetiresAt   *time.Time `json:"retires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (SigningKey) TableName() string {
+	return "jwt_signing_keys"
+}
+
+type loadedKey struct {
+	SigningKey
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// Keyring holds the access token signing keys. Rotation overlaps: the next
+// key is published before it activates, and the previous key keeps
+// verifying until every token it signed has expired.
+type Keyring struct {
+	db            *gorm.DB
+	encryptionKey string
+	tokenTTL      time.Duration
+
+	mu       sync.RWMutex
+	keys     []loadedKey
+	loadedAt time.Time
+}
+
+// New creates a keyring. tokenTTL is the lifetime of the tokens it signs
+// and sets how long a retired key stays valid for verification.
+func New(db *gorm.DB, encryptionKey string, tokenTTL time.Duration) *Keyring {
+	return &Keyring{
+		db:            db,
+		encryptionKey: encryptionKey,
+		tokenTTL:      tokenTTL,
+	}
+}
+
+func (k *Keyring) Migrate() error {
+	return k.db.AutoMigrate(&SigningKey{})
+}
+
+// Load reads the key set and creates the first key on an empty database
+func (k *Keyring) Load() error {
+	if err := k.reload(); err != nil {
+		return err
+	}
+
+	if _, err := k.activeKey(time.Now()); err == nil {
+		return nil
+	}
+
+	if _, err := k.rotate(0, 0); err != nil {
+		return err
+	}
+	return k.reload()
+}
+
+// Signer returns the kid and private key that should sign new tokens
+func (k *Keyring) Signer() (string, ed25519.PrivateKey, error) {
+	k.reloadIfStale()
+
+	key, err := k.activeKey(time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	return key.ID, key.private, nil
+}
+
+// PublicKey returns the verification key for kid. An unknown kid triggers
+// a reload so keys rotated by another instance are picked up promptly.
+func (k *Keyring) PublicKey(kid string) (ed25519.PublicKey, error) {
+	k.reloadIfStale()
+
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+
+	k.mu.RLock()
+	recent := time.Since(k.loadedAt) < time.Second
+	k.mu.RUnlock()
+	if !recent {
+		if err := k.reload(); err != nil {
+			return nil, err
+		}
+		if key, ok := k.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// Keys lists every key that is pending, active or still verifying
+func (k *Keyring) Keys() []SigningKey {
+	k.reloadIfStale()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]SigningKey, len(k.keys))
+	for i, key := range k.keys {
+		keys[i] = key.SigningKey
+	}
+	return keys
+}
+
+// 🔁 Rotate — creates a key that starts signing after PublishLead and
+// schedules the current keys to retire once their tokens have expired
+func (k *Keyring) Rotate() (*SigningKey, error) {
+	key, err := k.rotate(PublishLead, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// StartRotation rotates automatically whenever the newest key is older than
+// interval. It checks hourly (or every interval, if shorter) until ctx is
+// cancelled.
+func (k *Keyring) StartRotation(ctx context.Context, interval time.Duration) {
+	check := time.Hour
+	if interval < check {
+		check = interval
+	}
+
+	go func() {
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				key, err := k.rotateIfOlder(interval)
+				if err != nil {
+					log.Printf("⚠️  JWT key rotation failed: %v", err)
+					continue
+				}
+				if key != nil {
+					log.Printf("🔁 JWT signing key %s created — active from %s", key.ID, key.ActivatesAt.Format(time.RFC3339))
+				}
+			}
+		}
+	}()
+}
+
+func (k *Keyring) rotateIfOlder(interval time.Duration) (*SigningKey, error) {
+	key, err := k.rotate(PublishLead, interval)
+	if err != nil || key == nil {
+		return key, err
+	}
+	return key, k.reload()
+}
+
+// rotate inserts a new key under an advisory lock. A non-zero maxAge skips
+// rotation unless the newest key is older than that, so several instances
+// rotating on the same schedule create a single key.
+func (k *Keyring) rotate(lead, maxAge time.Duration) (*SigningKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := security.Encrypt(k.encryptionKey, hex.EncodeToString(private.Seed()))
+	if err != nil {
+		return nil, err
+	}
+
+	kidBytes := make([]byte, 12)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	key := SigningKey{
+		ID:          base64.RawURLEncoding.EncodeToString(kidBytes),
+		PrivateKey:  encrypted,
+		PublicKey:   public,
+		ActivatesAt: now.Add(lead),
+	}
+
+	created := false
+	err = k.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rotationLockKey).Error; err != nil {
+			return err
+		}
+
+		if maxAge > 0 {
+			var newest SigningKey
+			result := tx.Order("created_at DESC").Limit(1).Find(&newest)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 && now.Sub(newest.CreatedAt) < maxAge {
+				return nil
+			}
+		}
+
+		// Keys still signing now may have issued tokens up to the moment
+		// the new key activates; keep them until those tokens expire
+		retireAt := key.ActivatesAt.Add(k.tokenTTL)
+		if err := tx.Model(&SigningKey{}).
+			Where("retires_at IS NULL").
+			Update("retires_at", retireAt).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil || !created {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (k *Keyring) reloadIfStale() {
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) > reloadInterval
+	k.mu.RUnlock()
+
+	if stale {
+		if err := k.reload(); err != nil {
+			log.Printf("⚠️  Failed to reload JWT keys, using cached set: %v", err)
+		}
+	}
+}
+
+func (k *Keyring) reload() error {
+	var records []SigningKey
+	if err := k.db.
+		Where("retires_at IS NULL OR retires_at > ?", time.Now()).
+		Order("activates_at ASC").
+		Find(&records).Error; err != nil {
+		return err
+	}
+
+	keys := make([]loadedKey, 0, len(records))
+	for _, record := range records {
+		seedHex, err := security.Decrypt(k.encryptionKey, record.PrivateKey)
+		if err != nil {
+			return errors.New("failed to decrypt signing key " + record.ID)
+		}
+		seed, err := hex.DecodeString(seedHex)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return errors.New("corrupt signing key " + record.ID)
+		}
+
+		private := ed25519.NewKeyFromSeed(seed)
+		keys = append(keys, loadedKey{
+			SigningKey: record,
+			private:    private,
+			public:     private.Public().(ed25519.PublicKey),
+		})
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// activeKey is the most recently activated key that has not retired
+func (k *Keyring) activeKey(now time.Time) (*loadedKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var active *loadedKey
+	for i := range k.keys {
+		key := &k.keys[i]
+		if key.ActivatesAt.After(now) {
+			continue
+		}
+		if key.RetiresAt != nil && !key.RetiresAt.After(now) {
+			continue
+		}
+		if active == nil || key.ActivatesAt.After(active.ActivatesAt) {
+			active = key
+		}
+	}
+
+	if active == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return active, nil
+}
+
+func (k *Keyring) lookup(kid string) (ed25519.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range k.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.RetiresAt != nil && !key.RetiresAt.After(now) {
+			return nil, false
+		}
+		return key.public, true
+	}
+	return nil, false
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html.tmpl"))
+)
+
+// Template names. Each has a .txt.tmpl and a .html.tmpl file under templates/.
+const (
+	TemplatePasswordReset             = "password_reset"
+	TemplateEmailVerification         = "email_verification"
+	TemplateDoctorApplicationDecision = "doctor_application_decision"
+	TemplateNewSignIn                 = "new_sign_in"
+)
+
+var subjects = map[string]string{
+	TemplatePasswordReset:             "Reset your HealthVault password",
+	TemplateEmailVerification:         "Confirm your HealthVault email address",
+	TemplateDoctorApplicationDecision: "Your HealthVault clinician registration",
+	TemplateNewSignIn:                 "New sign-in to your HealthVault account",
+}
+
+// Render builds a message from the named template pair. The HTML part is
+// auto-escaped; data should be a struct or map of the template's fields.
+func Render(name, to string, data interface{}) (Message, error) {
+	var text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt.tmpl", data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html.tmpl", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: subjects[name],
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActingAsHeader names the patient a proxy is acting for
+const ActingAsHeader = "X-Acting-As"
+
+// ProxyResolver returns the verified proxy relationship letting proxyID
+// act for subjectID, or an error when there is none
+type ProxyResolver interface {
+	ResolveProxy(proxyID, subjectID uint) (uint, error)
+}
+
+// ActingAs lets a verified proxy make the request on behalf of the patient
+// named in X-Acting-As. The patient becomes "acting_as" for handlers, and
+// audit entries record both the proxy and the patient. Without the header
+// callers act for themselves.
+func ActingAs(proxies ProxyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		header := c.GetHeader(ActingAsHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		subjectID, err := strconv.ParseUint(header, 10, 64)
+		if err != nil || subjectID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + ActingAsHeader + " header"})
+			c.Abort()
+			return
+		}
+
+		info := requestInfo(c)
+		relationshipID, err := proxies.ResolveProxy(info.UserID, uint(subjectID))
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		info.ActingAs = uint(subjectID)
+		info.ProxyID = relationshipID
+		c.Set("acting_as", uint(subjectID))
+
+		c.Next()
+	}
+}
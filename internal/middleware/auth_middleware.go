@@ -1,15 +1,43 @@
 package middleware
 
 import (
+	"context"
+	"crypto/ed25519"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(secret string) gin.HandlerFunc {
+// KeyResolver returns the public key that verifies tokens signed under kid
+type KeyResolver interface {
+	PublicKey(kid string) (ed25519.PublicKey, error)
+}
+
+// SessionValidator checks server-side state the signature cannot: revoked
+// sessions, deleted or locked users and outdated token versions
+type SessionValidator interface {
+	ValidateAccessToken(userID uint, sessionID, tokenID string, version int) error
+}
+
+// apiKeyPrefix starts every API key (auth.APIKeyPrefix); access tokens
+// are JWTs and never do
+const apiKeyPrefix = "hv_"
+
+// APIKeyValidator authenticates service accounts. ValidateAPIKey fills in
+// the caller in the request info; RecordAPIKeyRequest audits the request
+// once it has been handled.
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, key string) error
+	RecordAPIKeyRequest(ctx context.Context, method, route string, status int)
+}
+
+// AuthMiddleware accepts a signed access token or, for service accounts,
+// an API key, both as Bearer credentials
+func AuthMiddleware(keys KeyResolver, sessions SessionValidator, apiKeys APIKeyValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		authHeader := c.GetHeader("Authorization")
@@ -29,13 +57,22 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 
 		tokenString := parts[1]
 
+		if strings.HasPrefix(tokenString, apiKeyPrefix) {
+			authenticateAPIKey(c, apiKeys, tokenString)
+			return
+		}
+
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method — prevents alg:none attack
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			// Validate signing method — prevents alg:none and HMAC confusion attacks
+			if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return []byte(secret), nil
-		})
+			kid, ok := token.Header["kid"].(string)
+			if !ok || kid == "" {
+				return nil, fmt.Errorf("missing kid header")
+			}
+			return keys.PublicKey(kid)
+		}, jwt.WithValidMethods([]string{"EdDSA"}))
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -51,10 +88,64 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 			return
 		}
 
+		userID, _ := claims["user_id"].(float64)
+		sessionID, _ := claims["sid"].(string)
+		tokenID, _ := claims["jti"].(string)
+		version, _ := claims["ver"].(float64)
+
+		if err := sessions.ValidateAccessToken(uint(userID), sessionID, tokenID, int(version)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
 		// Attach user info to context
 		c.Set("user_id", claims["user_id"])
 		c.Set("role", claims["role"])
+		c.Set("session_id", sessionID)
 
+		info := requestInfo(c)
+		if id, ok := claims["user_id"].(float64); ok {
+			info.UserID = uint(id)
+		}
+		if role, ok := claims["role"].(string); ok {
+			info.Role = role
+		}
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			info.AuthTime = time.Unix(int64(authTime), 0)
+			info.ACR, _ = claims["acr"].(string)
+		}
+
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyValidator, key string) {
+	info := requestInfo(c)
+	if err := apiKeys.ValidateAPIKey(c.Request.Context(), key); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", info.UserID)
+	c.Set("role", info.Role)
+	c.Set("api_key_id", info.APIKeyID)
+
+	c.Next()
+
+	apiKeys.RecordAPIKeyRequest(c.Request.Context(), c.Request.Method, c.FullPath(), c.Writer.Status())
+}
+
+// SessionOnly keeps API keys off routes that manage a person's own sign-in
+// (MFA, sessions, passkeys), which service accounts do not have
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if requestInfo(c).APIKeyID != 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a signed-in user"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/crypto"
+)
+
+const (
+	// deviceCookie holds a random ID that names the browser across logins
+	deviceCookie    = "hv_device"
+	deviceCookieAge = 365 * 24 * 60 * 60
+	deviceIDBytes   = 32
+)
+
+// DeviceCookieMiddleware gives browsers signing in without a device cookie
+// a new one. Login routes use it so the device recorded with a login is
+// the one the browser will present next time.
+func DeviceCookieMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := deviceCookieID(c); !ok {
+			b := make([]byte, deviceIDBytes)
+			if _, err := rand.Read(b); err == nil {
+				id := hex.EncodeToString(b)
+
+				// Lax, so the cookie still arrives on the OIDC callback
+				http.SetCookie(c.Writer, &http.Cookie{
+					Name:     deviceCookie,
+					Value:    id,
+					Path:     "/",
+					MaxAge:   deviceCookieAge,
+					Secure:   true,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+				requestInfo(c).Device = crypto.HashString(id)
+			}
+		}
+		c.Next()
+	}
+}
+
+// deviceKey identifies the browser: the hashed device cookie when there is
+// one, otherwise a hash of headers that stay the same across a browser's
+// requests. Only the cookie is hard to forge; headers are a fallback for
+// clients that do not keep cookies.
+func deviceKey(c *gin.Context) string {
+	if id, ok := deviceCookieID(c); ok {
+		return crypto.HashString(id)
+	}
+	return crypto.HashString(c.Request.UserAgent() + "\n" +
+		c.GetHeader("Accept-Language") + "\n" +
+		c.GetHeader("Sec-CH-UA-Platform"))
+}
+
+// deviceCookieID returns the device cookie if it is well formed
+func deviceCookieID(c *gin.Context) (string, bool) {
+	id, err := c.Cookie(deviceCookie)
+	if err != nil || len(id) != deviceIDBytes*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
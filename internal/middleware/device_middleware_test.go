@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/requestctx"
+)
+
+func TestDeviceCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var device string
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.POST("/login", DeviceCookieMiddleware(), func(c *gin.Context) {
+		device = requestctx.From(c.Request.Context()).Device
+	})
+	r.GET("/other", func(c *gin.Context) {
+		device = requestctx.From(c.Request.Context()).Device
+	})
+
+	serve := func(method, path, userAgent string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("User-Agent", userAgent)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The first login issues a cookie and records the device by it
+	w := serve(http.MethodPost, "/login", "Browser/1", nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != deviceCookie {
+		t.Fatalf("first login set cookies %v, want %s", cookies, deviceCookie)
+	}
+	cookie := cookies[0]
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("device cookie is not HttpOnly, Secure and SameSite=Lax: %+v", cookie)
+	}
+	first := device
+
+	// Later logins keep the cookie and the device, whatever the headers say
+	w = serve(http.MethodPost, "/login", "Browser/2", cookie)
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("login with a device cookie replaced it")
+	}
+	if device != first {
+		t.Error("device changed with the user agent despite the cookie")
+	}
+
+	// Copying another browser's headers does not make the same device
+	serve(http.MethodPost, "/login", "Browser/1", nil)
+	if device == first {
+		t.Error("login without the cookie matched the cookie's device")
+	}
+
+	// Other routes fall back to headers and never issue a cookie
+	w = serve(http.MethodGet, "/other", "Browser/1", nil)
+	if len(w.Result().Cookies()) != 0 || device == "" {
+		t.Errorf("other route: cookies %v, device %q", w.Result().Cookies(), device)
+	}
+
+	// A malformed cookie is ignored and replaced at login
+	w = serve(http.MethodPost, "/login", "Browser/1", &http.Cookie{Name: deviceCookie, Value: "forged"})
+	if len(w.Result().Cookies()) != 1 {
+		t.Error("malformed device cookie was not replaced")
+	}
+}
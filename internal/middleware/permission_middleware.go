@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/authz"
+)
+
+// RequirePermission admits callers whose role grants permission, subject
+// to the attribute policies. Conditions on the patient are left to the
+// service, which checks again once it knows the patient.
+func RequirePermission(authorizer *authz.Service, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		decision := authorizer.Check(c.Request.Context(), permission, nil)
+		if !decision.Allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "Access denied",
+				"permission": permission,
+				"reason":     decision.Reason,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -3,10 +3,16 @@ package middleware
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"regexp"
 
 	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/requestctx"
 )
 
+// requestIDPattern limits client-supplied request IDs to what is safe to
+// echo in headers and store in the audit log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
 // GenerateRequestID creates a cryptographically secure unique request ID
 func generateRequestID() string {
 	bytes := make([]byte, 16)
@@ -19,19 +25,37 @@ func generateRequestID() string {
 // RequestIDMiddleware attaches a unique ID to every request
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Use existing request ID from header if present
+		// Use a well-formed request ID from the header if present,
 		// otherwise generate a new one
 		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		if !requestIDPattern.MatchString(requestID) {
 			requestID = generateRequestID()
 		}
 
 		// Attach to context for use in handlers and logs
 		c.Set("request_id", requestID)
 
+		// Carry request metadata into services for audit attribution
+		info := requestInfo(c)
+		info.RequestID = requestID
+		info.IPAddress = c.ClientIP()
+		info.UserAgent = c.Request.UserAgent()
+		info.Device = deviceKey(c)
+
 		// Send it back in response header so client can trace it
 		c.Header("X-Request-ID", requestID)
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// requestInfo returns the request info attached to the request context,
+// attaching an empty one first if the request has none yet
+func requestInfo(c *gin.Context) *requestctx.Info {
+	if info, ok := requestctx.Lookup(c.Request.Context()); ok {
+		return info
+	}
+	info := &requestctx.Info{}
+	c.Request = c.Request.WithContext(requestctx.With(c.Request.Context(), info))
+	return info
+}
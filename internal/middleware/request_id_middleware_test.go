@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDFromHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/", func(c *gin.Context) {})
+
+	tests := []struct {
+		header string
+		kept   bool
+	}{
+		{"trace-01.a_b", true},
+		{strings.Repeat("a", 64), true},
+		{"", false},
+		{strings.Repeat("a", 65), false},
+		{"id with spaces", false},
+		{"=HYPERLINK(\"x\")", false},
+		{"idé", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", tt.header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get("X-Request-ID")
+		if tt.kept && got != tt.header {
+			t.Errorf("X-Request-ID %q came back as %q, want it kept", tt.header, got)
+		}
+		if !tt.kept && (got == tt.header || !requestIDPattern.MatchString(got)) {
+			t.Errorf("X-Request-ID %q came back as %q, want a new ID", tt.header, got)
+		}
+	}
+}
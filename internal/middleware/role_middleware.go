@@ -1,37 +0,0 @@
-package middleware
-
-import (
-	"net/http"
-
-	"github.com/gin-gonic/gin"
-)
-
-func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Role not found"})
-			c.Abort()
-			return
-		}
-
-		// Safe type assertion — prevents panic on unexpected type
-		userRole, ok := role.(string)
-		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid role format"})
-			c.Abort()
-			return
-		}
-
-		for _, allowed := range allowedRoles {
-			if userRole == allowed {
-				c.Next()
-				return
-			}
-		}
-
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		c.Abort()
-	}
-}
\ No newline at end of file
@@ -0,0 +1,248 @@
+package mockldap
+
+import (
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jimlambrt/gldap"
+)
+
+// userAccountControl flags
+const (
+	uacAccountDisable = 0x0002
+	uacNormalAccount  = 0x0200
+)
+
+// User is an account in the mock directory
+type User struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Email    string   `json:"email"`
+	Name     string   `json:"name"`
+	Groups   []string `json:"groups"`   // group common names
+	Disabled bool     `json:"disabled"` // binds are refused, as Active Directory does
+}
+
+// Config describes the mock directory's service account and users
+type Config struct {
+	BaseDN       string
+	BindDN       string
+	BindPassword string
+	Users        []User
+}
+
+// Directory is a minimal in-memory LDAP server for local development and
+// testing. It is shaped like Active Directory (sAMAccountName, memberOf,
+// binary objectGUID) and supports simple bind and filtered search.
+// It provides no security and must not be used in production.
+type Directory struct {
+	config  Config
+	entries []*gldap.Entry
+	server  *gldap.Server
+
+	mu    sync.Mutex
+	bound map[int]bool
+}
+
+// New builds the directory entries; call Run to start serving
+func New(config Config) (*Directory, error) {
+	d := &Directory{
+		config: config,
+		bound:  make(map[int]bool),
+	}
+
+	for _, user := range config.Users {
+		guid := make([]byte, 16)
+		if _, err := rand.Read(guid); err != nil {
+			return nil, err
+		}
+
+		accountControl := uacNormalAccount
+		if user.Disabled {
+			accountControl |= uacAccountDisable
+		}
+
+		memberOf := make([]string, len(user.Groups))
+		for i, group := range user.Groups {
+			memberOf[i] = "CN=" + group + ",OU=Groups," + config.BaseDN
+		}
+
+		d.entries = append(d.entries, gldap.NewEntry("CN="+user.Username+",OU=People,"+config.BaseDN, map[string][]string{
+			"objectClass":        {"top", "person", "organizationalPerson", "user"},
+			"sAMAccountName":     {user.Username},
+			"userPrincipalName":  {user.Email},
+			"mail":               {user.Email},
+			"displayName":        {user.Name},
+			"memberOf":           memberOf,
+			"objectGUID":         {string(guid)},
+			"userAccountControl": {strconv.Itoa(accountControl)},
+			"userPassword":       {user.Password},
+		}))
+	}
+
+	server, err := gldap.NewServer(gldap.WithOnClose(d.forget))
+	if err != nil {
+		return nil, err
+	}
+
+	mux, err := gldap.NewMux()
+	if err != nil {
+		return nil, err
+	}
+	if err := mux.Bind(d.bind); err != nil {
+		return nil, err
+	}
+	if err := mux.Search(d.search); err != nil {
+		return nil, err
+	}
+	if err := server.Router(mux); err != nil {
+		return nil, err
+	}
+
+	d.server = server
+	return d, nil
+}
+
+// Run serves LDAP on addr until Stop is called
+func (d *Directory) Run(addr string) error {
+	return d.server.Run(addr)
+}
+
+func (d *Directory) Ready() bool {
+	return d.server.Ready()
+}
+
+func (d *Directory) Stop() error {
+	return d.server.Stop()
+}
+
+func (d *Directory) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer w.Write(resp)
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil || m.AuthChoice != gldap.SimpleAuthChoice || m.Password == "" {
+		return
+	}
+
+	ok := strings.EqualFold(m.UserName, d.config.BindDN) && string(m.Password) == d.config.BindPassword
+	for _, entry := range d.entries {
+		if strings.EqualFold(m.UserName, entry.DN) && string(m.Password) == entry.GetAttributeValues("userPassword")[0] && !disabled(entry) {
+			ok = true
+		}
+	}
+
+	d.mu.Lock()
+	d.bound[r.ConnectionID()] = ok
+	d.mu.Unlock()
+
+	if ok {
+		resp.SetResultCode(gldap.ResultSuccess)
+	}
+}
+
+func (d *Directory) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+	defer w.Write(resp)
+
+	d.mu.Lock()
+	bound := d.bound[r.ConnectionID()]
+	d.mu.Unlock()
+	if !bound {
+		resp.SetResultCode(gldap.ResultInsufficientAccessRights)
+		return
+	}
+
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		resp.SetResultCode(gldap.ResultProtocolError)
+		return
+	}
+	filter, err := ldap.CompileFilter(m.Filter)
+	if err != nil {
+		resp.SetResultCode(gldap.ResultFilterError)
+		return
+	}
+
+	suffix := "," + strings.ToLower(m.BaseDN)
+	for _, entry := range d.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), suffix) || !matches(filter, entry) {
+			continue
+		}
+
+		result := r.NewSearchResponseEntry(entry.DN)
+		for _, attr := range entry.Attributes {
+			if attr.Name != "userPassword" {
+				result.AddAttribute(attr.Name, attr.Values)
+			}
+		}
+		w.Write(result)
+	}
+}
+
+func (d *Directory) forget(connectionID int) {
+	d.mu.Lock()
+	delete(d.bound, connectionID)
+	d.mu.Unlock()
+}
+
+// disabled reports whether the entry's userAccountControl has the
+// ACCOUNTDISABLE flag
+func disabled(entry *gldap.Entry) bool {
+	flags, _ := strconv.Atoi(entry.GetAttributeValues("userAccountControl")[0])
+	return flags&uacAccountDisable != 0
+}
+
+// matches evaluates the and, or, not, equality and presence filters; any
+// other filter type never matches
+func matches(filter *ber.Packet, entry *gldap.Entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], entry)
+
+	case ldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := filter.Children[1].Data.String()
+		for _, value := range attributeValues(entry, filter.Children[0].Data.String()) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+
+	case ldap.FilterPresent:
+		return len(attributeValues(entry, filter.Data.String())) > 0
+	}
+	return false
+}
+
+func attributeValues(entry *gldap.Entry, name string) []string {
+	for _, attr := range entry.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Values
+		}
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoCareRelationship is returned when a clinician touches the records of
+// a patient they are not currently assigned to
+var ErrNoCareRelationship = errors.New("no active care relationship with this patient — use emergency access if this is an emergency")
+
+var (
+	errAssignmentNotFound = errors.New("care assignment not found")
+	errNotAPatient        = errors.New("patient not found")
+	errNotAClinician      = errors.New("clinician not found")
+)
+
+// CareFilter narrows the care assignment list
+type CareFilter struct {
+	PatientID   uint
+	ClinicianID uint
+	Status      string
+	Page        int
+	PageSize    int
+}
+
+// activeCare restricts a query to assignments in force at now
+func activeCare(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("care_assignments.status = ? AND care_assignments.starts_at <= ? AND (care_assignments.ends_at IS NULL OR care_assignments.ends_at > ?)",
+		CareActive, now, now)
+}
+
+// requireCare refuses clinicians without an active assignment to the
+// patient. Refusals are audited like any other denied access.
+func (s *Service) requireCare(ctx context.Context, clinicianID, patientID uint) error {
+	var count int64
+	if err := activeCare(s.db.Model(&CareAssignment{}), time.Now()).
+		Where("clinician_id = ? AND patient_id = ?", clinicianID, patientID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	s.logEvent(ctx, "ACCESS_DENIED", &patientID, map[string]interface{}{
+		"reason": "no active care relationship",
+	})
+	return ErrNoCareRelationship
+}
+
+// 🩺 RequestCareAssignment — a clinician asks to join a patient's care
+// team. A care team manager reviews the request.
+func (s *Service) RequestCareAssignment(ctx context.Context, clinicianID, patientID uint, reason string, startsAt time.Time, endsAt *time.Time) (*CareAssignment, error) {
+	assignment, err := s.newAssignment(clinicianID, patientID, reason, startsAt, endsAt)
+	if err != nil {
+		return nil, err
+	}
+	assignment.Status = CareRequested
+	assignment.RequestedBy = clinicianID
+
+	if err := s.db.Create(assignment).Error; err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, "REQUEST_CARE_ASSIGNMENT", &patientID, map[string]interface{}{
+		"assignment_id": assignment.ID,
+		"clinician_id":  clinicianID,
+	})
+	return assignment, nil
+}
+
+// 🩺 AssignCare — a care team manager assigns a clinician directly
+func (s *Service) AssignCare(ctx context.Context, managerID, clinicianID, patientID uint, reason string, startsAt time.Time, endsAt *time.Time) (*CareAssignment, error) {
+	assignment, err := s.newAssignment(clinicianID, patientID, reason, startsAt, endsAt)
+	if err != nil {
+		return nil, err
+	}
+	assignment.Status = CareActive
+	assignment.RequestedBy = managerID
+	assignment.ReviewedBy = &managerID
+
+	if err := s.db.Create(assignment).Error; err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, "ASSIGN_CARE", &patientID, map[string]interface{}{
+		"assignment_id": assignment.ID,
+		"clinician_id":  clinicianID,
+		"starts_at":     assignment.StartsAt,
+		"ends_at":       assignment.EndsAt,
+	})
+	return assignment, nil
+}
+
+func (s *Service) newAssignment(clinicianID, patientID uint, reason string, startsAt time.Time, endsAt *time.Time) (*CareAssignment, error) {
+	if !s.userHasRole(patientID, true) {
+		return nil, errNotAPatient
+	}
+	if !s.userHasRole(clinicianID, false) {
+		return nil, errNotAClinician
+	}
+
+	now := time.Now()
+	if startsAt.IsZero() {
+		startsAt = now
+	}
+	if endsAt != nil && !endsAt.After(startsAt) {
+		return nil, errors.New("an assignment must end after it starts")
+	}
+	if endsAt != nil && !endsAt.After(now) {
+		return nil, errors.New("an assignment must end in the future")
+	}
+
+	// One open assignment per clinician and patient; a new one can follow
+	// once the current one has ended
+	var open int64
+	if err := s.db.Model(&CareAssignment{}).
+		Where("clinician_id = ? AND patient_id = ?", clinicianID, patientID).
+		Where("status = ? OR (status = ? AND (ends_at IS NULL OR ends_at > ?))", CareRequested, CareActive, now).
+		Count(&open).Error; err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, errors.New("this clinician already has an open assignment to this patient")
+	}
+
+	return &CareAssignment{
+		PatientID:   patientID,
+		ClinicianID: clinicianID,
+		Reason:      reason,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+	}, nil
+}
+
+// userHasRole checks that an active, undeleted user is a patient, or for
+// patient=false any staff role
+func (s *Service) userHasRole(userID uint, patient bool) bool {
+	query := s.db.Table("users").Where("id = ? AND status = ? AND deleted_at IS NULL", userID, "active")
+	if patient {
+		query = query.Where("role = ?", "patient")
+	} else {
+		query = query.Where("role <> ?", "patient")
+	}
+
+	var count int64
+	return query.Count(&count).Error == nil && count > 0
+}
+
+// ✅ ReviewCareAssignment — approves or declines a clinician's request.
+// Managers cannot review their own requests.
+func (s *Service) ReviewCareAssignment(ctx context.Context, managerID, assignmentID uint, approve bool, notes string) (*CareAssignment, error) {
+	var assignment CareAssignment
+	if err := s.db.First(&assignment, assignmentID).Error; err != nil {
+		return nil, errAssignmentNotFound
+	}
+	if assignment.Status != CareRequested {
+		return nil, errors.New("only requested assignments can be reviewed")
+	}
+	if assignment.RequestedBy == managerID {
+		return nil, errors.New("you cannot review your own request")
+	}
+
+	status, action := CareDeclined, "DECLINE_CARE_ASSIGNMENT"
+	if approve {
+		status, action = CareActive, "APPROVE_CARE_ASSIGNMENT"
+	}
+
+	// Conditional on the status so two reviewers cannot both decide
+	result := s.db.Model(&CareAssignment{}).
+		Where("id = ? AND status = ?", assignment.ID, CareRequested).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"reviewed_by":  managerID,
+			"review_notes": notes,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("only requested assignments can be reviewed")
+	}
+
+	assignment.Status = status
+	assignment.ReviewedBy = &managerID
+	assignment.ReviewNotes = notes
+
+	s.logEvent(ctx, action, &assignment.PatientID, map[string]interface{}{
+		"assignment_id": assignment.ID,
+		"clinician_id":  assignment.ClinicianID,
+		"notes":         notes,
+	})
+	return &assignment, nil
+}
+
+// ⏹️ EndCareAssignment — takes a clinician off a patient's care team now.
+// Clinicians may end their own assignments; managers may end any.
+func (s *Service) EndCareAssignment(ctx context.Context, userID, assignmentID uint, manager bool) error {
+	var assignment CareAssignment
+	if err := s.db.First(&assignment, assignmentID).Error; err != nil {
+		return errAssignmentNotFound
+	}
+	if !manager && assignment.ClinicianID != userID {
+		return errAssignmentNotFound
+	}
+	if assignment.Status != CareActive && assignment.Status != CareRequested {
+		return errors.New("this assignment has already ended")
+	}
+
+	previousStatus := assignment.Status
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":   CareEnded,
+		"ended_by": userID,
+	}
+	if assignment.EndsAt == nil || assignment.EndsAt.After(now) {
+		updates["ends_at"] = now
+	}
+	if err := s.db.Model(&assignment).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, "END_CARE_ASSIGNMENT", &assignment.PatientID, map[string]interface{}{
+		"assignment_id":   assignment.ID,
+		"clinician_id":    assignment.ClinicianID,
+		"previous_status": previousStatus,
+	})
+	return nil
+}
+
+// ListCareAssignments returns one page of assignments, newest first
+func (s *Service) ListCareAssignments(filter CareFilter) ([]CareAssignmentView, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	query := s.db.Model(&CareAssignment{})
+	if filter.PatientID != 0 {
+		query = query.Where("care_assignments.patient_id = ?", filter.PatientID)
+	}
+	if filter.ClinicianID != 0 {
+		query = query.Where("care_assignments.clinician_id = ?", filter.ClinicianID)
+	}
+	if filter.Status != "" {
+		query = query.Where("care_assignments.status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	views, err := s.careViews(query.
+		Order("care_assignments.created_at DESC, care_assignments.id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize))
+	return views, total, err
+}
+
+// MyPatients lists the clinician's assignments in force now
+func (s *Service) MyPatients(clinicianID uint) ([]CareAssignmentView, error) {
+	return s.careViews(activeCare(s.db.Model(&CareAssignment{}), time.Now()).
+		Where("care_assignments.clinician_id = ?", clinicianID).
+		Order("care_assignments.starts_at ASC"))
+}
+
+// CareTeam lists who is caring for a patient now, for the patient to see
+func (s *Service) CareTeam(patientID uint) ([]CareAssignmentView, error) {
+	return s.careViews(activeCare(s.db.Model(&CareAssignment{}), time.Now()).
+		Where("care_assignments.patient_id = ?", patientID).
+		Order("care_assignments.starts_at ASC"))
+}
+
+func (s *Service) careViews(query *gorm.DB) ([]CareAssignmentView, error) {
+	var views []CareAssignmentView
+	err := query.
+		Select("care_assignments.*, p.name AS patient_name, c.name AS clinician_name, c.role AS clinician_role").
+		Joins("JOIN users p ON p.id = care_assignments.patient_id").
+		Joins("JOIN users c ON c.id = care_assignments.clinician_id").
+		Scan(&views).Error
+	return views, err
+}
+
+// OpenCareAssignments counts the clinician's current and future
+// assignments, the ones TransferCareAssignments would hand over
+func (s *Service) OpenCareAssignments(clinicianID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&CareAssignment{}).
+		Where("clinician_id = ? AND status = ? AND (ends_at IS NULL OR ends_at > ?)", clinicianID, CareActive, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// CareHandover reports what TransferCareAssignments moved
+type CareHandover struct {
+	Assignments int64
+	Consents    int64
+
+	// WithoutConsent lists handed-over patients whose consent does not
+	// cover the successor, so an administrator can follow up
+	WithoutConsent []uint
+}
+
+// TransferCareAssignments hands a departing clinician's current and future
+// assignments to their successor. The originals end now; the successor's
+// keep the same end dates. Consent the patients gave the departing
+// clinician personally is copied to the successor with the same scope and
+// expiry, and shown in each patient's consent activity.
+func (s *Service) TransferCareAssignments(ctx context.Context, fromClinicianID, toClinicianID uint) (*CareHandover, error) {
+	now := time.Now()
+	var moved []CareAssignment
+	var patientIDs []uint
+	copied := map[uint]ConsentGrant{} // original consent ID -> successor's copy
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var assignments []CareAssignment
+		if err := tx.Where("clinician_id = ? AND status = ? AND (ends_at IS NULL OR ends_at > ?)", fromClinicianID, CareActive, now).
+			Find(&assignments).Error; err != nil {
+			return err
+		}
+
+		for _, assignment := range assignments {
+			patientIDs = append(patientIDs, assignment.PatientID)
+
+			// Updates writes the new end date back into assignment
+			endsAt := assignment.EndsAt
+			if err := tx.Model(&assignment).Updates(map[string]interface{}{
+				"status":  CareEnded,
+				"ends_at": now,
+			}).Error; err != nil {
+				return err
+			}
+
+			// The successor may already care for this patient
+			var existing int64
+			if err := activeCare(tx.Model(&CareAssignment{}), now).
+				Where("clinician_id = ? AND patient_id = ?", toClinicianID, assignment.PatientID).
+				Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+
+			startsAt := assignment.StartsAt
+			if startsAt.Before(now) {
+				startsAt = now
+			}
+			successor := CareAssignment{
+				PatientID:   assignment.PatientID,
+				ClinicianID: toClinicianID,
+				Status:      CareActive,
+				Reason:      "handover from clinician being offboarded",
+				StartsAt:    startsAt,
+				EndsAt:      endsAt,
+				RequestedBy: assignment.RequestedBy,
+				ReviewedBy:  assignment.ReviewedBy,
+			}
+			if err := tx.Create(&successor).Error; err != nil {
+				return err
+			}
+			moved = append(moved, successor)
+		}
+
+		if len(patientIDs) == 0 {
+			return nil
+		}
+		var grants []ConsentGrant
+		if err := tx.Where("patient_id IN ? AND grantee_type = ? AND clinician_id = ? AND revoked_at IS NULL AND expires_at > ?",
+			patientIDs, GranteeClinician, fromClinicianID, now).
+			Order("id ASC").Find(&grants).Error; err != nil {
+			return err
+		}
+		for _, grant := range grants {
+			successor := ConsentGrant{
+				PatientID:   grant.PatientID,
+				GranteeType: GranteeClinician,
+				ClinicianID: &toClinicianID,
+				Categories:  grant.Categories,
+				Actions:     grant.Actions,
+				Purpose:     fmt.Sprintf("handover of consent %d from a clinician being offboarded", grant.ID),
+				ExpiresAt:   grant.ExpiresAt,
+			}
+			if err := tx.Create(&successor).Error; err != nil {
+				return err
+			}
+			copied[grant.ID] = successor
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	movedIDs := make([]uint, len(moved))
+	for i, assignment := range moved {
+		movedIDs[i] = assignment.PatientID
+	}
+	s.logEvent(ctx, "TRANSFER_CARE_ASSIGNMENTS", nil, map[string]interface{}{
+		"from_clinician_id": fromClinicianID,
+		"to_clinician_id":   toClinicianID,
+		"patient_ids":       movedIDs,
+	})
+
+	for originalID, grant := range copied {
+		patientID := grant.PatientID
+		s.logEvent(ctx, "TRANSFER_CONSENT", &patientID, map[string]interface{}{
+			"consent_id":        grant.ID,
+			"from_consent_id":   originalID,
+			"from_clinician_id": fromClinicianID,
+			"clinician_id":      toClinicianID,
+			"categories":        grant.Categories,
+			"actions":           grant.Actions,
+			"expires_at":        grant.ExpiresAt,
+		})
+	}
+
+	handover := &CareHandover{
+		Assignments:    int64(len(moved)),
+		Consents:       int64(len(copied)),
+		WithoutConsent: []uint{},
+	}
+	seen := map[uint]bool{}
+	for _, patientID := range patientIDs {
+		if seen[patientID] {
+			continue
+		}
+		seen[patientID] = true
+
+		grants, err := s.consentsFor(toClinicianID, patientID)
+		if err != nil {
+			return nil, err
+		}
+		if len(grants) == 0 {
+			handover.WithoutConsent = append(handover.WithoutConsent, patientID)
+		}
+	}
+	return handover, nil
+}
@@ -0,0 +1,72 @@
+package record
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/khawsic/health/internal/authz"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTransferCareAssignmentsHandsOverConsent(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:handover?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&CareAssignment{}, &ConsentGrant{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, department TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	const departing, successor = 10, 20
+	db.Exec("INSERT INTO users (id, department) VALUES (?, ''), (?, '')", departing, successor)
+
+	now := time.Now()
+	for _, patientID := range []uint{1, 2} {
+		db.Create(&CareAssignment{
+			PatientID:   patientID,
+			ClinicianID: departing,
+			Status:      CareActive,
+			StartsAt:    now.Add(-time.Hour),
+			RequestedBy: departing,
+		})
+	}
+
+	clinician := uint(departing)
+	expires := now.Add(30 * 24 * time.Hour)
+	db.Create(&ConsentGrant{
+		PatientID:   1,
+		GranteeType: GranteeClinician,
+		ClinicianID: &clinician,
+		Categories:  authz.StringList{"*"},
+		Actions:     authz.StringList{"read"},
+		ExpiresAt:   expires,
+	})
+
+	s := NewService(db, "", nil)
+	handover, err := s.TransferCareAssignments(context.Background(), departing, successor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if handover.Assignments != 2 || handover.Consents != 1 {
+		t.Fatalf("moved %d assignments and %d consents, want 2 and 1", handover.Assignments, handover.Consents)
+	}
+	if len(handover.WithoutConsent) != 1 || handover.WithoutConsent[0] != 2 {
+		t.Fatalf("patients without consent = %v, want [2]", handover.WithoutConsent)
+	}
+
+	grants, err := s.consentsFor(successor, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 1 || !grants[0].ExpiresAt.Equal(expires) || len(grants[0].Actions) != 1 || grants[0].Actions[0] != "read" {
+		t.Fatalf("successor's consent for patient 1 = %+v, want a copy of the original grant", grants)
+	}
+}
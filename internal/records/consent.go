@@ -0,0 +1,283 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/khawsic/health/internal/audit"
+	"github.com/khawsic/health/internal/authz"
+)
+
+// ErrNoConsent is returned when the patient has not consented to the
+// caller's action on a record category
+var ErrNoConsent = errors.New("the patient has not consented to this access — use emergency access if this is an emergency")
+
+var errConsentNotFound = errors.New("consent not found")
+
+// consentEventActions are the audit actions a patient sees about their own
+// records: consent changes, and everything clinicians did or tried to do
+var consentEventActions = []string{
+	"GRANT_CONSENT",
+	"REVOKE_CONSENT",
+	"TRANSFER_CONSENT",
+	"CREATE_RECORD",
+	"UPDATE_RECORD",
+	"DELETE_RECORD",
+	"SEARCH_PATIENT_RECORDS",
+	"READ_RECORD_HISTORY",
+	"EMERGENCY_ACCESS",
+	"ACCESS_DENIED",
+	"READ_RECORDS",
+	"REQUEST_PROXY",
+	"APPROVE_PROXY",
+	"DECLINE_PROXY",
+	"REVOKE_PROXY",
+}
+
+// ValidCategory reports whether category is a known record category
+func ValidCategory(category string) bool {
+	for _, known := range Categories {
+		if category == known {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether the grant allows action on records of category
+func (g ConsentGrant) covers(action, category string) bool {
+	allowed := false
+	for _, a := range g.Actions {
+		if a == action {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+	if len(g.Categories) == 0 {
+		return true
+	}
+	for _, c := range g.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// consentsFor loads the grants in force from patientID to clinicianID,
+// directly or through the clinician's department
+func (s *Service) consentsFor(clinicianID, patientID uint) ([]ConsentGrant, error) {
+	var department string
+	if err := s.db.Table("users").Select("department").
+		Where("id = ?", clinicianID).
+		Scan(&department).Error; err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("patient_id = ? AND revoked_at IS NULL AND expires_at > ?", patientID, time.Now())
+	if department != "" {
+		query = query.Where("(grantee_type = ? AND clinician_id = ?) OR (grantee_type = ? AND department = ?)",
+			GranteeClinician, clinicianID, GranteeDepartment, department)
+	} else {
+		query = query.Where("grantee_type = ? AND clinician_id = ?", GranteeClinician, clinicianID)
+	}
+
+	var grants []ConsentGrant
+	err := query.Order("id ASC").Find(&grants).Error
+	return grants, err
+}
+
+func covering(grants []ConsentGrant, action, category string) *ConsentGrant {
+	for i := range grants {
+		if grants[i].covers(action, category) {
+			return &grants[i]
+		}
+	}
+	return nil
+}
+
+// requireConsent returns the grant allowing the clinician's action on a
+// record category. Refusals are audited and shown to the patient.
+func (s *Service) requireConsent(ctx context.Context, clinicianID, patientID uint, action, category string) (uint, error) {
+	grants, err := s.consentsFor(clinicianID, patientID)
+	if err != nil {
+		return 0, err
+	}
+	if grant := covering(grants, action, category); grant != nil {
+		return grant.ID, nil
+	}
+
+	s.denyWithoutConsent(ctx, patientID, action, []string{category})
+	return 0, ErrNoConsent
+}
+
+func (s *Service) denyWithoutConsent(ctx context.Context, patientID uint, action string, categories []string) {
+	s.logEvent(ctx, "ACCESS_DENIED", &patientID, map[string]interface{}{
+		"reason":     "no patient consent",
+		"action":     action,
+		"categories": categories,
+	})
+}
+
+// 📝 GrantConsent — a patient allows a clinician or a department to act on
+// their records until the grant expires
+func (s *Service) GrantConsent(ctx context.Context, patientID uint, grant ConsentGrant) (*ConsentGrant, error) {
+	grant.ID = 0
+	grant.PatientID = patientID
+	grant.RevokedAt = nil
+
+	switch grant.GranteeType {
+	case GranteeClinician:
+		if grant.ClinicianID == nil || !s.userHasRole(*grant.ClinicianID, false) {
+			return nil, errNotAClinician
+		}
+		grant.Department = ""
+	case GranteeDepartment:
+		grant.Department = authz.NormalizeDepartment(grant.Department)
+		if grant.Department == "" {
+			return nil, errors.New("department is required")
+		}
+		grant.ClinicianID = nil
+	default:
+		return nil, fmt.Errorf("grantee_type must be %q or %q", GranteeClinician, GranteeDepartment)
+	}
+
+	categories, err := uniqueOf(grant.Categories, Categories, "category")
+	if err != nil {
+		return nil, err
+	}
+	actions, err := uniqueOf(grant.Actions, []string{ConsentRead, ConsentWrite, ConsentDelete}, "action")
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		return nil, errors.New("at least one action is required")
+	}
+	grant.Categories = categories
+	grant.Actions = actions
+
+	if !grant.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+
+	if err := s.db.Create(&grant).Error; err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, "GRANT_CONSENT", &patientID, map[string]interface{}{
+		"consent_id":   grant.ID,
+		"grantee_type": grant.GranteeType,
+		"clinician_id": grant.ClinicianID,
+		"department":   grant.Department,
+		"categories":   grant.Categories,
+		"actions":      grant.Actions,
+		"expires_at":   grant.ExpiresAt,
+	})
+	return &grant, nil
+}
+
+// uniqueOf checks every value is one of allowed and drops repeats
+func uniqueOf(values, allowed []string, field string) ([]string, error) {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		known := false
+		for _, a := range allowed {
+			if value == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown %s %q; expected one of %s", field, value, strings.Join(allowed, ", "))
+		}
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result, nil
+}
+
+// 🚫 RevokeConsent — ends a grant now. Access already given is not undone.
+func (s *Service) RevokeConsent(ctx context.Context, patientID, consentID uint) error {
+	result := s.db.Model(&ConsentGrant{}).
+		Where("id = ? AND patient_id = ? AND revoked_at IS NULL", consentID, patientID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errConsentNotFound
+	}
+
+	s.logEvent(ctx, "REVOKE_CONSENT", &patientID, map[string]interface{}{
+		"consent_id": consentID,
+	})
+	return nil
+}
+
+// ListConsents returns every grant the patient has made, newest first,
+// including expired and revoked ones
+func (s *Service) ListConsents(patientID uint) ([]ConsentGrant, error) {
+	var grants []ConsentGrant
+	err := s.db.Where("patient_id = ?", patientID).
+		Order("created_at DESC, id DESC").
+		Find(&grants).Error
+	return grants, err
+}
+
+// ConsentActivity returns one page of what happened to the patient's
+// records and consents: grants, revocations, and every access or refused
+// access by clinicians, with the consent it relied on
+func (s *Service) ConsentActivity(ctx context.Context, patientID uint, page, pageSize int) ([]ConsentEvent, int64, error) {
+	if s.auditService == nil {
+		return []ConsentEvent{}, 0, nil
+	}
+
+	logs, total, err := s.auditService.FilterLogs(ctx, audit.FilterOptions{
+		PatientID: &patientID,
+		Actions:   consentEventActions,
+		Page:      page,
+		PageSize:  pageSize,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := make([]ConsentEvent, len(logs))
+	for i, entry := range logs {
+		events[i] = ConsentEvent{
+			ID:        entry.ID,
+			Action:    entry.Action,
+			UserID:    entry.UserID,
+			Role:      entry.Role,
+			Timestamp: entry.Timestamp,
+		}
+		if entry.Details != "" {
+			// Details are written by this application; skip any that do not parse
+			_ = json.Unmarshal([]byte(entry.Details), &events[i].Details)
+		}
+	}
+	return events, total, nil
+}
+
+// hasAction reports whether any grant allows action on some category
+func hasAction(grants []ConsentGrant, action string) bool {
+	for _, grant := range grants {
+		for _, a := range grant.Actions {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
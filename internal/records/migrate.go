@@ -2,14 +2,72 @@ package record
 
 import (
 	"log"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// transitionalConsent is how long grants created for existing care
+// relationships last
+const transitionalConsent = 90 * 24 * time.Hour
+
+// DataMigration marks a one-time backfill as done so later starts skip it.
+// The versioned SQL migrations record the same names.
+type DataMigration struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+func (DataMigration) TableName() string {
+	return "data_migrations"
+}
+
 func Migrate(db *gorm.DB) {
-	err := db.AutoMigrate(&MedicalRecord{}, &RecordVersion{})
+	err := db.AutoMigrate(&MedicalRecord{}, &RecordVersion{}, &CareAssignment{}, &ConsentGrant{}, &ProxyRelationship{}, &DataMigration{})
 	if err != nil {
 		log.Fatal("❌ Record migration failed:", err)
 	}
+
+	// Doctors already responsible for a patient's records keep access when
+	// care assignments are introduced
+	err = runOnce(db, "care_assignments_backfill", `
+		INSERT INTO care_assignments (patient_id, clinician_id, status, reason, starts_at, requested_by, created_at, updated_at)
+		SELECT DISTINCT patient_id, doctor_id, 'active', 'existing records', CURRENT_TIMESTAMP, doctor_id, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM medical_records
+		WHERE deleted_at IS NULL
+	`)
+	if err != nil {
+		log.Fatal("❌ Failed to backfill care assignments:", err)
+	}
+
+	// Consent is checked from now on. Clinicians already caring for a
+	// patient get a time-limited grant the patient can review and revoke,
+	// instead of losing access the moment consent is introduced.
+	err = runOnce(db, "consent_grants_backfill", `
+		INSERT INTO consent_grants (patient_id, grantee_type, clinician_id, department, categories, actions, purpose, expires_at, created_at, updated_at)
+		SELECT patient_id, ?, clinician_id, '', '[]', ?, 'existing care relationship when consent was introduced', ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM care_assignments
+		WHERE status = ? AND (ends_at IS NULL OR ends_at > CURRENT_TIMESTAMP)
+	`, GranteeClinician, `["read","write","delete"]`, time.Now().Add(transitionalConsent), CareActive)
+	if err != nil {
+		log.Fatal("❌ Failed to backfill consent grants:", err)
+	}
 	log.Println("✅ Record tables migrated")
-}
\ No newline at end of file
+}
+
+// runOnce applies a backfill unless it is already recorded as done. The
+// marker is written in the same transaction, so a failed backfill is
+// retried and instances starting together apply it only once.
+func runOnce(db *gorm.DB, name, sql string, values ...interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		marker := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&DataMigration{Name: name, AppliedAt: time.Now()})
+		if marker.Error != nil || marker.RowsAffected == 0 {
+			return marker.Error
+		}
+
+		log.Printf("🗂️  Applying one-time migration %s", name)
+		return tx.Exec(sql, values...).Error
+	})
+}
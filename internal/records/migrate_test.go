@@ -0,0 +1,42 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMigrateBackfillsOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:migrate?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&MedicalRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&MedicalRecord{PatientID: 1, DoctorID: 2, Diagnosis: "x", Treatment: "x"})
+
+	Migrate(db)
+
+	var assignments, grants int64
+	db.Model(&CareAssignment{}).Count(&assignments)
+	db.Model(&ConsentGrant{}).Count(&grants)
+	if assignments != 1 || grants != 1 {
+		t.Fatalf("after first start: %d care assignments and %d consent grants, want 1 of each", assignments, grants)
+	}
+
+	// Emptying the tables must not bring the backfilled rows back
+	db.Where("1 = 1").Delete(&ConsentGrant{})
+	db.Where("1 = 1").Delete(&CareAssignment{})
+	Migrate(db)
+
+	db.Model(&CareAssignment{}).Count(&assignments)
+	db.Model(&ConsentGrant{}).Count(&grants)
+	if assignments != 0 || grants != 0 {
+		t.Fatalf("after restart: %d care assignments and %d consent grants, want none — a backfill ran twice", assignments, grants)
+	}
+}
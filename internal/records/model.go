@@ -3,6 +3,7 @@ package record
 import (
 	"time"
 
+	"github.com/khawsic/health/internal/authz"
 	"gorm.io/gorm"
 )
 
@@ -10,6 +11,7 @@ type MedicalRecord struct {
 	ID        uint           `gorm:"primaryKey"`
 	PatientID uint           `gorm:"not null;index"`
 	DoctorID  uint           `gorm:"not null"`
+	Category  string         `gorm:"size:50;not null;default:general;index"`
 	Diagnosis string         `gorm:"not null"` // AES-256 encrypted
 	Treatment string         `gorm:"not null"` // AES-256 encrypted
 	Version   int            `gorm:"default:1"`
@@ -18,6 +20,21 @@ type MedicalRecord struct {
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
+// Record categories. Patients scope consent by category, so sensitive
+// kinds of care can be shared more narrowly than the rest.
+const (
+	CategoryGeneral      = "general"
+	CategoryMentalHealth = "mental_health"
+	CategorySubstanceUse = "substance_use"
+	CategorySexualHealth = "sexual_health"
+	CategoryGenetic      = "genetic"
+)
+
+// Categories lists every record category
+var Categories = []string{
+	CategoryGeneral, CategoryMentalHealth, CategorySubstanceUse, CategorySexualHealth, CategoryGenetic,
+}
+
 type RecordVersion struct {
 	ID        uint      `gorm:"primaryKey"`
 	RecordID  uint      `gorm:"not null;index"`
@@ -27,4 +44,125 @@ type RecordVersion struct {
 	Treatment string    `gorm:"not null"` // AES-256 encrypted
 	Version   int       `gorm:"not null"`
 	CreatedAt time.Time
+}
+
+// Care assignment statuses
+const (
+	CareRequested = "requested"
+	CareActive    = "active"
+	CareDeclined  = "declined"
+	CareEnded     = "ended"
+)
+
+// CareAssignment puts a clinician on a patient's care team. Clinicians may
+// only read and change the records of patients they are currently
+// assigned to: the assignment is active, has started and has not ended.
+type CareAssignment struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	PatientID   uint       `gorm:"not null;index" json:"patient_id"`
+	ClinicianID uint       `gorm:"not null;index" json:"clinician_id"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"`
+	Reason      string     `json:"reason"`
+	StartsAt    time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	RequestedBy uint       `gorm:"not null" json:"requested_by"`
+	ReviewedBy  *uint      `json:"reviewed_by"`
+	ReviewNotes string     `json:"review_notes"`
+	EndedBy     *uint      `json:"ended_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CareAssignmentView adds the names of the people involved for display
+type CareAssignmentView struct {
+	CareAssignment
+	PatientName   string `json:"patient_name"`
+	ClinicianName string `json:"clinician_name"`
+	ClinicianRole string `json:"clinician_role"`
+}
+
+// Consent actions a grant can allow. Reading includes version history;
+// writing is creating and updating records.
+const (
+	ConsentRead   = "read"
+	ConsentWrite  = "write"
+	ConsentDelete = "delete"
+)
+
+// Consent grantee types: one clinician, or everyone in a department
+const (
+	GranteeClinician  = "clinician"
+	GranteeDepartment = "department"
+)
+
+// ConsentGrant is a patient's permission for a clinician or a department to
+// act on their records. Empty Categories cover every category. A grant is
+// in force until it expires or the patient revokes it.
+type ConsentGrant struct {
+	ID          uint             `gorm:"primaryKey" json:"id"`
+	PatientID   uint             `gorm:"not null;index" json:"patient_id"`
+	GranteeType string           `gorm:"size:20;not null" json:"grantee_type"`
+	ClinicianID *uint            `gorm:"index" json:"clinician_id,omitempty"`
+	Department  string           `gorm:"size:100" json:"department,omitempty"`
+	Categories  authz.StringList `gorm:"type:text;not null" json:"categories"`
+	Actions     authz.StringList `gorm:"type:text;not null" json:"actions"`
+	Purpose     string           `json:"purpose"`
+	ExpiresAt   time.Time        `gorm:"not null;index" json:"expires_at"`
+	RevokedAt   *time.Time       `json:"revoked_at"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// ConsentEvent is an audit entry about a patient's records, as shown to
+// that patient
+type ConsentEvent struct {
+	ID        uint                   `json:"id"`
+	Action    string                 `json:"action"`
+	UserID    uint                   `json:"user_id"`
+	Role      string                 `json:"role"`
+	Timestamp time.Time              `json:"timestamp"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Proxy relationship statuses
+const (
+	ProxyRequested = "requested"
+	ProxyActive    = "active"
+	ProxyDeclined  = "declined"
+	ProxyRevoked   = "revoked"
+)
+
+// Proxy relationship kinds. Parents act for a minor until the minor comes
+// of age; guardians and caregivers act for adults.
+const (
+	ProxyParent    = "parent"    // parent or legal guardian of a minor
+	ProxyGuardian  = "guardian"  // court-appointed guardian of an adult
+	ProxyCaregiver = "caregiver" // caregiver the adult patient has authorised
+)
+
+// ProxyRelationship lets the proxy act for the subject patient once staff
+// have verified the evidence. It is in force while active and unexpired,
+// and for parents only while the subject is a minor.
+type ProxyRelationship struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ProxyID      uint       `gorm:"not null;index" json:"proxy_id"`
+	SubjectID    uint       `gorm:"not null;index" json:"subject_id"`
+	Relationship string     `gorm:"size:20;not null" json:"relationship"`
+	Status       string     `gorm:"size:20;not null;index" json:"status"`
+	Evidence     string     `json:"evidence"` // documents the proxy says they hold
+	ExpiresAt    *time.Time `json:"expires_at"`
+	VerifiedBy   *uint      `json:"verified_by"`
+	VerifiedAt   *time.Time `json:"verified_at"`
+	ReviewNotes  string     `json:"review_notes"`
+	RevokedBy    *uint      `json:"revoked_by"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ProxyRelationshipView adds the names of the people involved for display
+type ProxyRelationshipView struct {
+	ProxyRelationship
+	ProxyName   string `json:"proxy_name"`
+	SubjectName string `json:"subject_name"`
 }
\ No newline at end of file
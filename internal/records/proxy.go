@@ -0,0 +1,380 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoProxyAccess is returned when a user tries to act for a patient
+// without a verified proxy relationship in force
+var ErrNoProxyAccess = errors.New("you are not a verified proxy for this patient")
+
+var (
+	errProxyNotFound = errors.New("proxy relationship not found")
+	errSelfProxy     = errors.New("you cannot be your own proxy")
+)
+
+// ProxyRules are the age rules for proxy access
+type ProxyRules struct {
+	// AdultAge ends parents' access on the patient's birthday
+	AdultAge int
+
+	// From AdolescentAge, records in ConfidentialCategories are hidden
+	// from parents. No categories means adolescents have no confidential
+	// records.
+	AdolescentAge          int
+	ConfidentialCategories []string
+}
+
+// DefaultProxyRules ends parents' access at 18 and keeps nothing
+// confidential from them
+func DefaultProxyRules() ProxyRules {
+	return ProxyRules{AdultAge: 18, AdolescentAge: 12}
+}
+
+// UseProxyRules replaces the proxy age rules
+func (s *Service) UseProxyRules(rules ProxyRules) error {
+	if rules.AdultAge < 1 {
+		return errors.New("adult age must be positive")
+	}
+	if rules.AdolescentAge < 1 || rules.AdolescentAge >= rules.AdultAge {
+		return fmt.Errorf("adolescent age must be between 1 and %d", rules.AdultAge-1)
+	}
+	categories, err := uniqueOf(rules.ConfidentialCategories, Categories, "category")
+	if err != nil {
+		return err
+	}
+	rules.ConfidentialCategories = categories
+	s.proxyRules = rules
+	return nil
+}
+
+// ProxyFilter narrows the proxy relationship list
+type ProxyFilter struct {
+	ProxyID   uint
+	SubjectID uint
+	Status    string
+	Page      int
+	PageSize  int
+}
+
+// ageOn returns how many full years someone born on dob has lived at t
+func ageOn(dob, t time.Time) int {
+	years := t.Year() - dob.Year()
+	if t.Month() < dob.Month() || (t.Month() == dob.Month() && t.Day() < dob.Day()) {
+		years--
+	}
+	return years
+}
+
+// comesOfAge is the start of the patient's AdultAge birthday
+func (s *Service) comesOfAge(dob time.Time) time.Time {
+	return time.Date(dob.Year()+s.proxyRules.AdultAge, dob.Month(), dob.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// patientBirthDate loads an active patient's date of birth, which may be
+// unknown
+func (s *Service) patientBirthDate(patientID uint) (*time.Time, error) {
+	var patient struct {
+		DateOfBirth *time.Time
+	}
+	result := s.db.Table("users").Select("date_of_birth").
+		Where("id = ? AND role = ? AND status = ? AND deleted_at IS NULL", patientID, "patient", "active").
+		Scan(&patient)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errNotAPatient
+	}
+	return patient.DateOfBirth, nil
+}
+
+// 👪 RequestProxy — asks to act for the patient with subjectEmail. The
+// result is nil when there is no such patient, so callers can answer the
+// same either way.
+func (s *Service) RequestProxy(ctx context.Context, proxyID uint, subjectEmail, relationship, evidence string) (*ProxyRelationship, error) {
+	switch relationship {
+	case ProxyParent, ProxyGuardian, ProxyCaregiver:
+	default:
+		return nil, fmt.Errorf("relationship must be %s, %s or %s", ProxyParent, ProxyGuardian, ProxyCaregiver)
+	}
+	if strings.TrimSpace(evidence) == "" {
+		return nil, errors.New("describe the documents that show your relationship to the patient")
+	}
+
+	var subject struct {
+		ID uint
+	}
+	if err := s.db.Table("users").Select("id").
+		Where("LOWER(email) = ? AND role = ? AND status = ? AND deleted_at IS NULL",
+			strings.ToLower(strings.TrimSpace(subjectEmail)), "patient", "active").
+		Scan(&subject).Error; err != nil {
+		return nil, err
+	}
+	if subject.ID == 0 {
+		return nil, nil
+	}
+	if subject.ID == proxyID {
+		return nil, errSelfProxy
+	}
+
+	var open int64
+	if err := s.db.Model(&ProxyRelationship{}).
+		Where("proxy_id = ? AND subject_id = ? AND status IN ?", proxyID, subject.ID, []string{ProxyRequested, ProxyActive}).
+		Count(&open).Error; err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, errors.New("you already have a request or relationship for this patient")
+	}
+
+	proxy := ProxyRelationship{
+		ProxyID:      proxyID,
+		SubjectID:    subject.ID,
+		Relationship: relationship,
+		Status:       ProxyRequested,
+		Evidence:     evidence,
+	}
+	if err := s.db.Create(&proxy).Error; err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, "REQUEST_PROXY", &proxy.SubjectID, map[string]interface{}{
+		"proxy_relationship_id": proxy.ID,
+		"proxy_id":              proxyID,
+		"relationship":          relationship,
+	})
+	return &proxy, nil
+}
+
+// ✅ ReviewProxy — staff verify or decline a proxy request after checking
+// the evidence. Parents are verified only for minors, and their access
+// ends when the minor comes of age even if expiresAt is later.
+func (s *Service) ReviewProxy(ctx context.Context, verifierID, relationshipID uint, approve bool, notes string, expiresAt *time.Time) (*ProxyRelationship, error) {
+	var proxy ProxyRelationship
+	if err := s.db.First(&proxy, relationshipID).Error; err != nil {
+		return nil, errProxyNotFound
+	}
+	if proxy.Status != ProxyRequested {
+		return nil, errors.New("only requested relationships can be reviewed")
+	}
+	if verifierID == proxy.ProxyID || verifierID == proxy.SubjectID {
+		return nil, errors.New("you cannot verify a relationship you are part of")
+	}
+
+	status, action := ProxyDeclined, "DECLINE_PROXY"
+	if approve {
+		status, action = ProxyActive, "APPROVE_PROXY"
+
+		now := time.Now()
+		if expiresAt != nil && !expiresAt.After(now) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+
+		dob, err := s.patientBirthDate(proxy.SubjectID)
+		if err != nil {
+			return nil, err
+		}
+		minor := dob != nil && ageOn(*dob, now) < s.proxyRules.AdultAge
+
+		if proxy.Relationship == ProxyParent {
+			if dob == nil {
+				return nil, errors.New("record the patient's date of birth before verifying a parent")
+			}
+			if !minor {
+				return nil, errors.New("the patient is an adult — verify a guardian or caregiver instead")
+			}
+			adulthood := s.comesOfAge(*dob)
+			if expiresAt == nil || expiresAt.After(adulthood) {
+				expiresAt = &adulthood
+			}
+		} else if minor {
+			return nil, errors.New("the patient is a minor — verify a parent instead")
+		}
+	} else {
+		expiresAt = nil
+	}
+
+	now := time.Now()
+	result := s.db.Model(&ProxyRelationship{}).
+		Where("id = ? AND status = ?", proxy.ID, ProxyRequested).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"verified_by":  verifierID,
+			"verified_at":  now,
+			"review_notes": notes,
+			"expires_at":   expiresAt,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("only requested relationships can be reviewed")
+	}
+
+	proxy.Status = status
+	proxy.VerifiedBy = &verifierID
+	proxy.VerifiedAt = &now
+	proxy.ReviewNotes = notes
+	proxy.ExpiresAt = expiresAt
+
+	s.logEvent(ctx, action, &proxy.SubjectID, map[string]interface{}{
+		"proxy_relationship_id": proxy.ID,
+		"proxy_id":              proxy.ProxyID,
+		"relationship":          proxy.Relationship,
+		"expires_at":            expiresAt,
+		"notes":                 notes,
+	})
+	return &proxy, nil
+}
+
+// 🚫 RevokeProxy — ends a relationship or withdraws a request. Proxies may
+// step down and adult patients may revoke their proxies; a parent's
+// access to a minor can only be revoked by staff.
+func (s *Service) RevokeProxy(ctx context.Context, userID, relationshipID uint, staff bool) error {
+	var proxy ProxyRelationship
+	if err := s.db.First(&proxy, relationshipID).Error; err != nil {
+		return errProxyNotFound
+	}
+	if !staff && userID != proxy.ProxyID && userID != proxy.SubjectID {
+		return errProxyNotFound
+	}
+	if proxy.Status != ProxyActive && proxy.Status != ProxyRequested {
+		return errors.New("this relationship has already ended")
+	}
+
+	revokedBy := "staff"
+	if !staff {
+		revokedBy = "proxy"
+		if userID == proxy.SubjectID {
+			revokedBy = "subject"
+			if proxy.Relationship == ProxyParent && proxy.Status == ProxyActive {
+				dob, err := s.patientBirthDate(proxy.SubjectID)
+				if err != nil {
+					return err
+				}
+				if dob == nil || ageOn(*dob, time.Now()) < s.proxyRules.AdultAge {
+					return errors.New("a parent's access can only be ended by staff while you are a minor")
+				}
+			}
+		}
+	}
+
+	previousStatus := proxy.Status
+	result := s.db.Model(&ProxyRelationship{}).
+		Where("id = ? AND status = ?", proxy.ID, previousStatus).
+		Updates(map[string]interface{}{
+			"status":     ProxyRevoked,
+			"revoked_by": userID,
+			"revoked_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("this relationship has already ended")
+	}
+
+	s.logEvent(ctx, "REVOKE_PROXY", &proxy.SubjectID, map[string]interface{}{
+		"proxy_relationship_id": proxy.ID,
+		"proxy_id":              proxy.ProxyID,
+		"revoked_by":            revokedBy,
+		"previous_status":       previousStatus,
+	})
+	return nil
+}
+
+// proxyAccess returns the relationship letting proxyID act for subjectID
+// now, and the record categories hidden from that proxy
+func (s *Service) proxyAccess(proxyID, subjectID uint) (*ProxyRelationship, []string, error) {
+	if proxyID == subjectID {
+		return nil, nil, errSelfProxy
+	}
+
+	now := time.Now()
+	var proxy ProxyRelationship
+	err := s.db.Where("proxy_id = ? AND subject_id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+		proxyID, subjectID, ProxyActive, now).
+		First(&proxy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrNoProxyAccess
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dob, err := s.patientBirthDate(subjectID)
+	if errors.Is(err, errNotAPatient) {
+		return nil, nil, ErrNoProxyAccess
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Checked again here in case the date of birth changed after
+	// verification
+	if proxy.Relationship != ProxyParent || dob == nil {
+		return &proxy, nil, nil
+	}
+	age := ageOn(*dob, now)
+	if age >= s.proxyRules.AdultAge {
+		return nil, nil, ErrNoProxyAccess
+	}
+	if age >= s.proxyRules.AdolescentAge {
+		return &proxy, s.proxyRules.ConfidentialCategories, nil
+	}
+	return &proxy, nil, nil
+}
+
+// ResolveProxy returns the ID of the relationship letting proxyID act for
+// subjectID, or ErrNoProxyAccess
+func (s *Service) ResolveProxy(proxyID, subjectID uint) (uint, error) {
+	proxy, _, err := s.proxyAccess(proxyID, subjectID)
+	if err != nil {
+		return 0, err
+	}
+	return proxy.ID, nil
+}
+
+// ListProxyRelationships returns one page of relationships, newest first
+func (s *Service) ListProxyRelationships(filter ProxyFilter) ([]ProxyRelationshipView, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	query := s.db.Model(&ProxyRelationship{})
+	if filter.ProxyID != 0 {
+		query = query.Where("proxy_relationships.proxy_id = ?", filter.ProxyID)
+	}
+	if filter.SubjectID != 0 {
+		query = query.Where("proxy_relationships.subject_id = ?", filter.SubjectID)
+	}
+	if filter.Status != "" {
+		query = query.Where("proxy_relationships.status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var views []ProxyRelationshipView
+	err := query.
+		Select("proxy_relationships.*, p.name AS proxy_name, s.name AS subject_name").
+		Joins("JOIN users p ON p.id = proxy_relationships.proxy_id").
+		Joins("JOIN users s ON s.id = proxy_relationships.subject_id").
+		Order("proxy_relationships.created_at DESC, proxy_relationships.id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Scan(&views).Error
+	return views, total, err
+}
@@ -1,10 +1,15 @@
 package record
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"sort"
 
 	"github.com/khawsic/health/internal/audit"
+	"github.com/khawsic/health/internal/authz"
+	"github.com/khawsic/health/internal/requestctx"
 	"github.com/khawsic/health/internal/security"
 	"gorm.io/gorm"
 )
@@ -13,6 +18,8 @@ type Service struct {
 	db           *gorm.DB
 	key          string
 	auditService *audit.Service
+	authorizer   *authz.Service
+	proxyRules   ProxyRules
 }
 
 func NewService(db *gorm.DB, key string, auditService *audit.Service) *Service {
@@ -20,11 +27,44 @@ func NewService(db *gorm.DB, key string, auditService *audit.Service) *Service {
 		db:           db,
 		key:          key,
 		auditService: auditService,
+		proxyRules:   DefaultProxyRules(),
 	}
 }
 
-// Create encrypts Diagnosis & Treatment and logs the action
-func (s *Service) Create(patientID, doctorID uint, diagnosis, treatment string) error {
+// UseAuthorizer makes every method check the caller's permission against
+// the patient it touches, on top of the route check
+func (s *Service) UseAuthorizer(authorizer *authz.Service) {
+	s.authorizer = authorizer
+}
+
+func (s *Service) authorize(ctx context.Context, permission string, patientID uint) error {
+	if s.authorizer == nil {
+		return nil
+	}
+	return s.authorizer.Require(ctx, permission, authz.Resource{PatientID: patientID})
+}
+
+// Create encrypts Diagnosis & Treatment and logs the action. An empty
+// category files the record as general.
+func (s *Service) Create(ctx context.Context, patientID, doctorID uint, category, diagnosis, treatment string) error {
+	if category == "" {
+		category = CategoryGeneral
+	}
+	if !ValidCategory(category) {
+		return fmt.Errorf("unknown category %q", category)
+	}
+
+	if err := s.authorize(ctx, authz.PermRecordWrite, patientID); err != nil {
+		return err
+	}
+	if err := s.requireCare(ctx, doctorID, patientID); err != nil {
+		return err
+	}
+	consentID, err := s.requireConsent(ctx, doctorID, patientID, ConsentWrite, category)
+	if err != nil {
+		return err
+	}
+
 	encDiagnosis, err := security.Encrypt(s.key, diagnosis)
 	if err != nil {
 		return err
@@ -38,6 +78,7 @@ func (s *Service) Create(patientID, doctorID uint, diagnosis, treatment string)
 	record := MedicalRecord{
 		PatientID: patientID,
 		DoctorID:  doctorID,
+		Category:  category,
 		Diagnosis: encDiagnosis,
 		Treatment: encTreatment,
 		Version:   1,
@@ -48,7 +89,13 @@ func (s *Service) Create(patientID, doctorID uint, diagnosis, treatment string)
 	}
 
 	if s.auditService != nil {
-		if err := s.auditService.Log(doctorID, "CREATE_RECORD", &record.ID); err != nil {
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:    doctorID,
+			Action:    "CREATE_RECORD",
+			RecordID:  &record.ID,
+			PatientID: &record.PatientID,
+			Details:   map[string]interface{}{"category": category, "consent_id": consentID},
+		}); err != nil {
 			log.Printf("⚠️  Audit log failed for CREATE_RECORD: %v", err)
 		}
 	}
@@ -57,13 +104,23 @@ func (s *Service) Create(patientID, doctorID uint, diagnosis, treatment string)
 }
 
 // Update saves old version to history then updates the record
-func (s *Service) Update(recordID, doctorID uint, diagnosis, treatment string) error {
+func (s *Service) Update(ctx context.Context, recordID, doctorID uint, diagnosis, treatment string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 
 		var existing MedicalRecord
 		if err := tx.First(&existing, recordID).Error; err != nil {
 			return errors.New("record not found")
 		}
+		if err := s.authorize(ctx, authz.PermRecordWrite, existing.PatientID); err != nil {
+			return err
+		}
+		if err := s.requireCare(ctx, doctorID, existing.PatientID); err != nil {
+			return err
+		}
+		consentID, err := s.requireConsent(ctx, doctorID, existing.PatientID, ConsentWrite, existing.Category)
+		if err != nil {
+			return err
+		}
 
 		// Save current version to history before overwriting
 		version := RecordVersion{
@@ -99,7 +156,13 @@ func (s *Service) Update(recordID, doctorID uint, diagnosis, treatment string) e
 		}
 
 		if s.auditService != nil {
-			if err := s.auditService.Log(doctorID, "UPDATE_RECORD", &existing.ID); err != nil {
+			if err := s.auditService.LogEntry(ctx, audit.Entry{
+				UserID:    doctorID,
+				Action:    "UPDATE_RECORD",
+				RecordID:  &existing.ID,
+				PatientID: &existing.PatientID,
+				Details:   map[string]interface{}{"consent_id": consentID},
+			}); err != nil {
 				log.Printf("⚠️  Audit log failed for UPDATE_RECORD: %v", err)
 			}
 		}
@@ -108,8 +171,27 @@ func (s *Service) Update(recordID, doctorID uint, diagnosis, treatment string) e
 	})
 }
 
-// GetVersionHistory returns all previous versions of a record
-func (s *Service) GetVersionHistory(recordID uint) ([]RecordVersion, error) {
+// GetVersionHistory returns all previous versions of a record. The caller
+// is checked against the record before any version is read, so a refusal
+// looks the same whether or not the record has history.
+func (s *Service) GetVersionHistory(ctx context.Context, recordID, doctorID uint) ([]RecordVersion, error) {
+	// History outlives deletion, so the record is looked up whether or not
+	// it was deleted
+	var record MedicalRecord
+	if err := s.db.Unscoped().Select("id", "patient_id", "category").First(&record, recordID).Error; err != nil {
+		return nil, errors.New("record not found")
+	}
+	if err := s.authorize(ctx, authz.PermRecordHistory, record.PatientID); err != nil {
+		return nil, err
+	}
+	if err := s.requireCare(ctx, doctorID, record.PatientID); err != nil {
+		return nil, err
+	}
+	consentID, err := s.requireConsent(ctx, doctorID, record.PatientID, ConsentRead, record.Category)
+	if err != nil {
+		return nil, err
+	}
+
 	var versions []RecordVersion
 	if err := s.db.Where("record_id = ?", recordID).
 		Order("version ASC").
@@ -130,13 +212,48 @@ func (s *Service) GetVersionHistory(recordID uint) ([]RecordVersion, error) {
 		versions[i].Treatment = decTreat
 	}
 
+	if s.auditService != nil && len(versions) > 0 {
+		versionIDs := make([]uint, len(versions))
+		for i, v := range versions {
+			versionIDs[i] = v.ID
+		}
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:    doctorID,
+			Action:    "READ_RECORD_HISTORY",
+			RecordID:  &recordID,
+			PatientID: &record.PatientID,
+			Details:   map[string]interface{}{"version_ids": versionIDs, "consent_id": consentID},
+		}); err != nil {
+			log.Printf("⚠️  Audit log failed for READ_RECORD_HISTORY: %v", err)
+		}
+	}
+
 	return versions, nil
 }
 
-// GetByPatient decrypts records for patient view
-func (s *Service) GetByPatient(patientID uint) ([]MedicalRecord, error) {
+// GetByPatient decrypts records for patient view. A proxy acting for the
+// patient sees them too, less any categories kept confidential from them.
+func (s *Service) GetByPatient(ctx context.Context, patientID uint) ([]MedicalRecord, error) {
+	if err := s.authorize(ctx, authz.PermRecordReadOwn, patientID); err != nil {
+		return nil, err
+	}
+
+	var hidden []string
+	if callerID := requestctx.From(ctx).UserID; callerID != 0 && callerID != patientID {
+		_, categories, err := s.proxyAccess(callerID, patientID)
+		if err != nil {
+			return nil, err
+		}
+		hidden = categories
+	}
+
+	query := s.db.Where("patient_id = ?", patientID)
+	if len(hidden) > 0 {
+		query = query.Where("category NOT IN ?", hidden)
+	}
+
 	var records []MedicalRecord
-	if err := s.db.Where("patient_id = ?", patientID).Find(&records).Error; err != nil {
+	if err := query.Find(&records).Error; err != nil {
 		return nil, err
 	}
 
@@ -154,7 +271,16 @@ func (s *Service) GetByPatient(patientID uint) ([]MedicalRecord, error) {
 	}
 
 	if s.auditService != nil {
-		if err := s.auditService.Log(patientID, "READ_RECORDS", nil); err != nil {
+		details := map[string]interface{}{"record_ids": recordIDs(records)}
+		if len(hidden) > 0 {
+			details["confidential_categories"] = hidden
+		}
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:    requestctx.From(ctx).UserID,
+			Action:    "READ_RECORDS",
+			PatientID: &patientID,
+			Details:   details,
+		}); err != nil {
 			log.Printf("⚠️  Audit log failed for READ_RECORDS: %v", err)
 		}
 	}
@@ -163,16 +289,50 @@ func (s *Service) GetByPatient(patientID uint) ([]MedicalRecord, error) {
 }
 
 // SearchByPatient allows doctor to search records by patient ID
-func (s *Service) SearchByPatient(patientID, doctorID uint) ([]MedicalRecord, error) {
-	var records []MedicalRecord
-	if err := s.db.Where("patient_id = ?", patientID).Find(&records).Error; err != nil {
+func (s *Service) SearchByPatient(ctx context.Context, patientID, doctorID uint) ([]MedicalRecord, error) {
+	if err := s.authorize(ctx, authz.PermRecordRead, patientID); err != nil {
+		return nil, err
+	}
+	if err := s.requireCare(ctx, doctorID, patientID); err != nil {
 		return nil, err
 	}
 
-	if len(records) == 0 {
+	grants, err := s.consentsFor(doctorID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAction(grants, ConsentRead) {
+		s.denyWithoutConsent(ctx, patientID, ConsentRead, nil)
+		return nil, ErrNoConsent
+	}
+
+	var all []MedicalRecord
+	if err := s.db.Where("patient_id = ?", patientID).Find(&all).Error; err != nil {
+		return nil, err
+	}
+
+	if len(all) == 0 {
 		return nil, errors.New("no records found for this patient")
 	}
 
+	// Only categories the patient consented to are returned
+	var records []MedicalRecord
+	var withheld []string
+	consentIDs := map[uint]bool{}
+	for _, record := range all {
+		grant := covering(grants, ConsentRead, record.Category)
+		if grant == nil {
+			withheld = append(withheld, record.Category)
+			continue
+		}
+		consentIDs[grant.ID] = true
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		s.denyWithoutConsent(ctx, patientID, ConsentRead, withheld)
+		return nil, ErrNoConsent
+	}
+
 	for i := range records {
 		decDiag, err := security.Decrypt(s.key, records[i].Diagnosis)
 		if err != nil {
@@ -187,7 +347,16 @@ func (s *Service) SearchByPatient(patientID, doctorID uint) ([]MedicalRecord, er
 	}
 
 	if s.auditService != nil {
-		if err := s.auditService.Log(doctorID, "SEARCH_PATIENT_RECORDS", nil); err != nil {
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:    doctorID,
+			Action:    "SEARCH_PATIENT_RECORDS",
+			PatientID: &patientID,
+			Details: map[string]interface{}{
+				"record_ids":  recordIDs(records),
+				"consent_ids": sortedIDs(consentIDs),
+				"withheld":    len(withheld),
+			},
+		}); err != nil {
 			log.Printf("⚠️  Audit log failed for SEARCH_PATIENT_RECORDS: %v", err)
 		}
 	}
@@ -196,18 +365,34 @@ func (s *Service) SearchByPatient(patientID, doctorID uint) ([]MedicalRecord, er
 }
 
 // SoftDelete marks a record as deleted without removing it
-func (s *Service) SoftDelete(recordID, doctorID uint) error {
+func (s *Service) SoftDelete(ctx context.Context, recordID, doctorID uint) error {
 	var record MedicalRecord
 	if err := s.db.First(&record, recordID).Error; err != nil {
 		return errors.New("record not found")
 	}
+	if err := s.authorize(ctx, authz.PermRecordDelete, record.PatientID); err != nil {
+		return err
+	}
+	if err := s.requireCare(ctx, doctorID, record.PatientID); err != nil {
+		return err
+	}
+	consentID, err := s.requireConsent(ctx, doctorID, record.PatientID, ConsentDelete, record.Category)
+	if err != nil {
+		return err
+	}
 
 	if err := s.db.Delete(&record).Error; err != nil {
 		return err
 	}
 
 	if s.auditService != nil {
-		if err := s.auditService.Log(doctorID, "DELETE_RECORD", &record.ID); err != nil {
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:    doctorID,
+			Action:    "DELETE_RECORD",
+			RecordID:  &record.ID,
+			PatientID: &record.PatientID,
+			Details:   map[string]interface{}{"consent_id": consentID},
+		}); err != nil {
 			log.Printf("⚠️  Audit log failed for DELETE_RECORD: %v", err)
 		}
 	}
@@ -215,12 +400,17 @@ func (s *Service) SoftDelete(recordID, doctorID uint) error {
 	return nil
 }
 
-// EmergencyAccess decrypts a record using the master key with elevated audit logging
-func (s *Service) EmergencyAccess(recordID uint, userID uint) (*MedicalRecord, error) {
+// EmergencyAccess decrypts a record using the master key with elevated audit
+// logging. It is the break-glass path and needs no care relationship or
+// consent; the patient sees the access in their consent activity.
+func (s *Service) EmergencyAccess(ctx context.Context, recordID uint, userID uint) (*MedicalRecord, error) {
 	var record MedicalRecord
 	if err := s.db.First(&record, recordID).Error; err != nil {
 		return nil, errors.New("record not found")
 	}
+	if err := s.authorize(ctx, authz.PermRecordEmergency, record.PatientID); err != nil {
+		return nil, err
+	}
 
 	decDiag, err := security.Decrypt(s.key, record.Diagnosis)
 	if err != nil {
@@ -236,7 +426,12 @@ func (s *Service) EmergencyAccess(recordID uint, userID uint) (*MedicalRecord, e
 	record.Treatment = decTreat
 
 	if s.auditService != nil {
-		if err := s.auditService.Log(userID, "EMERGENCY_ACCESS", &record.ID); err != nil {
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:    userID,
+			Action:    "EMERGENCY_ACCESS",
+			RecordID:  &record.ID,
+			PatientID: &record.PatientID,
+		}); err != nil {
 			log.Printf("⚠️  Audit log failed for EMERGENCY_ACCESS: %v", err)
 		}
 	}
@@ -245,7 +440,11 @@ func (s *Service) EmergencyAccess(recordID uint, userID uint) (*MedicalRecord, e
 }
 
 // GetAll decrypts all records for admin view
-func (s *Service) GetAll() ([]MedicalRecord, error) {
+func (s *Service) GetAll(ctx context.Context, adminID uint) ([]MedicalRecord, error) {
+	if err := s.authorize(ctx, authz.PermRecordReadAll, 0); err != nil {
+		return nil, err
+	}
+
 	var records []MedicalRecord
 	if err := s.db.Find(&records).Error; err != nil {
 		return nil, err
@@ -264,5 +463,85 @@ func (s *Service) GetAll() ([]MedicalRecord, error) {
 		records[i].Treatment = decTreat
 	}
 
+	if s.auditService != nil {
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			UserID:  adminID,
+			Action:  "READ_ALL_RECORDS",
+			Details: map[string]interface{}{"record_ids": recordIDs(records)},
+		}); err != nil {
+			log.Printf("⚠️  Audit log failed for READ_ALL_RECORDS: %v", err)
+		}
+	}
+
 	return records, nil
-}
\ No newline at end of file
+}
+
+// ReassignDoctor moves every record a departing doctor is responsible for
+// to their successor. Version history keeps the original authors.
+func (s *Service) ReassignDoctor(ctx context.Context, fromDoctorID, toDoctorID uint) (int64, error) {
+	var patientIDs []uint
+	if err := s.db.Model(&MedicalRecord{}).
+		Where("doctor_id = ?", fromDoctorID).
+		Distinct().
+		Pluck("patient_id", &patientIDs).Error; err != nil {
+		return 0, err
+	}
+
+	result := s.db.Model(&MedicalRecord{}).
+		Where("doctor_id = ?", fromDoctorID).
+		Update("doctor_id", toDoctorID)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if s.auditService != nil {
+		if err := s.auditService.LogEntry(ctx, audit.Entry{
+			Action: "REASSIGN_PATIENTS",
+			Details: map[string]interface{}{
+				"from_doctor_id": fromDoctorID,
+				"to_doctor_id":   toDoctorID,
+				"records":        result.RowsAffected,
+				"patient_ids":    patientIDs,
+			},
+		}); err != nil {
+			log.Printf("⚠️  Audit log failed for REASSIGN_PATIENTS: %v", err)
+		}
+	}
+
+	return result.RowsAffected, nil
+}
+
+// sortedIDs returns the members of set in ascending order, so audit
+// details are stable
+func sortedIDs(set map[uint]bool) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// recordIDs lists the IDs of records being disclosed, for the audit trail
+func recordIDs(records []MedicalRecord) []uint {
+	ids := make([]uint, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+// logEvent audits an action by the caller in ctx. Failures are logged
+// rather than returned, like every other record audit entry.
+func (s *Service) logEvent(ctx context.Context, action string, patientID *uint, details map[string]interface{}) {
+	if s.auditService == nil {
+		return
+	}
+	if err := s.auditService.LogEntry(ctx, audit.Entry{
+		Action:    action,
+		PatientID: patientID,
+		Details:   details,
+	}); err != nil {
+		log.Printf("⚠️  Audit log failed for %s: %v", action, err)
+	}
+}
@@ -0,0 +1,37 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestVersionHistoryChecksCareBeforeReading(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:history?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&MedicalRecord{}, &RecordVersion{}, &CareAssignment{}); err != nil {
+		t.Fatal(err)
+	}
+	record := MedicalRecord{PatientID: 1, DoctorID: 2, Category: CategoryGeneral, Diagnosis: "x", Treatment: "x"}
+	db.Create(&record)
+
+	s := NewService(db, "", nil)
+	const stranger = 3
+
+	// A record with no history yet must refuse a stranger, not answer
+	// with an empty list that confirms it exists
+	if _, err := s.GetVersionHistory(context.Background(), record.ID, stranger); !errors.Is(err, ErrNoCareRelationship) {
+		t.Fatalf("history without a care relationship: err = %v, want %v", err, ErrNoCareRelationship)
+	}
+	if _, err := s.GetVersionHistory(context.Background(), record.ID+1, stranger); err == nil {
+		t.Fatal("history of a missing record succeeded")
+	}
+}
@@ -0,0 +1,61 @@
+package requestctx
+
+import (
+	"context"
+	"time"
+)
+
+// Info carries per-request metadata from the HTTP layer down into services
+// so audit entries can be attributed without threading every field by hand
+type Info struct {
+	RequestID string
+	IPAddress string
+	UserAgent string
+	UserID    uint
+	Role      string
+
+	// Device identifies the browser by the hash of its device cookie,
+	// issued at login. Clients without one fall back to a hash of their
+	// user agent, language and platform headers, which anyone can copy.
+	Device string
+
+	// ActingAs is the patient a proxy is acting for through the proxy
+	// relationship ProxyID; zero when users act for themselves
+	ActingAs uint
+	ProxyID  uint
+
+	// APIKeyID is set when a service account authenticated with an API
+	// key. Scopes then lists the only permissions the request may use.
+	APIKeyID uint
+	Scopes   []string
+
+	// AuthTime is when the user last authenticated in this session and ACR
+	// how strongly; both come from the access token and are unset for
+	// API keys
+	AuthTime time.Time
+	ACR      string
+}
+
+type ctxKey struct{}
+
+// With returns a copy of ctx carrying info
+func With(ctx context.Context, info *Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// Lookup returns the request info stored in ctx and whether it was present
+func Lookup(ctx context.Context) (*Info, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	info, ok := ctx.Value(ctxKey{}).(*Info)
+	return info, ok && info != nil
+}
+
+// From returns the request info stored in ctx, or an empty Info if none
+func From(ctx context.Context) *Info {
+	if info, ok := Lookup(ctx); ok {
+		return info
+	}
+	return &Info{}
+}
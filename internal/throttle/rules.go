@@ -0,0 +1,108 @@
+package throttle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ulule/limiter/v3"
+)
+
+// Route classes. Each public route belongs to one, and each class has its
+// own limits.
+const (
+	ClassLogin         = "login"
+	ClassRegister      = "register"
+	ClassPasswordReset = "password_reset"
+	ClassRefresh       = "refresh"
+	ClassDefault       = "default"
+)
+
+// Classes lists every route class a rules file may configure
+var Classes = []string{ClassLogin, ClassRegister, ClassPasswordReset, ClassRefresh, ClassDefault}
+
+// Rule limits one route class. Rates use the "<limit>-<period>" format,
+// with S, M, H or D periods (e.g. "10-M" is ten a minute). IP applies to
+// every request from an address; Account applies per address and account
+// when the request names one. An empty rate turns that limit off.
+type Rule struct {
+	IP      string `json:"ip"`
+	Account string `json:"account"`
+}
+
+// Rules maps route classes to their limits. They are loaded from the JSON
+// file named by THROTTLE_RULES_FILE over the defaults.
+type Rules map[string]Rule
+
+// DefaultRules is used when no rules file is configured
+func DefaultRules() Rules {
+	return Rules{
+		ClassLogin:         {IP: "30-M", Account: "10-M"},
+		ClassRegister:      {IP: "10-M", Account: "3-M"},
+		ClassPasswordReset: {IP: "10-M", Account: "5-H"},
+		ClassRefresh:       {IP: "60-M"},
+		ClassDefault:       {IP: "60-M"},
+	}
+}
+
+// LoadRules reads a rules file; classes it leaves out keep their default
+// limits
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+
+	var overrides Rules
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return rules, fmt.Errorf("invalid throttle rules file: %w", err)
+	}
+	for class, rule := range overrides {
+		if _, ok := rules[class]; !ok {
+			return rules, fmt.Errorf("unknown route class %q", class)
+		}
+		rules[class] = rule
+	}
+
+	if _, err := rules.compile(); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+type compiledRule struct {
+	ip      *limiter.Rate
+	account *limiter.Rate
+}
+
+func (r Rules) compile() (map[string]compiledRule, error) {
+	compiled := make(map[string]compiledRule, len(r))
+	for class, rule := range r {
+		ip, err := parseRate(rule.IP)
+		if err != nil {
+			return nil, fmt.Errorf("%s ip rate: %w", class, err)
+		}
+		account, err := parseRate(rule.Account)
+		if err != nil {
+			return nil, fmt.Errorf("%s account rate: %w", class, err)
+		}
+		compiled[class] = compiledRule{ip: ip, account: account}
+	}
+	return compiled, nil
+}
+
+func parseRate(value string) (*limiter.Rate, error) {
+	if value == "" {
+		return nil, nil
+	}
+	rate, err := limiter.NewRateFromFormatted(value)
+	if err != nil {
+		return nil, err
+	}
+	if rate.Limit < 1 {
+		return nil, fmt.Errorf("rate %q must allow at least one request", value)
+	}
+	return &rate, nil
+}
@@ -0,0 +1,120 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+	"gorm.io/gorm"
+)
+
+// Counter is one fixed-window bucket. Every instance reads and writes the
+// same row, so limits hold across replicas and survive restarts.
+type Counter struct {
+	Key       string    `gorm:"primaryKey;size:255"`
+	Count     int64     `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (Counter) TableName() string {
+	return "throttle_counters"
+}
+
+// PostgresStore is a limiter.Store backed by the throttle_counters table
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Migrate() error {
+	return s.db.AutoMigrate(&Counter{})
+}
+
+// Get counts one hit against key
+func (s *PostgresStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return s.Increment(ctx, key, 1, rate)
+}
+
+// Increment adds count hits in a single upsert. A window that has expired
+// starts again from count.
+func (s *PostgresStore) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	now := time.Now()
+
+	var counter Counter
+	err := s.db.WithContext(ctx).Raw(`
+		INSERT INTO throttle_counters (key, count, expires_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN throttle_counters.expires_at <= ?
+				THEN excluded.count
+				ELSE throttle_counters.count + excluded.count END,
+			expires_at = CASE WHEN throttle_counters.expires_at <= ?
+				THEN excluded.expires_at
+				ELSE throttle_counters.expires_at END
+		RETURNING key, count, expires_at
+	`, key, count, now.Add(rate.Period), now, now).Scan(&counter).Error
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, counter.ExpiresAt, counter.Count), nil
+}
+
+// Peek reports the limit for key without counting a hit
+func (s *PostgresStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now := time.Now()
+
+	var counter Counter
+	err := s.db.WithContext(ctx).Where("key = ? AND expires_at > ?", key, now).First(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+	}
+	if err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, counter.ExpiresAt, counter.Count), nil
+}
+
+// Reset clears the window for key
+func (s *PostgresStore) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	now := time.Now()
+
+	if err := s.db.WithContext(ctx).Where("key = ?", key).Delete(&Counter{}).Error; err != nil {
+		return limiter.Context{}, err
+	}
+
+	return common.GetContextFromState(now, rate, now.Add(rate.Period), 0), nil
+}
+
+// Prune deletes counters whose window has ended
+func (s *PostgresStore) Prune(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&Counter{})
+	return result.RowsAffected, result.Error
+}
+
+// StartPruning deletes expired counters every interval until ctx is
+// cancelled. Any instance may prune; the deletes do not conflict.
+func (s *PostgresStore) StartPruning(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Prune(ctx); err != nil {
+					log.Printf("⚠️  Throttle counter pruning failed: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,144 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khawsic/health/internal/crypto"
+	"github.com/ulule/limiter/v3"
+)
+
+// maxPeekBytes bounds how much of a request body is read to find the
+// account it names
+const maxPeekBytes = 64 << 10
+
+// Throttler applies the per-class limits to public routes
+type Throttler struct {
+	store limiter.Store
+	rules map[string]compiledRule
+}
+
+// New checks the rules and builds a throttler counting in store. Use a
+// shared store (PostgresStore) when more than one instance serves traffic.
+func New(store limiter.Store, rules Rules) (*Throttler, error) {
+	compiled, err := rules.compile()
+	if err != nil {
+		return nil, err
+	}
+	for _, class := range Classes {
+		if _, ok := compiled[class]; !ok {
+			return nil, fmt.Errorf("no rule for route class %q", class)
+		}
+	}
+	return &Throttler{store: store, rules: compiled}, nil
+}
+
+// StartPruning clears expired counters in the background when the store
+// keeps them in the database
+func (t *Throttler) StartPruning(ctx context.Context, interval time.Duration) {
+	if store, ok := t.store.(*PostgresStore); ok {
+		store.StartPruning(ctx, interval)
+	}
+}
+
+// Limit counts the request against the client address and, when the JSON
+// body names an email, against that address and account together. Either
+// limit being reached answers 429 with Retry-After.
+func (t *Throttler) Limit(class string) gin.HandlerFunc {
+	rule, ok := t.rules[class]
+	if !ok {
+		panic("throttle: no rule for route class " + class)
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		ip := c.ClientIP()
+
+		if rule.ip != nil {
+			limit, err := t.store.Get(ctx, class+":ip:"+ip, *rule.ip)
+			if !t.allow(c, limit, err) {
+				return
+			}
+		}
+
+		if rule.account != nil {
+			if account := requestAccount(c); account != "" {
+				limit, err := t.store.Get(ctx, class+":account:"+ip+":"+account, *rule.account)
+				if !t.allow(c, limit, err) {
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// allow sets the rate limit headers and aborts when the limit is reached
+// or cannot be checked
+func (t *Throttler) allow(c *gin.Context, limit limiter.Context, err error) bool {
+	if err != nil {
+		log.Printf("⚠️  Rate limit check failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable"})
+		c.Abort()
+		return false
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.FormatInt(limit.Limit, 10))
+	c.Header("X-RateLimit-Remaining", strconv.FormatInt(limit.Remaining, 10))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(limit.Reset, 10))
+
+	if !limit.Reached {
+		return true
+	}
+
+	retryAfter := time.Until(time.Unix(limit.Reset, 0)).Round(time.Second)
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": "Too many requests — try again in " + retryAfter.String(),
+	})
+	c.Abort()
+	return false
+}
+
+// requestAccount returns a hash of the email in a JSON body, leaving the
+// body intact for the handler. Only the hash is stored in counter keys.
+func requestAccount(c *gin.Context) string {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ""
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBytes))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), c.Request.Body), c.Request.Body}
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(peeked, &body) != nil {
+		return ""
+	}
+
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	if email == "" {
+		return ""
+	}
+	return crypto.HashString(email)
+}
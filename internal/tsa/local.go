@@ -0,0 +1,201 @@
+package tsa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitorus/timestamp"
+)
+
+// localPolicy is a private-arc policy OID marking tokens from the stand-in TSA
+var localPolicy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 3161, 1}
+
+// LocalAuthority is a self-signed Time Stamping Authority for development
+// and testing. It can sign in-process or be served over HTTP.
+// It provides no trust guarantees and must not be used in production.
+type LocalAuthority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// NewLocalAuthority creates an authority with a fresh ephemeral key
+func NewLocalAuthority() (*LocalAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Local Development TSA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalAuthority{cert: cert, key: key}, nil
+}
+
+// LoadOrCreateLocalAuthority keeps the authority's key and certificate in dir
+// so tokens issued before a restart still verify afterwards
+func LoadOrCreateLocalAuthority(dir string) (*LocalAuthority, error) {
+	certPath := filepath.Join(dir, "tsa-cert.pem")
+	keyPath := filepath.Join(dir, "tsa-key.pem")
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if certErr == nil && keyErr == nil {
+		return parseLocalAuthority(certPEM, keyPEM)
+	}
+
+	authority, err := NewLocalAuthority()
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(authority.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(certPath, authority.CertificatePEM(), 0o644); err != nil {
+		return nil, err
+	}
+
+	return authority, nil
+}
+
+func parseLocalAuthority(certPEM, keyPEM []byte) (*LocalAuthority, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("invalid local TSA certificate file")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("invalid local TSA key file")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("local TSA key cannot sign")
+	}
+
+	return &LocalAuthority{cert: cert, key: key}, nil
+}
+
+// CertificatePEM returns the authority certificate to use as a trust root
+func (a *LocalAuthority) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.cert.Raw})
+}
+
+// Roots returns a pool trusting only this authority
+func (a *LocalAuthority) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.cert)
+	return pool
+}
+
+// Timestamp signs digest in-process and returns the token
+func (a *LocalAuthority) Timestamp(digest []byte) ([]byte, error) {
+	reply, err := a.respond(&timestamp.Request{
+		HashAlgorithm: crypto.SHA256,
+		HashedMessage: digest,
+		Certificates:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := timestamp.ParseResponse(reply)
+	if err != nil {
+		return nil, err
+	}
+	return ts.RawToken, nil
+}
+
+// ServeHTTP answers RFC 3161 requests sent as application/timestamp-query
+func (a *LocalAuthority) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<16))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	req, err := timestamp.ParseRequest(body)
+	if err != nil {
+		http.Error(w, "invalid timestamp request", http.StatusBadRequest)
+		return
+	}
+
+	reply, err := a.respond(req)
+	if err != nil {
+		http.Error(w, "failed to create timestamp", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(reply)
+}
+
+func (a *LocalAuthority) respond(req *timestamp.Request) ([]byte, error) {
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     req.HashAlgorithm,
+		HashedMessage:     req.HashedMessage,
+		Time:              time.Now().UTC(),
+		Accuracy:          time.Second,
+		Policy:            localPolicy,
+		Nonce:             req.Nonce,
+		AddTSACertificate: req.Certificates,
+	}
+	return ts.CreateResponseWithOpts(a.cert, a.key, crypto.SHA256)
+}
@@ -0,0 +1,128 @@
+package tsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/digitorus/timestamp"
+)
+
+// Authority obtains RFC 3161 timestamp tokens over a SHA-256 digest.
+// The returned token is the DER-encoded CMS TimeStampToken.
+type Authority interface {
+	Timestamp(digest []byte) ([]byte, error)
+}
+
+// HTTPAuthority requests tokens from a remote Time Stamping Authority
+type HTTPAuthority struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPAuthority(url string) *HTTPAuthority {
+	return &HTTPAuthority{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Timestamp sends a TimeStampReq and validates the reply against the request
+func (a *HTTPAuthority) Timestamp(digest []byte) ([]byte, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &timestamp.Request{
+		HashAlgorithm: crypto.SHA256,
+		HashedMessage: digest,
+		Certificates:  true,
+		Nonce:         nonce,
+	}
+	body, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Post(a.url, "application/timestamp-query", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("TSA request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA returned status %d", resp.StatusCode)
+	}
+
+	reply, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := timestamp.ParseResponse(reply)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TSA response: %w", err)
+	}
+	if ts.Nonce == nil || ts.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("TSA response nonce does not match request")
+	}
+	if !bytes.Equal(ts.HashedMessage, digest) {
+		return nil, errors.New("TSA response does not cover the requested digest")
+	}
+
+	return ts.RawToken, nil
+}
+
+// Verify checks a stored token's signature, that it covers digest, and —
+// when roots is non-nil — that the signing certificate chains to a trusted
+// TSA root. It returns the TSA-asserted generation time.
+func Verify(token, digest []byte, roots *x509.CertPool) (time.Time, error) {
+	ts, err := timestamp.Parse(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+
+	if ts.HashAlgorithm != crypto.SHA256 || !bytes.Equal(ts.HashedMessage, digest) {
+		return time.Time{}, errors.New("timestamp token does not cover the expected digest")
+	}
+
+	if roots != nil {
+		if len(ts.Certificates) == 0 {
+			return time.Time{}, errors.New("timestamp token carries no signing certificate")
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range ts.Certificates[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := ts.Certificates[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   ts.Time,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		})
+		if err != nil {
+			return time.Time{}, fmt.Errorf("untrusted TSA certificate: %w", err)
+		}
+	}
+
+	return ts.Time, nil
+}
+
+// LoadRoots reads a PEM bundle of trusted TSA certificates
+func LoadRoots(pemData []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, errors.New("no certificates found in TSA CA bundle")
+	}
+	return pool, nil
+}
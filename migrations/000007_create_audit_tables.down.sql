@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_checkpoints;
+DROP TABLE IF EXISTS chain_verifications;
+DROP TABLE IF EXISTS audit_logs;
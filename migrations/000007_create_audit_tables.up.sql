@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    role TEXT,
+    action TEXT NOT NULL,
+    record_id BIGINT,
+    patient_id BIGINT,
+    ip_address TEXT,
+    request_id TEXT,
+    timestamp TIMESTAMPTZ NOT NULL,
+    details TEXT,
+    hash_version BIGINT NOT NULL DEFAULT 1,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    signature TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_hash ON audit_logs(hash);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_role ON audit_logs(role);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_record_id ON audit_logs(record_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_patient_id ON audit_logs(patient_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_ip_address ON audit_logs(ip_address);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs(request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_timestamp ON audit_logs(timestamp);
+
+CREATE TABLE IF NOT EXISTS chain_verifications (
+    id BIGSERIAL PRIMARY KEY,
+    from_entry_id BIGINT NOT NULL,
+    to_entry_id BIGINT NOT NULL,
+    entries_checked BIGINT NOT NULL,
+    valid BOOLEAN NOT NULL,
+    failure_kind TEXT,
+    failed_entry_id BIGINT,
+    error TEXT,
+    verified_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chain_verifications_valid ON chain_verifications(valid);
+CREATE INDEX IF NOT EXISTS idx_chain_verifications_verified_at ON chain_verifications(verified_at);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL,
+    entry_hash TEXT NOT NULL,
+    token BYTEA NOT NULL,
+    gen_time TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_checkpoints_entry_id ON audit_checkpoints(entry_id);
@@ -0,0 +1,11 @@
+REVOKE ALL ON audit_logs, chain_verifications, audit_checkpoints FROM audit_writer;
+REVOKE ALL ON SEQUENCE audit_logs_id_seq, chain_verifications_id_seq, audit_checkpoints_id_seq FROM audit_writer;
+DROP ROLE IF EXISTS audit_writer;
+
+DROP TRIGGER IF EXISTS audit_checkpoints_no_truncate ON audit_checkpoints;
+DROP TRIGGER IF EXISTS audit_checkpoints_append_only ON audit_checkpoints;
+DROP TRIGGER IF EXISTS chain_verifications_no_truncate ON chain_verifications;
+DROP TRIGGER IF EXISTS chain_verifications_append_only ON chain_verifications;
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP FUNCTION IF EXISTS audit_reject_modification();
@@ -0,0 +1,46 @@
+-- Audit tables are append-only: reject row changes and truncation for every
+-- role, including the table owner. The owner (and any superuser) can still
+-- ALTER TABLE ... DISABLE TRIGGER, so ownership moves to a role the
+-- application cannot use in 000025; VerifyChain catches what slips through.
+CREATE OR REPLACE FUNCTION audit_reject_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit table % is append-only: % is not permitted', TG_TABLE_NAME, TG_OP
+        USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_reject_modification();
+CREATE TRIGGER audit_logs_no_truncate
+    BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_reject_modification();
+
+CREATE TRIGGER chain_verifications_append_only
+    BEFORE UPDATE OR DELETE ON chain_verifications
+    FOR EACH ROW EXECUTE FUNCTION audit_reject_modification();
+CREATE TRIGGER chain_verifications_no_truncate
+    BEFORE TRUNCATE ON chain_verifications
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_reject_modification();
+
+CREATE TRIGGER audit_checkpoints_append_only
+    BEFORE UPDATE OR DELETE ON audit_checkpoints
+    FOR EACH ROW EXECUTE FUNCTION audit_reject_modification();
+CREATE TRIGGER audit_checkpoints_no_truncate
+    BEFORE TRUNCATE ON audit_checkpoints
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_reject_modification();
+
+-- Dedicated low-privilege role used by the application for audit appends.
+-- Set its password out of band: ALTER ROLE audit_writer PASSWORD '...';
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'audit_writer') THEN
+        CREATE ROLE audit_writer LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT;
+    END IF;
+END
+$$;
+
+REVOKE ALL ON audit_logs, chain_verifications, audit_checkpoints FROM PUBLIC;
+REVOKE ALL ON audit_logs, chain_verifications, audit_checkpoints FROM audit_writer;
+GRANT SELECT, INSERT ON audit_logs, chain_verifications, audit_checkpoints TO audit_writer;
+GRANT USAGE ON SEQUENCE audit_logs_id_seq, chain_verifications_id_seq, audit_checkpoints_id_seq TO audit_writer;
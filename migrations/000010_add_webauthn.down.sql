@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS webauthn_sessions;
+DROP TABLE IF EXISTS webauthn_credentials;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS webauthn_handle,
+DROP COLUMN IF EXISTS passkey_only;
@@ -0,0 +1,24 @@
+-- Hashes cannot be reversed: outstanding tokens are invalidated and users
+-- must log in or request a password reset again
+DELETE FROM refresh_tokens;
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN token_hash,
+DROP COLUMN family_id,
+DROP COLUMN rotated_at,
+ADD COLUMN token TEXT NOT NULL UNIQUE;
+
+CREATE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
+
+DO $$
+BEGIN
+    IF to_regclass('password_reset_tokens') IS NOT NULL THEN
+        DELETE FROM password_reset_tokens;
+        DROP INDEX IF EXISTS idx_password_reset_tokens_token_hash;
+        ALTER TABLE password_reset_tokens
+        DROP COLUMN token_hash,
+        ADD COLUMN token TEXT NOT NULL UNIQUE;
+    END IF;
+END $$;
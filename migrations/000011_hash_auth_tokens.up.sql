@@ -0,0 +1,43 @@
+-- Refresh tokens: hash existing plaintext tokens in place and start a
+-- family per legacy token so rotation can take over from here
+ALTER TABLE refresh_tokens
+ADD COLUMN token_hash TEXT,
+ADD COLUMN family_id TEXT,
+ADD COLUMN rotated_at TIMESTAMP;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    family_id = 'legacy-' || id;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN token_hash SET NOT NULL,
+ALTER COLUMN family_id SET NOT NULL,
+DROP COLUMN token;
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+-- Password reset tokens were created by the application migration only,
+-- so the table may not exist yet
+DO $$
+BEGIN
+    IF to_regclass('password_reset_tokens') IS NULL THEN
+        CREATE TABLE password_reset_tokens (
+            id SERIAL PRIMARY KEY,
+            user_id INT NOT NULL,
+            token_hash TEXT NOT NULL UNIQUE,
+            expires_at TIMESTAMP NOT NULL,
+            used BOOLEAN DEFAULT FALSE,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT
+        );
+    ELSE
+        ALTER TABLE password_reset_tokens ADD COLUMN token_hash TEXT;
+        UPDATE password_reset_tokens
+        SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+        ALTER TABLE password_reset_tokens
+        ALTER COLUMN token_hash SET NOT NULL,
+        DROP COLUMN token;
+        CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
+    END IF;
+END $$;
@@ -0,0 +1,8 @@
+CREATE TABLE jwt_signing_keys (
+    id TEXT PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    public_key BYTEA NOT NULL,
+    activates_at TIMESTAMPTZ NOT NULL,
+    retires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS token_denylist;
+DROP TABLE IF EXISTS sessions;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS doctor_applications;
+DROP TABLE IF EXISTS email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS email_verified_at,
+DROP COLUMN IF EXISTS status;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS access_policies;
+DROP TABLE IF EXISTS role_permission_seeds;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+ALTER TABLE users DROP COLUMN IF EXISTS department;
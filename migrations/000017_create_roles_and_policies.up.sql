@@ -0,0 +1,77 @@
+ALTER TABLE users
+ADD COLUMN department VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT,
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE role_permissions (
+    role VARCHAR(50) NOT NULL,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role, permission),
+    CONSTRAINT fk_role FOREIGN KEY (role) REFERENCES roles(name) ON DELETE RESTRICT
+);
+
+-- Default grants already given to built-in roles, so a grant an
+-- administrator removes is not given again on the next start
+CREATE TABLE role_permission_seeds (
+    role VARCHAR(50) NOT NULL,
+    permission VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE access_policies (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    effect VARCHAR(10) NOT NULL,
+    roles TEXT NOT NULL,
+    permissions TEXT NOT NULL,
+    conditions TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_updated_by FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE RESTRICT
+);
+
+INSERT INTO roles (name, description, built_in) VALUES
+    ('admin', 'System administrator', TRUE),
+    ('doctor', 'Treating physician', TRUE),
+    ('patient', 'Patient with access to their own records', TRUE),
+    ('nurse', 'Nursing staff', TRUE),
+    ('pharmacist', 'Dispensing pharmacist', TRUE),
+    ('auditor', 'Read-only compliance auditor', TRUE),
+    ('researcher', 'Researcher — no patient data until permissions or policies grant it', TRUE);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'record:read_all'),
+    ('admin', 'audit:read'),
+    ('admin', 'audit:export'),
+    ('admin', 'user:read'),
+    ('admin', 'user:manage'),
+    ('admin', 'doctor_application:review'),
+    ('admin', 'mfa_policy:manage'),
+    ('admin', 'key:manage'),
+    ('admin', 'role:manage'),
+    ('admin', 'policy:manage'),
+    ('doctor', 'record:read'),
+    ('doctor', 'record:history'),
+    ('doctor', 'record:write'),
+    ('doctor', 'record:delete'),
+    ('doctor', 'record:emergency'),
+    ('patient', 'record:read_own'),
+    ('nurse', 'record:read'),
+    ('nurse', 'record:history'),
+    ('pharmacist', 'record:read'),
+    ('auditor', 'audit:read'),
+    ('auditor', 'audit:export'),
+    ('auditor', 'user:read');
+
+INSERT INTO role_permission_seeds (role, permission)
+SELECT role, permission FROM role_permissions;
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission LIKE 'care_team:%';
+DELETE FROM role_permission_seeds WHERE permission LIKE 'care_team:%';
+DROP TABLE IF EXISTS care_assignments;
+DROP TABLE IF EXISTS data_migrations;
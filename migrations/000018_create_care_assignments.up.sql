@@ -0,0 +1,58 @@
+CREATE TABLE care_assignments (
+    id SERIAL PRIMARY KEY,
+    patient_id INT NOT NULL,
+    clinician_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    requested_by INT NOT NULL,
+    reviewed_by INT,
+    review_notes TEXT,
+    ended_by INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_patient FOREIGN KEY (patient_id) REFERENCES users(id) ON DELETE RESTRICT,
+    CONSTRAINT fk_clinician FOREIGN KEY (clinician_id) REFERENCES users(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_care_assignments_patient_id ON care_assignments(patient_id);
+CREATE INDEX idx_care_assignments_clinician_id ON care_assignments(clinician_id);
+CREATE INDEX idx_care_assignments_status ON care_assignments(status);
+
+-- One-time data backfills, shared with the application's own migrations
+-- so neither repeats the other's work
+CREATE TABLE data_migrations (
+    name VARCHAR(100) PRIMARY KEY,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Doctors already responsible for a patient's records keep access
+INSERT INTO data_migrations (name) VALUES ('care_assignments_backfill');
+
+INSERT INTO care_assignments (patient_id, clinician_id, status, reason, starts_at, requested_by)
+SELECT DISTINCT patient_id, doctor_id, 'active', 'existing records', CURRENT_TIMESTAMP, doctor_id
+FROM medical_records
+WHERE deleted_at IS NULL;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'care_team:read'),
+    ('admin', 'care_team:manage'),
+    ('doctor', 'care_team:read'),
+    ('doctor', 'care_team:request'),
+    ('nurse', 'care_team:read'),
+    ('nurse', 'care_team:request'),
+    ('pharmacist', 'care_team:read'),
+    ('pharmacist', 'care_team:request')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission_seeds (role, permission) VALUES
+    ('admin', 'care_team:read'),
+    ('admin', 'care_team:manage'),
+    ('doctor', 'care_team:read'),
+    ('doctor', 'care_team:request'),
+    ('nurse', 'care_team:read'),
+    ('nurse', 'care_team:request'),
+    ('pharmacist', 'care_team:read'),
+    ('pharmacist', 'care_team:request')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+DELETE FROM data_migrations WHERE name = 'consent_grants_backfill';
+DELETE FROM role_permission_seeds WHERE permission = 'consent:manage';
+DELETE FROM role_permissions WHERE permission = 'consent:manage';
+DROP TABLE IF EXISTS consent_grants;
+DROP INDEX IF EXISTS idx_medical_records_category;
+ALTER TABLE medical_records DROP COLUMN IF EXISTS category;
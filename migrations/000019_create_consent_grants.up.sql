@@ -0,0 +1,43 @@
+ALTER TABLE medical_records
+ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT 'general';
+
+CREATE INDEX idx_medical_records_category ON medical_records(category);
+
+CREATE TABLE consent_grants (
+    id SERIAL PRIMARY KEY,
+    patient_id INT NOT NULL,
+    grantee_type VARCHAR(20) NOT NULL,
+    clinician_id INT,
+    department VARCHAR(100),
+    categories TEXT NOT NULL,
+    actions TEXT NOT NULL,
+    purpose TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_patient FOREIGN KEY (patient_id) REFERENCES users(id) ON DELETE RESTRICT,
+    CONSTRAINT fk_clinician FOREIGN KEY (clinician_id) REFERENCES users(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_consent_grants_patient_id ON consent_grants(patient_id);
+CREATE INDEX idx_consent_grants_clinician_id ON consent_grants(clinician_id);
+CREATE INDEX idx_consent_grants_expires_at ON consent_grants(expires_at);
+
+-- Clinicians already caring for a patient keep access for 90 days, so
+-- patients can review and revoke the grant before it lapses
+INSERT INTO data_migrations (name) VALUES ('consent_grants_backfill');
+
+INSERT INTO consent_grants (patient_id, grantee_type, clinician_id, department, categories, actions, purpose, expires_at)
+SELECT patient_id, 'clinician', clinician_id, '', '[]', '["read","write","delete"]',
+       'existing care relationship when consent was introduced', CURRENT_TIMESTAMP + INTERVAL '90 days'
+FROM care_assignments
+WHERE status = 'active' AND (ends_at IS NULL OR ends_at > CURRENT_TIMESTAMP);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('patient', 'consent:manage')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission_seeds (role, permission) VALUES
+    ('patient', 'consent:manage')
+ON CONFLICT DO NOTHING;
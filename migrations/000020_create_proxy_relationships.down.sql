@@ -0,0 +1,4 @@
+DELETE FROM role_permission_seeds WHERE permission IN ('proxy:verify', 'proxy:request');
+DELETE FROM role_permissions WHERE permission IN ('proxy:verify', 'proxy:request');
+DROP TABLE IF EXISTS proxy_relationships;
+ALTER TABLE users DROP COLUMN IF EXISTS date_of_birth;
@@ -0,0 +1,35 @@
+ALTER TABLE users
+ADD COLUMN date_of_birth DATE;
+
+CREATE TABLE proxy_relationships (
+    id SERIAL PRIMARY KEY,
+    proxy_id INT NOT NULL,
+    subject_id INT NOT NULL,
+    relationship VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    evidence TEXT,
+    expires_at TIMESTAMP,
+    verified_by INT,
+    verified_at TIMESTAMP,
+    review_notes TEXT,
+    revoked_by INT,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_proxy FOREIGN KEY (proxy_id) REFERENCES users(id) ON DELETE RESTRICT,
+    CONSTRAINT fk_subject FOREIGN KEY (subject_id) REFERENCES users(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_proxy_relationships_proxy_id ON proxy_relationships(proxy_id);
+CREATE INDEX idx_proxy_relationships_subject_id ON proxy_relationships(subject_id);
+CREATE INDEX idx_proxy_relationships_status ON proxy_relationships(status);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'proxy:verify'),
+    ('patient', 'proxy:request')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission_seeds (role, permission) VALUES
+    ('admin', 'proxy:verify'),
+    ('patient', 'proxy:request')
+ON CONFLICT DO NOTHING;
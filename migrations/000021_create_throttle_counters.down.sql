@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS throttle_counters;
+
+ALTER TABLE users DROP COLUMN IF EXISTS last_failed_at;
@@ -0,0 +1,10 @@
+ALTER TABLE users
+ADD COLUMN last_failed_at TIMESTAMP;
+
+CREATE TABLE throttle_counters (
+    key VARCHAR(255) PRIMARY KEY,
+    count BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_throttle_counters_expires_at ON throttle_counters(expires_at);
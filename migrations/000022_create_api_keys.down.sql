@@ -0,0 +1,4 @@
+DELETE FROM role_permission_seeds WHERE permission = 'service_account:manage';
+DELETE FROM role_permissions WHERE permission = 'service_account:manage';
+DROP TABLE IF EXISTS api_keys;
+ALTER TABLE users DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,26 @@
+ALTER TABLE users
+ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'human';
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    service_account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    allowed_ips TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    last_used_ip TEXT,
+    created_by INT NOT NULL,
+    revoked_at TIMESTAMP,
+    revoked_by INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_service_account FOREIGN KEY (service_account_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_keys_service_account_id ON api_keys(service_account_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'service_account:manage')
+ON CONFLICT DO NOTHING;
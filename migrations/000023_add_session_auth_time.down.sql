@@ -0,0 +1,3 @@
+ALTER TABLE sessions
+DROP COLUMN IF EXISTS acr,
+DROP COLUMN IF EXISTS auth_time;
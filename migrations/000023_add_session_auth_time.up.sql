@@ -0,0 +1,3 @@
+ALTER TABLE sessions
+ADD COLUMN auth_time TIMESTAMP,
+ADD COLUMN acr VARCHAR(20);
@@ -0,0 +1,19 @@
+CREATE TABLE login_events (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    success BOOLEAN NOT NULL,
+    reason TEXT,
+    acr VARCHAR(20),
+    ip_address TEXT,
+    user_agent TEXT,
+    device TEXT,
+    country TEXT,
+    region TEXT,
+    city TEXT,
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    new_location BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_login_event_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_login_events_user_id ON login_events(user_id, created_at);
@@ -0,0 +1,4 @@
+-- Ownership returns to the role running the rollback
+ALTER TABLE audit_logs OWNER TO CURRENT_USER;
+ALTER TABLE chain_verifications OWNER TO CURRENT_USER;
+ALTER TABLE audit_checkpoints OWNER TO CURRENT_USER;
@@ -0,0 +1,26 @@
+-- The append-only triggers from 000008 bind every role except the table
+-- owner, who can disable them. Hand the audit tables to a role nobody logs
+-- in as, so neither the application role nor audit_writer owns them. The
+-- previous owner, normally the application role, keeps read access for
+-- queries and chain verification.
+-- Run as a superuser or a member of both the current owner and audit_owner.
+DO $$
+DECLARE
+    audit_table TEXT;
+    previous_owner TEXT;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'audit_owner') THEN
+        CREATE ROLE audit_owner NOLOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT;
+    END IF;
+
+    FOREACH audit_table IN ARRAY ARRAY['audit_logs', 'chain_verifications', 'audit_checkpoints'] LOOP
+        SELECT pg_get_userbyid(relowner) INTO previous_owner
+        FROM pg_class WHERE oid = to_regclass(audit_table);
+
+        IF previous_owner <> 'audit_owner' THEN
+            EXECUTE format('GRANT SELECT ON %I TO %I', audit_table, previous_owner);
+            EXECUTE format('ALTER TABLE %I OWNER TO audit_owner', audit_table);
+        END IF;
+    END LOOP;
+END
+$$;